@@ -0,0 +1,6 @@
+// Package transcribev1 转录服务 gRPC 接口的生成代码
+//
+// 需要安装 protoc、protoc-gen-go 和 protoc-gen-go-grpc
+package transcribev1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative transcribe/v1/transcribe.proto
@@ -0,0 +1,1267 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: transcribe/v1/transcribe.proto
+
+// 转录服务的 gRPC 接口，与 HTTP 接口使用同一个转录引擎
+// 修改后运行 go generate ./api/... 重新生成 Go 代码
+
+package transcribev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RecognizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 音频文件内容
+	Audio []byte `protobuf:"bytes,1,opt,name=audio,proto3" json:"audio,omitempty"`
+	// 音频格式：wav、flac、mp3、ogg、pcm、f32，不填时根据文件头识别
+	Format string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	// 输入音频的采样率，WAV 以文件头为准，PCM 不填时使用模型采样率
+	SampleRate int32 `protobuf:"varint,3,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	// 识别模式：online 或 offline，不填时使用配置的默认模式
+	Mode string `protobuf:"bytes,4,opt,name=mode,proto3" json:"mode,omitempty"`
+	// 是否返回词和 token 的时间戳
+	Timestamps bool `protobuf:"varint,5,opt,name=timestamps,proto3" json:"timestamps,omitempty"`
+	// 额外的热词，与服务端的热词文件合并，需要 transducer 模型
+	Hotwords []string `protobuf:"bytes,6,rep,name=hotwords,proto3" json:"hotwords,omitempty"`
+	// 热词的加分，不填时使用配置的默认值
+	HotwordsScore float32 `protobuf:"fixed32,7,opt,name=hotwords_score,json=hotwordsScore,proto3" json:"hotwords_score,omitempty"`
+	// 解码方法：greedy_search 或 modified_beam_search，不填时使用配置的值
+	DecodingMethod string `protobuf:"bytes,8,opt,name=decoding_method,json=decodingMethod,proto3" json:"decoding_method,omitempty"`
+	// modified_beam_search 保留的路径数，不填时使用配置的值
+	MaxActivePaths int32 `protobuf:"varint,9,opt,name=max_active_paths,json=maxActivePaths,proto3" json:"max_active_paths,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RecognizeRequest) Reset() {
+	*x = RecognizeRequest{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeRequest) ProtoMessage() {}
+
+func (x *RecognizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeRequest.ProtoReflect.Descriptor instead.
+func (*RecognizeRequest) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RecognizeRequest) GetAudio() []byte {
+	if x != nil {
+		return x.Audio
+	}
+	return nil
+}
+
+func (x *RecognizeRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *RecognizeRequest) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *RecognizeRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *RecognizeRequest) GetTimestamps() bool {
+	if x != nil {
+		return x.Timestamps
+	}
+	return false
+}
+
+func (x *RecognizeRequest) GetHotwords() []string {
+	if x != nil {
+		return x.Hotwords
+	}
+	return nil
+}
+
+func (x *RecognizeRequest) GetHotwordsScore() float32 {
+	if x != nil {
+		return x.HotwordsScore
+	}
+	return 0
+}
+
+func (x *RecognizeRequest) GetDecodingMethod() string {
+	if x != nil {
+		return x.DecodingMethod
+	}
+	return ""
+}
+
+func (x *RecognizeRequest) GetMaxActivePaths() int32 {
+	if x != nil {
+		return x.MaxActivePaths
+	}
+	return 0
+}
+
+type RecognizeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Text  string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// 所有 token 概率的几何平均，识别器不提供 token 概率（离线识别器、非 transducer 模型）时为 0
+	Confidence float64 `protobuf:"fixed64,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	// 音频时长（秒）
+	Duration float64 `protobuf:"fixed64,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	// 启用说话人分离时每个说话人片段的文本
+	SpeakerSegments []*SpeakerSegment `protobuf:"bytes,4,rep,name=speaker_segments,json=speakerSegments,proto3" json:"speaker_segments,omitempty"`
+	// 启用语音检测切分时每句话的文本
+	Segments []*Segment `protobuf:"bytes,5,rep,name=segments,proto3" json:"segments,omitempty"`
+	// 只在请求时间戳时返回；模型不提供时间戳时调用返回 INVALID_ARGUMENT
+	Words         []*Word  `protobuf:"bytes,6,rep,name=words,proto3" json:"words,omitempty"`
+	Tokens        []*Token `protobuf:"bytes,7,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizeResponse) Reset() {
+	*x = RecognizeResponse{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeResponse) ProtoMessage() {}
+
+func (x *RecognizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeResponse.ProtoReflect.Descriptor instead.
+func (*RecognizeResponse) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RecognizeResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *RecognizeResponse) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *RecognizeResponse) GetDuration() float64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *RecognizeResponse) GetSpeakerSegments() []*SpeakerSegment {
+	if x != nil {
+		return x.SpeakerSegments
+	}
+	return nil
+}
+
+func (x *RecognizeResponse) GetSegments() []*Segment {
+	if x != nil {
+		return x.Segments
+	}
+	return nil
+}
+
+func (x *RecognizeResponse) GetWords() []*Word {
+	if x != nil {
+		return x.Words
+	}
+	return nil
+}
+
+func (x *RecognizeResponse) GetTokens() []*Token {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+type SpeakerSegment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SpeakerId     int32                  `protobuf:"varint,1,opt,name=speaker_id,json=speakerId,proto3" json:"speaker_id,omitempty"`
+	Start         float64                `protobuf:"fixed64,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           float64                `protobuf:"fixed64,3,opt,name=end,proto3" json:"end,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,5,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SpeakerSegment) Reset() {
+	*x = SpeakerSegment{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpeakerSegment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpeakerSegment) ProtoMessage() {}
+
+func (x *SpeakerSegment) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpeakerSegment.ProtoReflect.Descriptor instead.
+func (*SpeakerSegment) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SpeakerSegment) GetSpeakerId() int32 {
+	if x != nil {
+		return x.SpeakerId
+	}
+	return 0
+}
+
+func (x *SpeakerSegment) GetStart() float64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *SpeakerSegment) GetEnd() float64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *SpeakerSegment) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SpeakerSegment) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+type Segment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         float64                `protobuf:"fixed64,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           float64                `protobuf:"fixed64,2,opt,name=end,proto3" json:"end,omitempty"`
+	Text          string                 `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,4,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Segment) Reset() {
+	*x = Segment{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Segment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Segment) ProtoMessage() {}
+
+func (x *Segment) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Segment.ProtoReflect.Descriptor instead.
+func (*Segment) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Segment) GetStart() float64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Segment) GetEnd() float64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Segment) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Segment) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+type Word struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Start         float64                `protobuf:"fixed64,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           float64                `protobuf:"fixed64,3,opt,name=end,proto3" json:"end,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,4,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Word) Reset() {
+	*x = Word{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Word) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Word) ProtoMessage() {}
+
+func (x *Word) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Word.ProtoReflect.Descriptor instead.
+func (*Word) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Word) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Word) GetStart() float64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Word) GetEnd() float64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Word) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+type Token struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Start         float64                `protobuf:"fixed64,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           float64                `protobuf:"fixed64,3,opt,name=end,proto3" json:"end,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,4,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Token) Reset() {
+	*x = Token{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Token) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Token) ProtoMessage() {}
+
+func (x *Token) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Token.ProtoReflect.Descriptor instead.
+func (*Token) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Token) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Token) GetStart() float64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Token) GetEnd() float64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Token) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+type StreamingRecognizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Request:
+	//
+	//	*StreamingRecognizeRequest_Config
+	//	*StreamingRecognizeRequest_Audio
+	//	*StreamingRecognizeRequest_Flush
+	Request       isStreamingRecognizeRequest_Request `protobuf_oneof:"request"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamingRecognizeRequest) Reset() {
+	*x = StreamingRecognizeRequest{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamingRecognizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingRecognizeRequest) ProtoMessage() {}
+
+func (x *StreamingRecognizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingRecognizeRequest.ProtoReflect.Descriptor instead.
+func (*StreamingRecognizeRequest) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamingRecognizeRequest) GetRequest() isStreamingRecognizeRequest_Request {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeRequest) GetConfig() *StreamingConfig {
+	if x != nil {
+		if x, ok := x.Request.(*StreamingRecognizeRequest_Config); ok {
+			return x.Config
+		}
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeRequest) GetAudio() []byte {
+	if x != nil {
+		if x, ok := x.Request.(*StreamingRecognizeRequest_Audio); ok {
+			return x.Audio
+		}
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeRequest) GetFlush() *Flush {
+	if x != nil {
+		if x, ok := x.Request.(*StreamingRecognizeRequest_Flush); ok {
+			return x.Flush
+		}
+	}
+	return nil
+}
+
+type isStreamingRecognizeRequest_Request interface {
+	isStreamingRecognizeRequest_Request()
+}
+
+type StreamingRecognizeRequest_Config struct {
+	// 音频配置，只能作为第一条消息
+	Config *StreamingConfig `protobuf:"bytes,1,opt,name=config,proto3,oneof"`
+}
+
+type StreamingRecognizeRequest_Audio struct {
+	// 一段音频，格式由 config 决定
+	Audio []byte `protobuf:"bytes,2,opt,name=audio,proto3,oneof"`
+}
+
+type StreamingRecognizeRequest_Flush struct {
+	// 不等端点检测，立即结束当前句子
+	Flush *Flush `protobuf:"bytes,3,opt,name=flush,proto3,oneof"`
+}
+
+func (*StreamingRecognizeRequest_Config) isStreamingRecognizeRequest_Request() {}
+
+func (*StreamingRecognizeRequest_Audio) isStreamingRecognizeRequest_Request() {}
+
+func (*StreamingRecognizeRequest_Flush) isStreamingRecognizeRequest_Request() {}
+
+type StreamingConfig struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 音频采样率，不填时使用模型采样率
+	SampleRate int32 `protobuf:"varint,1,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	// 音频格式，默认 pcm（16 位小端单声道），f32 为 32 位小端浮点单声道；
+	// 也可以是 wav 等容器格式，此时每段音频都必须是完整的文件
+	Format string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	// 识别语言，流式模型不区分语言时只在 ready 中回显
+	Language string `protobuf:"bytes,3,opt,name=language,proto3" json:"language,omitempty"`
+	// 是否返回部分结果，默认返回
+	PartialResults *bool `protobuf:"varint,4,opt,name=partial_results,json=partialResults,proto3,oneof" json:"partial_results,omitempty"`
+	// 本次会话额外的热词，与服务端的热词文件合并，需要 transducer 模型
+	Hotwords []string `protobuf:"bytes,5,rep,name=hotwords,proto3" json:"hotwords,omitempty"`
+	// 热词的加分，不填时使用配置的默认值
+	HotwordsScore float32 `protobuf:"fixed32,6,opt,name=hotwords_score,json=hotwordsScore,proto3" json:"hotwords_score,omitempty"`
+	// 解码方法和 modified_beam_search 保留的路径数，不填时使用配置的值
+	DecodingMethod string `protobuf:"bytes,7,opt,name=decoding_method,json=decodingMethod,proto3" json:"decoding_method,omitempty"`
+	MaxActivePaths int32  `protobuf:"varint,8,opt,name=max_active_paths,json=maxActivePaths,proto3" json:"max_active_paths,omitempty"`
+	// 是否按静音自动结束句子，关闭后句子只在 flush 或结束发送时结束
+	EnableEndpoint *bool `protobuf:"varint,9,opt,name=enable_endpoint,json=enableEndpoint,proto3,oneof" json:"enable_endpoint,omitempty"`
+	// 端点检测规则（秒），不填时使用配置的值
+	Rule1MinTrailingSilence float32 `protobuf:"fixed32,10,opt,name=rule1_min_trailing_silence,json=rule1MinTrailingSilence,proto3" json:"rule1_min_trailing_silence,omitempty"`
+	Rule2MinTrailingSilence float32 `protobuf:"fixed32,11,opt,name=rule2_min_trailing_silence,json=rule2MinTrailingSilence,proto3" json:"rule2_min_trailing_silence,omitempty"`
+	Rule3MinUtteranceLength float32 `protobuf:"fixed32,12,opt,name=rule3_min_utterance_length,json=rule3MinUtteranceLength,proto3" json:"rule3_min_utterance_length,omitempty"`
+	// 是否在结果中返回词和 token 的时间戳，模型不提供时间戳时调用返回 INVALID_ARGUMENT
+	Timestamps    bool `protobuf:"varint,13,opt,name=timestamps,proto3" json:"timestamps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamingConfig) Reset() {
+	*x = StreamingConfig{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamingConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingConfig) ProtoMessage() {}
+
+func (x *StreamingConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingConfig.ProtoReflect.Descriptor instead.
+func (*StreamingConfig) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamingConfig) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *StreamingConfig) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *StreamingConfig) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *StreamingConfig) GetPartialResults() bool {
+	if x != nil && x.PartialResults != nil {
+		return *x.PartialResults
+	}
+	return false
+}
+
+func (x *StreamingConfig) GetHotwords() []string {
+	if x != nil {
+		return x.Hotwords
+	}
+	return nil
+}
+
+func (x *StreamingConfig) GetHotwordsScore() float32 {
+	if x != nil {
+		return x.HotwordsScore
+	}
+	return 0
+}
+
+func (x *StreamingConfig) GetDecodingMethod() string {
+	if x != nil {
+		return x.DecodingMethod
+	}
+	return ""
+}
+
+func (x *StreamingConfig) GetMaxActivePaths() int32 {
+	if x != nil {
+		return x.MaxActivePaths
+	}
+	return 0
+}
+
+func (x *StreamingConfig) GetEnableEndpoint() bool {
+	if x != nil && x.EnableEndpoint != nil {
+		return *x.EnableEndpoint
+	}
+	return false
+}
+
+func (x *StreamingConfig) GetRule1MinTrailingSilence() float32 {
+	if x != nil {
+		return x.Rule1MinTrailingSilence
+	}
+	return 0
+}
+
+func (x *StreamingConfig) GetRule2MinTrailingSilence() float32 {
+	if x != nil {
+		return x.Rule2MinTrailingSilence
+	}
+	return 0
+}
+
+func (x *StreamingConfig) GetRule3MinUtteranceLength() float32 {
+	if x != nil {
+		return x.Rule3MinUtteranceLength
+	}
+	return 0
+}
+
+func (x *StreamingConfig) GetTimestamps() bool {
+	if x != nil {
+		return x.Timestamps
+	}
+	return false
+}
+
+type Flush struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Flush) Reset() {
+	*x = Flush{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Flush) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Flush) ProtoMessage() {}
+
+func (x *Flush) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Flush.ProtoReflect.Descriptor instead.
+func (*Flush) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{8}
+}
+
+type StreamingRecognizeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*StreamingRecognizeResponse_Ready
+	//	*StreamingRecognizeResponse_Result
+	Event         isStreamingRecognizeResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamingRecognizeResponse) Reset() {
+	*x = StreamingRecognizeResponse{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamingRecognizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamingRecognizeResponse) ProtoMessage() {}
+
+func (x *StreamingRecognizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamingRecognizeResponse.ProtoReflect.Descriptor instead.
+func (*StreamingRecognizeResponse) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamingRecognizeResponse) GetEvent() isStreamingRecognizeResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeResponse) GetReady() *Ready {
+	if x != nil {
+		if x, ok := x.Event.(*StreamingRecognizeResponse_Ready); ok {
+			return x.Ready
+		}
+	}
+	return nil
+}
+
+func (x *StreamingRecognizeResponse) GetResult() *Result {
+	if x != nil {
+		if x, ok := x.Event.(*StreamingRecognizeResponse_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isStreamingRecognizeResponse_Event interface {
+	isStreamingRecognizeResponse_Event()
+}
+
+type StreamingRecognizeResponse_Ready struct {
+	// 服务端接受 config 后发送的第一条消息
+	Ready *Ready `protobuf:"bytes,1,opt,name=ready,proto3,oneof"`
+}
+
+type StreamingRecognizeResponse_Result struct {
+	// 识别结果
+	Result *Result `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*StreamingRecognizeResponse_Ready) isStreamingRecognizeResponse_Event() {}
+
+func (*StreamingRecognizeResponse_Result) isStreamingRecognizeResponse_Event() {}
+
+type Ready struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Version int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// 客户端音频的采样率
+	SampleRate int32 `protobuf:"varint,2,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	// 模型采样率，音频会在服务端重采样到该采样率
+	ModelSampleRate int32  `protobuf:"varint,3,opt,name=model_sample_rate,json=modelSampleRate,proto3" json:"model_sample_rate,omitempty"`
+	Format          string `protobuf:"bytes,4,opt,name=format,proto3" json:"format,omitempty"`
+	Language        string `protobuf:"bytes,5,opt,name=language,proto3" json:"language,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Ready) Reset() {
+	*x = Ready{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ready) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ready) ProtoMessage() {}
+
+func (x *Ready) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ready.ProtoReflect.Descriptor instead.
+func (*Ready) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Ready) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Ready) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *Ready) GetModelSampleRate() int32 {
+	if x != nil {
+		return x.ModelSampleRate
+	}
+	return 0
+}
+
+func (x *Ready) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *Ready) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+type Result struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// final 为 true 表示句子已经结束，之后不会再变化
+	Final bool `protobuf:"varint,1,opt,name=final,proto3" json:"final,omitempty"`
+	// 句子序号，从 0 开始，同一句子的部分结果和最终结果相同
+	Utterance int32  `protobuf:"varint,2,opt,name=utterance,proto3" json:"utterance,omitempty"`
+	Text      string `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	// 句子在会话音频中的起止时间（秒）
+	Start float64 `protobuf:"fixed64,4,opt,name=start,proto3" json:"start,omitempty"`
+	End   float64 `protobuf:"fixed64,5,opt,name=end,proto3" json:"end,omitempty"`
+	// 句子中各 token 概率的几何平均，只有 transducer 模型提供，其他模型为 0
+	Confidence float64 `protobuf:"fixed64,6,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	// 只在 config 请求时间戳时返回，时间相对于会话音频的开始
+	Words         []*Word  `protobuf:"bytes,7,rep,name=words,proto3" json:"words,omitempty"`
+	Tokens        []*Token `protobuf:"bytes,8,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_transcribe_v1_transcribe_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_transcribe_v1_transcribe_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Result) GetFinal() bool {
+	if x != nil {
+		return x.Final
+	}
+	return false
+}
+
+func (x *Result) GetUtterance() int32 {
+	if x != nil {
+		return x.Utterance
+	}
+	return 0
+}
+
+func (x *Result) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Result) GetStart() float64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Result) GetEnd() float64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Result) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Result) GetWords() []*Word {
+	if x != nil {
+		return x.Words
+	}
+	return nil
+}
+
+func (x *Result) GetTokens() []*Token {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+var File_transcribe_v1_transcribe_proto protoreflect.FileDescriptor
+
+const file_transcribe_v1_transcribe_proto_rawDesc = "" +
+	"\n" +
+	"\x1etranscribe/v1/transcribe.proto\x12\rtranscribe.v1\"\xab\x02\n" +
+	"\x10RecognizeRequest\x12\x14\n" +
+	"\x05audio\x18\x01 \x01(\fR\x05audio\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12\x1f\n" +
+	"\vsample_rate\x18\x03 \x01(\x05R\n" +
+	"sampleRate\x12\x12\n" +
+	"\x04mode\x18\x04 \x01(\tR\x04mode\x12\x1e\n" +
+	"\n" +
+	"timestamps\x18\x05 \x01(\bR\n" +
+	"timestamps\x12\x1a\n" +
+	"\bhotwords\x18\x06 \x03(\tR\bhotwords\x12%\n" +
+	"\x0ehotwords_score\x18\a \x01(\x02R\rhotwordsScore\x12'\n" +
+	"\x0fdecoding_method\x18\b \x01(\tR\x0edecodingMethod\x12(\n" +
+	"\x10max_active_paths\x18\t \x01(\x05R\x0emaxActivePaths\"\xba\x02\n" +
+	"\x11RecognizeResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x02 \x01(\x01R\n" +
+	"confidence\x12\x1a\n" +
+	"\bduration\x18\x03 \x01(\x01R\bduration\x12H\n" +
+	"\x10speaker_segments\x18\x04 \x03(\v2\x1d.transcribe.v1.SpeakerSegmentR\x0fspeakerSegments\x122\n" +
+	"\bsegments\x18\x05 \x03(\v2\x16.transcribe.v1.SegmentR\bsegments\x12)\n" +
+	"\x05words\x18\x06 \x03(\v2\x13.transcribe.v1.WordR\x05words\x12,\n" +
+	"\x06tokens\x18\a \x03(\v2\x14.transcribe.v1.TokenR\x06tokens\"\x8b\x01\n" +
+	"\x0eSpeakerSegment\x12\x1d\n" +
+	"\n" +
+	"speaker_id\x18\x01 \x01(\x05R\tspeakerId\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x01R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x01R\x03end\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x05 \x01(\x01R\n" +
+	"confidence\"e\n" +
+	"\aSegment\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x01R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x01R\x03end\x12\x12\n" +
+	"\x04text\x18\x03 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x04 \x01(\x01R\n" +
+	"confidence\"b\n" +
+	"\x04Word\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x01R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x01R\x03end\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x04 \x01(\x01R\n" +
+	"confidence\"c\n" +
+	"\x05Token\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x01R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x01R\x03end\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x04 \x01(\x01R\n" +
+	"confidence\"\xa6\x01\n" +
+	"\x19StreamingRecognizeRequest\x128\n" +
+	"\x06config\x18\x01 \x01(\v2\x1e.transcribe.v1.StreamingConfigH\x00R\x06config\x12\x16\n" +
+	"\x05audio\x18\x02 \x01(\fH\x00R\x05audio\x12,\n" +
+	"\x05flush\x18\x03 \x01(\v2\x14.transcribe.v1.FlushH\x00R\x05flushB\t\n" +
+	"\arequest\"\xd7\x04\n" +
+	"\x0fStreamingConfig\x12\x1f\n" +
+	"\vsample_rate\x18\x01 \x01(\x05R\n" +
+	"sampleRate\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12\x1a\n" +
+	"\blanguage\x18\x03 \x01(\tR\blanguage\x12,\n" +
+	"\x0fpartial_results\x18\x04 \x01(\bH\x00R\x0epartialResults\x88\x01\x01\x12\x1a\n" +
+	"\bhotwords\x18\x05 \x03(\tR\bhotwords\x12%\n" +
+	"\x0ehotwords_score\x18\x06 \x01(\x02R\rhotwordsScore\x12'\n" +
+	"\x0fdecoding_method\x18\a \x01(\tR\x0edecodingMethod\x12(\n" +
+	"\x10max_active_paths\x18\b \x01(\x05R\x0emaxActivePaths\x12,\n" +
+	"\x0fenable_endpoint\x18\t \x01(\bH\x01R\x0eenableEndpoint\x88\x01\x01\x12;\n" +
+	"\x1arule1_min_trailing_silence\x18\n" +
+	" \x01(\x02R\x17rule1MinTrailingSilence\x12;\n" +
+	"\x1arule2_min_trailing_silence\x18\v \x01(\x02R\x17rule2MinTrailingSilence\x12;\n" +
+	"\x1arule3_min_utterance_length\x18\f \x01(\x02R\x17rule3MinUtteranceLength\x12\x1e\n" +
+	"\n" +
+	"timestamps\x18\r \x01(\bR\n" +
+	"timestampsB\x12\n" +
+	"\x10_partial_resultsB\x12\n" +
+	"\x10_enable_endpoint\"\a\n" +
+	"\x05Flush\"\x84\x01\n" +
+	"\x1aStreamingRecognizeResponse\x12,\n" +
+	"\x05ready\x18\x01 \x01(\v2\x14.transcribe.v1.ReadyH\x00R\x05ready\x12/\n" +
+	"\x06result\x18\x02 \x01(\v2\x15.transcribe.v1.ResultH\x00R\x06resultB\a\n" +
+	"\x05event\"\xa2\x01\n" +
+	"\x05Ready\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12\x1f\n" +
+	"\vsample_rate\x18\x02 \x01(\x05R\n" +
+	"sampleRate\x12*\n" +
+	"\x11model_sample_rate\x18\x03 \x01(\x05R\x0fmodelSampleRate\x12\x16\n" +
+	"\x06format\x18\x04 \x01(\tR\x06format\x12\x1a\n" +
+	"\blanguage\x18\x05 \x01(\tR\blanguage\"\xf1\x01\n" +
+	"\x06Result\x12\x14\n" +
+	"\x05final\x18\x01 \x01(\bR\x05final\x12\x1c\n" +
+	"\tutterance\x18\x02 \x01(\x05R\tutterance\x12\x12\n" +
+	"\x04text\x18\x03 \x01(\tR\x04text\x12\x14\n" +
+	"\x05start\x18\x04 \x01(\x01R\x05start\x12\x10\n" +
+	"\x03end\x18\x05 \x01(\x01R\x03end\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x06 \x01(\x01R\n" +
+	"confidence\x12)\n" +
+	"\x05words\x18\a \x03(\v2\x13.transcribe.v1.WordR\x05words\x12,\n" +
+	"\x06tokens\x18\b \x03(\v2\x14.transcribe.v1.TokenR\x06tokens2\xcc\x01\n" +
+	"\vTranscriber\x12N\n" +
+	"\tRecognize\x12\x1f.transcribe.v1.RecognizeRequest\x1a .transcribe.v1.RecognizeResponse\x12m\n" +
+	"\x12StreamingRecognize\x12(.transcribe.v1.StreamingRecognizeRequest\x1a).transcribe.v1.StreamingRecognizeResponse(\x010\x01B?Z=github.com/layzdonw/transerver/api/transcribe/v1;transcribev1b\x06proto3"
+
+var (
+	file_transcribe_v1_transcribe_proto_rawDescOnce sync.Once
+	file_transcribe_v1_transcribe_proto_rawDescData []byte
+)
+
+func file_transcribe_v1_transcribe_proto_rawDescGZIP() []byte {
+	file_transcribe_v1_transcribe_proto_rawDescOnce.Do(func() {
+		file_transcribe_v1_transcribe_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_transcribe_v1_transcribe_proto_rawDesc), len(file_transcribe_v1_transcribe_proto_rawDesc)))
+	})
+	return file_transcribe_v1_transcribe_proto_rawDescData
+}
+
+var file_transcribe_v1_transcribe_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_transcribe_v1_transcribe_proto_goTypes = []any{
+	(*RecognizeRequest)(nil),           // 0: transcribe.v1.RecognizeRequest
+	(*RecognizeResponse)(nil),          // 1: transcribe.v1.RecognizeResponse
+	(*SpeakerSegment)(nil),             // 2: transcribe.v1.SpeakerSegment
+	(*Segment)(nil),                    // 3: transcribe.v1.Segment
+	(*Word)(nil),                       // 4: transcribe.v1.Word
+	(*Token)(nil),                      // 5: transcribe.v1.Token
+	(*StreamingRecognizeRequest)(nil),  // 6: transcribe.v1.StreamingRecognizeRequest
+	(*StreamingConfig)(nil),            // 7: transcribe.v1.StreamingConfig
+	(*Flush)(nil),                      // 8: transcribe.v1.Flush
+	(*StreamingRecognizeResponse)(nil), // 9: transcribe.v1.StreamingRecognizeResponse
+	(*Ready)(nil),                      // 10: transcribe.v1.Ready
+	(*Result)(nil),                     // 11: transcribe.v1.Result
+}
+var file_transcribe_v1_transcribe_proto_depIdxs = []int32{
+	2,  // 0: transcribe.v1.RecognizeResponse.speaker_segments:type_name -> transcribe.v1.SpeakerSegment
+	3,  // 1: transcribe.v1.RecognizeResponse.segments:type_name -> transcribe.v1.Segment
+	4,  // 2: transcribe.v1.RecognizeResponse.words:type_name -> transcribe.v1.Word
+	5,  // 3: transcribe.v1.RecognizeResponse.tokens:type_name -> transcribe.v1.Token
+	7,  // 4: transcribe.v1.StreamingRecognizeRequest.config:type_name -> transcribe.v1.StreamingConfig
+	8,  // 5: transcribe.v1.StreamingRecognizeRequest.flush:type_name -> transcribe.v1.Flush
+	10, // 6: transcribe.v1.StreamingRecognizeResponse.ready:type_name -> transcribe.v1.Ready
+	11, // 7: transcribe.v1.StreamingRecognizeResponse.result:type_name -> transcribe.v1.Result
+	4,  // 8: transcribe.v1.Result.words:type_name -> transcribe.v1.Word
+	5,  // 9: transcribe.v1.Result.tokens:type_name -> transcribe.v1.Token
+	0,  // 10: transcribe.v1.Transcriber.Recognize:input_type -> transcribe.v1.RecognizeRequest
+	6,  // 11: transcribe.v1.Transcriber.StreamingRecognize:input_type -> transcribe.v1.StreamingRecognizeRequest
+	1,  // 12: transcribe.v1.Transcriber.Recognize:output_type -> transcribe.v1.RecognizeResponse
+	9,  // 13: transcribe.v1.Transcriber.StreamingRecognize:output_type -> transcribe.v1.StreamingRecognizeResponse
+	12, // [12:14] is the sub-list for method output_type
+	10, // [10:12] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_transcribe_v1_transcribe_proto_init() }
+func file_transcribe_v1_transcribe_proto_init() {
+	if File_transcribe_v1_transcribe_proto != nil {
+		return
+	}
+	file_transcribe_v1_transcribe_proto_msgTypes[6].OneofWrappers = []any{
+		(*StreamingRecognizeRequest_Config)(nil),
+		(*StreamingRecognizeRequest_Audio)(nil),
+		(*StreamingRecognizeRequest_Flush)(nil),
+	}
+	file_transcribe_v1_transcribe_proto_msgTypes[7].OneofWrappers = []any{}
+	file_transcribe_v1_transcribe_proto_msgTypes[9].OneofWrappers = []any{
+		(*StreamingRecognizeResponse_Ready)(nil),
+		(*StreamingRecognizeResponse_Result)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_transcribe_v1_transcribe_proto_rawDesc), len(file_transcribe_v1_transcribe_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_transcribe_v1_transcribe_proto_goTypes,
+		DependencyIndexes: file_transcribe_v1_transcribe_proto_depIdxs,
+		MessageInfos:      file_transcribe_v1_transcribe_proto_msgTypes,
+	}.Build()
+	File_transcribe_v1_transcribe_proto = out.File
+	file_transcribe_v1_transcribe_proto_goTypes = nil
+	file_transcribe_v1_transcribe_proto_depIdxs = nil
+}
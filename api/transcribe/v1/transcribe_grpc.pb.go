@@ -0,0 +1,165 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: transcribe/v1/transcribe.proto
+
+// 转录服务的 gRPC 接口，与 HTTP 接口使用同一个转录引擎
+// 修改后运行 go generate ./api/... 重新生成 Go 代码
+
+package transcribev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Transcriber_Recognize_FullMethodName          = "/transcribe.v1.Transcriber/Recognize"
+	Transcriber_StreamingRecognize_FullMethodName = "/transcribe.v1.Transcriber/StreamingRecognize"
+)
+
+// TranscriberClient is the client API for Transcriber service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TranscriberClient interface {
+	// Recognize 转录一段完整音频，与 POST /transcribe 相同
+	Recognize(ctx context.Context, in *RecognizeRequest, opts ...grpc.CallOption) (*RecognizeResponse, error)
+	// StreamingRecognize 流式识别，与 /ws/realtime 相同
+	// 第一条消息必须是 config，之后发送音频和 flush；客户端关闭发送端相当于 stop，
+	// 服务端返回剩余的 final 结果后结束调用
+	StreamingRecognize(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[StreamingRecognizeRequest, StreamingRecognizeResponse], error)
+}
+
+type transcriberClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTranscriberClient(cc grpc.ClientConnInterface) TranscriberClient {
+	return &transcriberClient{cc}
+}
+
+func (c *transcriberClient) Recognize(ctx context.Context, in *RecognizeRequest, opts ...grpc.CallOption) (*RecognizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecognizeResponse)
+	err := c.cc.Invoke(ctx, Transcriber_Recognize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transcriberClient) StreamingRecognize(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[StreamingRecognizeRequest, StreamingRecognizeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Transcriber_ServiceDesc.Streams[0], Transcriber_StreamingRecognize_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamingRecognizeRequest, StreamingRecognizeResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Transcriber_StreamingRecognizeClient = grpc.BidiStreamingClient[StreamingRecognizeRequest, StreamingRecognizeResponse]
+
+// TranscriberServer is the server API for Transcriber service.
+// All implementations must embed UnimplementedTranscriberServer
+// for forward compatibility.
+type TranscriberServer interface {
+	// Recognize 转录一段完整音频，与 POST /transcribe 相同
+	Recognize(context.Context, *RecognizeRequest) (*RecognizeResponse, error)
+	// StreamingRecognize 流式识别，与 /ws/realtime 相同
+	// 第一条消息必须是 config，之后发送音频和 flush；客户端关闭发送端相当于 stop，
+	// 服务端返回剩余的 final 结果后结束调用
+	StreamingRecognize(grpc.BidiStreamingServer[StreamingRecognizeRequest, StreamingRecognizeResponse]) error
+	mustEmbedUnimplementedTranscriberServer()
+}
+
+// UnimplementedTranscriberServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTranscriberServer struct{}
+
+func (UnimplementedTranscriberServer) Recognize(context.Context, *RecognizeRequest) (*RecognizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recognize not implemented")
+}
+func (UnimplementedTranscriberServer) StreamingRecognize(grpc.BidiStreamingServer[StreamingRecognizeRequest, StreamingRecognizeResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamingRecognize not implemented")
+}
+func (UnimplementedTranscriberServer) mustEmbedUnimplementedTranscriberServer() {}
+func (UnimplementedTranscriberServer) testEmbeddedByValue()                     {}
+
+// UnsafeTranscriberServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TranscriberServer will
+// result in compilation errors.
+type UnsafeTranscriberServer interface {
+	mustEmbedUnimplementedTranscriberServer()
+}
+
+func RegisterTranscriberServer(s grpc.ServiceRegistrar, srv TranscriberServer) {
+	// If the following call pancis, it indicates UnimplementedTranscriberServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Transcriber_ServiceDesc, srv)
+}
+
+func _Transcriber_Recognize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecognizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TranscriberServer).Recognize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transcriber_Recognize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TranscriberServer).Recognize(ctx, req.(*RecognizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transcriber_StreamingRecognize_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TranscriberServer).StreamingRecognize(&grpc.GenericServerStream[StreamingRecognizeRequest, StreamingRecognizeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Transcriber_StreamingRecognizeServer = grpc.BidiStreamingServer[StreamingRecognizeRequest, StreamingRecognizeResponse]
+
+// Transcriber_ServiceDesc is the grpc.ServiceDesc for Transcriber service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Transcriber_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "transcribe.v1.Transcriber",
+	HandlerType: (*TranscriberServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Recognize",
+			Handler:    _Transcriber_Recognize_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamingRecognize",
+			Handler:       _Transcriber_StreamingRecognize_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "transcribe/v1/transcribe.proto",
+}
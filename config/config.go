@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -9,6 +10,8 @@ import (
 type Config struct {
 	Server ServerConfig `mapstructure:"server"`
 	Sherpa SherpaConfig `mapstructure:"sherpa"`
+	Pool   PoolConfig   `mapstructure:"pool"`
+	Jobs   JobsConfig   `mapstructure:"jobs"`
 }
 
 type ServerConfig struct {
@@ -16,16 +19,141 @@ type ServerConfig struct {
 	UnixSocket    string `mapstructure:"unix_socket"`
 	UseUnixSocket bool   `mapstructure:"use_unix_socket"`
 	Host          string `mapstructure:"host"`
+	// MaxUploadMB /transcribe 和 /jobs 请求体的大小上限（MB），0 表示不限制
+	MaxUploadMB int64      `mapstructure:"max_upload_mb"`
+	GRPC        GRPCConfig `mapstructure:"grpc"`
+}
+
+// GRPCConfig gRPC 服务配置，与 HTTP 服务使用相同的 host 和 use_unix_socket
+type GRPCConfig struct {
+	// Enabled 是否启动 gRPC 服务，默认不启用
+	Enabled bool `mapstructure:"enabled"`
+	// Port gRPC 服务端口
+	Port int `mapstructure:"port"`
+	// UnixSocket use_unix_socket 为 true 时 gRPC 服务使用的 socket 文件
+	UnixSocket string `mapstructure:"unix_socket"`
 }
 
 type SherpaConfig struct {
-	ModelPath            string `mapstructure:"model_path"`
-	TokensPath           string `mapstructure:"tokens_path"`
-	SampleRate           int    `mapstructure:"sample_rate"`
-	NumThreads           int    `mapstructure:"num_threads"`
-	DecodingMethod       string `mapstructure:"decoding_method"`
-	EnableDiarization    bool   `mapstructure:"enable_diarization"`
-	DiarizationModelPath string `mapstructure:"diarization_model_path"`
+	// ModelType 模型类型：transducer、paraformer、zipformer2-ctc、whisper、sense-voice、nemo-ctc、
+	// moonshine、fire-red-asr、dolphin、tdnn
+	ModelType  string `mapstructure:"model_type"`
+	ModelPath  string `mapstructure:"model_path"`
+	TokensPath string `mapstructure:"tokens_path"`
+	ModelFiles `mapstructure:",squash"`
+	SampleRate int `mapstructure:"sample_rate"`
+	NumThreads int `mapstructure:"num_threads"`
+	// DecodingMethod 解码方法：greedy_search 或 modified_beam_search（只支持 transducer 模型）
+	DecodingMethod string `mapstructure:"decoding_method"`
+	// MaxActivePaths modified_beam_search 保留的路径数（beam 大小），1 到 32
+	MaxActivePaths int `mapstructure:"max_active_paths"`
+	// EnableEndpoint 流式识别的端点检测，关闭后句子只在 flush 或音频结束时结束
+	EnableEndpoint bool `mapstructure:"enable_endpoint"`
+	// Rule1MinTrailingSilence 还没有识别出文字时结束句子的尾部静音（秒）
+	Rule1MinTrailingSilence float32 `mapstructure:"rule1_min_trailing_silence"`
+	// Rule2MinTrailingSilence 识别出文字后结束句子的尾部静音（秒）
+	Rule2MinTrailingSilence float32 `mapstructure:"rule2_min_trailing_silence"`
+	// Rule3MinUtteranceLength 句子达到该时长（秒）时强制结束
+	Rule3MinUtteranceLength float32 `mapstructure:"rule3_min_utterance_length"`
+	EnableDiarization       bool    `mapstructure:"enable_diarization"`
+	DiarizationModelPath    string  `mapstructure:"diarization_model_path"`
+	// BatchMode /transcribe 默认使用的识别模式：online 或 offline
+	// 为空时流式模型使用 online，只有离线实现的模型使用 offline
+	BatchMode string `mapstructure:"batch_mode"`
+	// HotwordsFile 热词文件，每行一个热词，需要 transducer 模型和 modified_beam_search 解码
+	HotwordsFile string `mapstructure:"hotwords_file"`
+	// HotwordsScore 热词的默认加分
+	HotwordsScore float32 `mapstructure:"hotwords_score"`
+	// ModelingUnit 热词的切分方式：cjkchar、bpe 或 cjkchar+bpe；bpe 需要 BpeVocab
+	ModelingUnit string `mapstructure:"modeling_unit"`
+	BpeVocab     string `mapstructure:"bpe_vocab"`
+	// CustomRecognizers 请求带热词时额外创建的识别器数量上限，每个识别器都会另外加载一份模型
+	CustomRecognizers int           `mapstructure:"custom_recognizers"`
+	Offline           OfflineConfig `mapstructure:"offline"`
+	VAD               VADConfig     `mapstructure:"vad"`
+}
+
+// VADConfig 批量转录的语音检测切分
+type VADConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ModelPath Silero VAD 模型文件（silero_vad.onnx），为空时使用基于能量的检测
+	ModelPath string `mapstructure:"model_path"`
+	// Threshold Silero VAD 的语音概率阈值
+	Threshold float32 `mapstructure:"threshold"`
+	// MinSilence 结束一句话所需的最短静音（秒）
+	MinSilence float64 `mapstructure:"min_silence"`
+	// MinSpeech 最短语音（秒）
+	MinSpeech float64 `mapstructure:"min_speech"`
+	// MaxSegment 最长句子（秒）
+	MaxSegment float64 `mapstructure:"max_segment"`
+	// Workers 同时识别的句子数量
+	Workers int `mapstructure:"workers"`
+}
+
+// OfflineConfig 批量转录使用的离线（非流式）识别模型
+type OfflineConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ModelType 模型类型，取值与 sherpa.model_type 相同
+	ModelType  string `mapstructure:"model_type"`
+	ModelPath  string `mapstructure:"model_path"`
+	TokensPath string `mapstructure:"tokens_path"`
+	ModelFiles `mapstructure:",squash"`
+}
+
+// ModelFiles 模型文件路径，为空时使用 model_path 目录下的默认文件名
+type ModelFiles struct {
+	Encoder         string `mapstructure:"encoder"`
+	Decoder         string `mapstructure:"decoder"`
+	Joiner          string `mapstructure:"joiner"`
+	Model           string `mapstructure:"model"`
+	Preprocessor    string `mapstructure:"preprocessor"`
+	UncachedDecoder string `mapstructure:"uncached_decoder"`
+	CachedDecoder   string `mapstructure:"cached_decoder"`
+	// Language whisper 和 sense-voice 的识别语言，为空时自动检测
+	Language string `mapstructure:"language"`
+}
+
+// PoolConfig 解码并发控制
+type PoolConfig struct {
+	// Workers 同时进行的解码数量
+	Workers int `mapstructure:"workers"`
+	// QueueSize 排队请求数量上限，超过时返回 429
+	QueueSize int `mapstructure:"queue_size"`
+	// QueueTimeout 排队等待的最长时间，超时返回 503
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+	// MaxSessions 实时转录会话数量上限，0 表示不限制
+	MaxSessions int `mapstructure:"max_sessions"`
+}
+
+// JobsConfig 异步转录任务
+type JobsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store 任务存储：memory（重启后丢失，音频写入临时文件）或 disk
+	Store string `mapstructure:"store"`
+	// Dir disk 存储保存任务和音频的目录
+	Dir string `mapstructure:"dir"`
+	// Workers 同时处理的任务数量，解码仍然受 pool 限制
+	Workers int `mapstructure:"workers"`
+	// QueueSize 排队任务数量上限，超过时返回 429，0 表示不限制
+	QueueSize int `mapstructure:"queue_size"`
+	// Retention 任务结束后保留状态和结果的时间，超过后删除，0 表示一直保留
+	Retention time.Duration `mapstructure:"retention"`
+	Webhook   WebhookConfig `mapstructure:"webhook"`
+}
+
+// WebhookConfig 任务结束时的回调
+type WebhookConfig struct {
+	// Secret 回调签名密钥，为空时不接受 callback_url
+	Secret string `mapstructure:"secret"`
+	// MaxAttempts 最多发送次数
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// InitialBackoff 第一次重试前的等待时间，之后每次加倍，不超过 MaxBackoff
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	// Timeout 单次请求的超时时间
+	Timeout time.Duration `mapstructure:"timeout"`
+	// AllowPrivateNetworks 允许回调回环、私有和链路本地地址
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
 }
 
 var AppConfig Config
@@ -39,11 +167,49 @@ func LoadConfig(configPath string) error {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.use_unix_socket", false)
 	viper.SetDefault("server.unix_socket", "/tmp/transcribe.sock")
+	viper.SetDefault("server.max_upload_mb", 1024)
+	viper.SetDefault("server.grpc.enabled", false)
+	viper.SetDefault("server.grpc.port", 9090)
+	viper.SetDefault("server.grpc.unix_socket", "/tmp/transcribe-grpc.sock")
+	viper.SetDefault("sherpa.model_type", "transducer")
 	viper.SetDefault("sherpa.sample_rate", 16000)
 	viper.SetDefault("sherpa.num_threads", 1)
 	viper.SetDefault("sherpa.decoding_method", "greedy_search")
+	viper.SetDefault("sherpa.max_active_paths", 4)
+	viper.SetDefault("sherpa.enable_endpoint", true)
+	viper.SetDefault("sherpa.rule1_min_trailing_silence", 2.4)
+	viper.SetDefault("sherpa.rule2_min_trailing_silence", 1.2)
+	viper.SetDefault("sherpa.rule3_min_utterance_length", 20)
 	viper.SetDefault("sherpa.enable_diarization", false)
 	viper.SetDefault("sherpa.diarization_model_path", "")
+	viper.SetDefault("sherpa.batch_mode", "")
+	viper.SetDefault("sherpa.hotwords_file", "")
+	viper.SetDefault("sherpa.hotwords_score", 1.5)
+	viper.SetDefault("sherpa.modeling_unit", "cjkchar")
+	viper.SetDefault("sherpa.custom_recognizers", 2)
+	viper.SetDefault("sherpa.offline.enabled", false)
+	viper.SetDefault("sherpa.vad.enabled", false)
+	viper.SetDefault("sherpa.vad.threshold", 0.5)
+	viper.SetDefault("sherpa.vad.min_silence", 0.5)
+	viper.SetDefault("sherpa.vad.min_speech", 0.25)
+	viper.SetDefault("sherpa.vad.max_segment", 30)
+	viper.SetDefault("sherpa.vad.workers", 1)
+	viper.SetDefault("pool.workers", 2)
+	viper.SetDefault("pool.queue_size", 16)
+	viper.SetDefault("pool.queue_timeout", "30s")
+	viper.SetDefault("pool.max_sessions", 0)
+	viper.SetDefault("jobs.enabled", true)
+	viper.SetDefault("jobs.store", "memory")
+	viper.SetDefault("jobs.dir", "data/jobs")
+	viper.SetDefault("jobs.workers", 1)
+	viper.SetDefault("jobs.queue_size", 100)
+	viper.SetDefault("jobs.retention", "24h")
+	viper.SetDefault("jobs.webhook.secret", "")
+	viper.SetDefault("jobs.webhook.max_attempts", 5)
+	viper.SetDefault("jobs.webhook.initial_backoff", "1s")
+	viper.SetDefault("jobs.webhook.max_backoff", "1m")
+	viper.SetDefault("jobs.webhook.timeout", "10s")
+	viper.SetDefault("jobs.webhook.allow_private_networks", false)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Printf("无法读取配置文件: %v", err)
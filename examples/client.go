@@ -8,6 +8,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
 )
 
 type TranscribeResponse struct {
@@ -61,7 +62,8 @@ func uploadAndTranscribe(serverURL, audioFile string) (string, error) {
 	writer := multipart.NewWriter(&buf)
 
 	// 添加音频文件
-	part, err := writer.CreateFormFile("audio", "audio.wav")
+	// 使用原始文件名，服务端会结合扩展名和文件头识别格式
+	part, err := writer.CreateFormFile("audio", filepath.Base(audioFile))
 	if err != nil {
 		return "", fmt.Errorf("创建表单文件失败: %v", err)
 	}
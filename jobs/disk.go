@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var _ Store = (*DiskStore)(nil)
+
+// DiskStore 保存在磁盘上的任务存储，重启后任务仍然存在
+// 每个任务一个目录：<dir>/<id>/job.json 保存状态，<dir>/<id>/audio 保存音频
+type DiskStore struct {
+	dir string
+	// mu 保证 job.json 的读写不会交错
+	mu sync.RWMutex
+}
+
+// NewDiskStore 使用 dir 目录保存任务，目录不存在时创建
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("无法创建任务目录: %v", err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// jobDir 返回任务目录，ID 不能包含路径分隔符
+func (s *DiskStore) jobDir(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+func (s *DiskStore) Create(job *Job, audio io.Reader) error {
+	dir, err := s.jobDir(job.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return fmt.Errorf("无法创建任务目录: %v", err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "audio"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if _, err := io.Copy(f, audio); err != nil {
+		f.Close()
+		os.RemoveAll(dir)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(dir, job); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	return nil
+}
+
+// write 先写临时文件再重命名，避免崩溃时留下不完整的 job.json
+func (s *DiskStore) write(dir string, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, "job.json.tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "job.json"))
+}
+
+func (s *DiskStore) read(dir string) (*Job, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "job.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("无法解析任务 %s: %v", filepath.Base(dir), err)
+	}
+	return &job, nil
+}
+
+func (s *DiskStore) Get(id string) (*Job, error) {
+	dir, err := s.jobDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.read(dir)
+}
+
+func (s *DiskStore) Update(job *Job) error {
+	dir, err := s.jobDir(job.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, "job.json")); err != nil {
+		return ErrNotFound
+	}
+	return s.write(dir, job)
+}
+
+func (s *DiskStore) Audio(id string) (io.ReadCloser, error) {
+	dir, err := s.jobDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, "audio"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *DiskStore) DeleteAudio(id string) error {
+	dir, err := s.jobDir(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(dir); err != nil {
+		return ErrNotFound
+	}
+	if err := os.Remove(filepath.Join(dir, "audio")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *DiskStore) Delete(id string) error {
+	dir, err := s.jobDir(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(dir); err != nil {
+		return ErrNotFound
+	}
+	return os.RemoveAll(dir)
+}
+
+func (s *DiskStore) List() ([]*Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []*Job
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		job, err := s.read(filepath.Join(s.dir, entry.Name()))
+		if err == ErrNotFound {
+			// 创建到一半的任务
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sortByCreation(jobs)
+	return jobs, nil
+}
+
+// Close 任务都保存在磁盘上，没有需要释放的资源
+func (s *DiskStore) Close() error {
+	return nil
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+var (
+	// ErrNotFound 任务不存在
+	ErrNotFound = errors.New("任务不存在")
+	// ErrQueueFull 排队的任务已达上限
+	ErrQueueFull = errors.New("任务队列已满")
+	// ErrClosed 任务管理器已关闭
+	ErrClosed = errors.New("任务管理器已关闭")
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done 返回任务是否已经结束
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Options 任务的转录选项，含义与 /transcribe 的同名字段相同
+type Options struct {
+	Format     string `json:"format,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+	Timestamps bool   `json:"timestamps,omitempty"`
+	// Hotwords 额外的热词，HotwordsScore 为热词加分
+	Hotwords      []string `json:"hotwords,omitempty"`
+	HotwordsScore float32  `json:"hotwords_score,omitempty"`
+	// DecodingMethod 解码方法，MaxActivePaths 为 modified_beam_search 保留的路径数
+	DecodingMethod string `json:"decoding_method,omitempty"`
+	MaxActivePaths int    `json:"max_active_paths,omitempty"`
+	// CallbackURL 任务结束时回调的地址，为空时不回调
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+func (o Options) transcribeOptions() transcribe.TranscribeOptions {
+	return transcribe.TranscribeOptions{
+		Format:     o.Format,
+		SampleRate: o.SampleRate,
+		Mode:       o.Mode,
+		Timestamps: o.Timestamps,
+		DecodingOptions: transcribe.DecodingOptions{
+			Hotwords:       o.Hotwords,
+			HotwordsScore:  o.HotwordsScore,
+			DecodingMethod: o.DecodingMethod,
+			MaxActivePaths: o.MaxActivePaths,
+		},
+	}
+}
+
+// Job 一个异步转录任务
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	// Progress 完成比例，0 到 1，按已解码的音频字节数估计，说话人分离时随识别的片段更新
+	Progress float64 `json:"progress"`
+	// Size 上传的音频字节数
+	Size    int64                           `json:"size"`
+	Options Options                         `json:"options"`
+	Result  *transcribe.TranscriptionResult `json:"result,omitempty"`
+	Error   string                          `json:"error,omitempty"`
+	// Deliveries 回调请求记录
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// clone 返回任务的副本，回调记录不与原任务共享
+func (j *Job) clone() *Job {
+	copied := *j
+	copied.Deliveries = append([]Delivery(nil), j.Deliveries...)
+	return &copied
+}
+
+// newJobID 生成 32 位十六进制的随机任务 ID
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
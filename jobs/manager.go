@@ -0,0 +1,525 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/layzdonw/transerver/transcribe"
+	"github.com/sirupsen/logrus"
+)
+
+// sweepInterval 检查过期任务的最长间隔
+const sweepInterval = time.Minute
+
+// Config 任务管理器配置
+type Config struct {
+	// Workers 同时处理的任务数量，默认 1
+	Workers int
+	// QueueSize 排队任务数量上限，超过时 Submit 返回 ErrQueueFull，为 0 时不限制
+	QueueSize int
+	// Retention 任务结束后保留的时间，超过后从存储中删除，为 0 时一直保留
+	Retention time.Duration
+	// Webhook 任务结束时的回调设置
+	Webhook WebhookConfig
+}
+
+// Manager 异步转录任务管理器
+// 提交的任务先保存到 Store，再由固定数量的 worker 按提交顺序处理
+type Manager struct {
+	store       Store
+	transcriber transcribe.Transcriber
+	config      Config
+	logger      *logrus.Logger
+	webhook     *webhook
+	// ctx 在 Close 时取消，停止正在处理的任务和尚未完成的回调重试
+	ctx    context.Context
+	cancel context.CancelFunc
+	// callbacks 正在进行的回调
+	callbacks sync.WaitGroup
+
+	mu      sync.Mutex
+	pending []string
+	// running 正在处理的任务，取消任务时通过它停止解码
+	running map[string]context.CancelFunc
+	// reserved 正在保存音频、尚未排队的任务数量
+	reserved int
+	closed   bool
+	// wake 有新任务排队时通知 worker
+	wake chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager 创建任务管理器并启动 worker，管理器关闭时关闭 store
+// 上次运行时排队的任务会重新排队，处理到一半的任务标记为失败
+func NewManager(store Store, transcriber transcribe.Transcriber, config Config) (*Manager, error) {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		store:       store,
+		transcriber: transcriber,
+		config:      config,
+		logger:      logrus.New(),
+		webhook:     newWebhook(config.Webhook),
+		ctx:         ctx,
+		cancel:      cancel,
+		running:     make(map[string]context.CancelFunc),
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	if err := m.recover(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("无法恢复任务: %v", err)
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	if config.Retention > 0 {
+		m.wg.Add(1)
+		go m.sweeper()
+	}
+	return m, nil
+}
+
+func (m *Manager) recover() error {
+	jobs, err := m.store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		switch job.Status {
+		case StatusQueued:
+			m.pending = append(m.pending, job.ID)
+		case StatusRunning:
+			m.logger.Warnf("任务 %s 在服务重启时被中断", job.ID)
+			m.finish(job, nil, errors.New("服务重启时任务被中断"))
+		}
+	}
+	if len(m.pending) > 0 {
+		m.logger.Infof("恢复 %d 个排队的任务", len(m.pending))
+		m.notify()
+	}
+	return nil
+}
+
+// Submit 保存音频并创建排队的任务
+func (m *Manager) Submit(audio io.Reader, opts Options) (*Job, error) {
+	if opts.CallbackURL != "" {
+		if err := m.webhook.validate(opts.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	// 先占用队列位置，避免把音频写入存储后才发现队列已满
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if m.config.QueueSize > 0 && len(m.pending)+m.reserved >= m.config.QueueSize {
+		m.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	m.reserved++
+	m.mu.Unlock()
+
+	job, err := m.create(audio, opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reserved--
+	if err != nil {
+		return nil, err
+	}
+	m.pending = append(m.pending, job.ID)
+	m.notify()
+	return job, nil
+}
+
+func (m *Manager) create(audio io.Reader, opts Options) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, fmt.Errorf("无法生成任务 ID: %v", err)
+	}
+
+	job := &Job{
+		ID:        id,
+		Status:    StatusQueued,
+		Options:   opts,
+		CreatedAt: time.Now().UTC(),
+	}
+	counter := &countingReader{r: audio}
+	if err := m.store.Create(job, counter); err != nil {
+		return nil, fmt.Errorf("无法保存任务: %w", err)
+	}
+	job.Size = counter.n
+	if err := m.store.Update(job); err != nil {
+		m.store.Delete(job.ID)
+		return nil, fmt.Errorf("无法保存任务: %w", err)
+	}
+	return job, nil
+}
+
+// countingReader 记录已经读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// notify 唤醒一个等待中的 worker，调用方需要持有 m.mu 或者 worker 尚未启动
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get 返回任务的当前状态
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.store.Get(id)
+}
+
+// Cancel 取消排队或正在处理的任务；已经结束的任务会被删除
+// 正在处理的任务停止读取和解码，已经得到的结果被丢弃
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status.Done() {
+		if err := m.store.Delete(id); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+
+	for i, pending := range m.pending {
+		if pending == id {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			break
+		}
+	}
+
+	queued := job.Status == StatusQueued
+	now := time.Now().UTC()
+	job.Status = StatusCanceled
+	job.FinishedAt = &now
+	if err := m.store.Update(job); err != nil {
+		return nil, err
+	}
+	// 正在处理的任务由 worker 在停止读取后删除音频
+	if queued {
+		m.releaseAudio(id)
+	}
+	if stop, ok := m.running[id]; ok {
+		stop()
+	}
+	m.logger.Infof("任务 %s 已取消", id)
+	m.callback(job)
+	return job, nil
+}
+
+// Close 停止接收新任务，打断正在处理的任务并等待 worker 退出，最后关闭任务存储
+// 被打断的任务和排队的任务一起保留在存储中，下次启动时重新处理；尚未完成的回调不再重试
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	m.mu.Unlock()
+
+	// 先取消再等待，长录音的解码不会拖住关闭
+	m.cancel()
+	m.wg.Wait()
+	m.callbacks.Wait()
+	return m.store.Close()
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+
+	for {
+		id, ctx, ok := m.next()
+		if !ok {
+			return
+		}
+		m.run(ctx, id)
+	}
+}
+
+// next 取出下一个排队的任务并标记为处理中，返回取消任务时会被取消的 ctx；管理器关闭时返回 false
+func (m *Manager) next() (string, context.Context, bool) {
+	for {
+		m.mu.Lock()
+		for len(m.pending) > 0 && !m.closed {
+			id := m.pending[0]
+			m.pending = m.pending[1:]
+			if ctx, ok := m.start(id); ok {
+				// 还有任务时唤醒其他 worker
+				if len(m.pending) > 0 {
+					m.notify()
+				}
+				m.mu.Unlock()
+				return id, ctx, true
+			}
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-m.wake:
+		case <-m.done:
+			return "", nil, false
+		}
+	}
+}
+
+// start 把任务标记为处理中并登记到 running，调用方需要持有 m.mu
+func (m *Manager) start(id string) (context.Context, bool) {
+	job, err := m.store.Get(id)
+	if err != nil {
+		m.logger.Errorf("无法读取任务 %s: %v", id, err)
+		return nil, false
+	}
+	if job.Status != StatusQueued {
+		return nil, false
+	}
+
+	now := time.Now().UTC()
+	job.Status = StatusRunning
+	job.StartedAt = &now
+	if err := m.store.Update(job); err != nil {
+		m.logger.Errorf("无法更新任务 %s: %v", id, err)
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.running[id] = cancel
+	return ctx, true
+}
+
+func (m *Manager) run(ctx context.Context, id string) {
+	job, err := m.store.Get(id)
+	if err != nil {
+		m.logger.Errorf("无法读取任务 %s: %v", id, err)
+		m.mu.Lock()
+		m.stopRunning(id)
+		m.mu.Unlock()
+		return
+	}
+
+	m.logger.Infof("开始处理任务 %s", id)
+	result, err := m.transcribe(ctx, job)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopRunning(id)
+
+	// 处理期间任务可能已被取消或删除
+	current, getErr := m.store.Get(id)
+	if getErr != nil || current.Status != StatusRunning {
+		m.logger.Infof("任务 %s 已取消，丢弃转录结果", id)
+		if getErr == nil {
+			m.releaseAudio(id)
+		}
+		return
+	}
+	if err != nil && m.ctx.Err() != nil {
+		m.requeue(current)
+		return
+	}
+	m.finish(current, result, err)
+}
+
+// sweeper 定期删除结束超过 Retention 的任务，直到管理器关闭
+func (m *Manager) sweeper() {
+	defer m.wg.Done()
+
+	interval := sweepInterval
+	if m.config.Retention < interval {
+		interval = m.config.Retention
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			m.sweep(now)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// sweep 删除在 now 之前结束超过 Retention 的任务
+func (m *Manager) sweep(now time.Time) {
+	jobs, err := m.store.List()
+	if err != nil {
+		m.logger.Errorf("无法列出任务: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := 0
+	for _, job := range jobs {
+		if !job.Status.Done() || job.FinishedAt == nil || now.Sub(*job.FinishedAt) < m.config.Retention {
+			continue
+		}
+		if err := m.store.Delete(job.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			m.logger.Errorf("无法删除任务 %s: %v", job.ID, err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		m.logger.Infof("删除 %d 个过期的任务", removed)
+	}
+}
+
+// requeue 把被 Close 打断的任务恢复为排队状态并保留音频，下次启动时从头处理
+func (m *Manager) requeue(job *Job) {
+	job.Status = StatusQueued
+	job.Progress = 0
+	job.StartedAt = nil
+	if err := m.store.Update(job); err != nil {
+		m.logger.Errorf("无法更新任务 %s: %v", job.ID, err)
+		return
+	}
+	m.logger.Infof("任务 %s 被服务关闭打断，重新排队", job.ID)
+}
+
+// stopRunning 把任务从 running 中移除并释放它的 ctx，调用方需要持有 m.mu
+func (m *Manager) stopRunning(id string) {
+	if stop, ok := m.running[id]; ok {
+		stop()
+		delete(m.running, id)
+	}
+}
+
+func (m *Manager) transcribe(ctx context.Context, job *Job) (*transcribe.TranscriptionResult, error) {
+	audio, err := m.store.Audio(job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取音频: %v", err)
+	}
+	defer audio.Close()
+
+	opts := job.Options.transcribeOptions()
+	opts.Size = job.Size
+	opts.Context = ctx
+	opts.Progress = func(progress float64) {
+		m.setProgress(job.ID, progress)
+	}
+	return m.transcriber.TranscribeReader(audio, opts)
+}
+
+// setProgress 保存任务进度，变化不到 1% 时不写入存储
+func (m *Manager) setProgress(id string, progress float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil || job.Status != StatusRunning || progress < job.Progress+0.01 {
+		return
+	}
+	job.Progress = progress
+	if err := m.store.Update(job); err != nil {
+		m.logger.Errorf("无法更新任务 %s: %v", id, err)
+	}
+}
+
+// finish 保存任务的最终状态
+func (m *Manager) finish(job *Job, result *transcribe.TranscriptionResult, err error) {
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		m.logger.Errorf("任务 %s 失败: %v", job.ID, err)
+	} else {
+		job.Status = StatusSucceeded
+		job.Progress = 1
+		job.Result = result
+		m.logger.Infof("任务 %s 完成", job.ID)
+	}
+
+	// 任务已经结束，先删除音频，查询到最终状态时音频已经释放
+	m.releaseAudio(job.ID)
+	if err := m.store.Update(job); err != nil {
+		m.logger.Errorf("无法更新任务 %s: %v", job.ID, err)
+		return
+	}
+	m.callback(job)
+}
+
+// releaseAudio 删除已经结束的任务的音频，任务状态和结果仍然保留
+func (m *Manager) releaseAudio(id string) {
+	if err := m.store.DeleteAudio(id); err != nil && !errors.Is(err, ErrNotFound) {
+		m.logger.Errorf("无法删除任务 %s 的音频: %v", id, err)
+	}
+}
+
+// callback 在后台把任务的最终状态发送到 callback_url，并把每次请求的结果记录到任务中
+func (m *Manager) callback(job *Job) {
+	if job.Options.CallbackURL == "" {
+		return
+	}
+
+	event := "job." + string(job.Status)
+	body, err := json.Marshal(WebhookEvent{Event: event, Job: job})
+	if err != nil {
+		m.logger.Errorf("无法编码任务 %s 的回调: %v", job.ID, err)
+		return
+	}
+
+	m.callbacks.Add(1)
+	go func() {
+		defer m.callbacks.Done()
+
+		m.webhook.deliver(m.ctx, job.Options.CallbackURL, event, body, func(delivery Delivery) {
+			if !delivery.Success {
+				m.logger.Warnf("任务 %s 第 %d 次回调失败: %s", job.ID, delivery.Attempt, delivery.Error)
+			}
+			m.recordDelivery(job.ID, delivery)
+		})
+	}()
+}
+
+func (m *Manager) recordDelivery(id string, delivery Delivery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil {
+		// 任务已被删除
+		return
+	}
+	job.Deliveries = append(job.Deliveries, delivery)
+	if err := m.store.Update(job); err != nil {
+		m.logger.Errorf("无法更新任务 %s: %v", id, err)
+	}
+}
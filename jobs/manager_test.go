@@ -0,0 +1,277 @@
+package jobs
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// pcmAudio 0.1 秒的静音
+func pcmAudio() *bytes.Reader {
+	return bytes.NewReader(make([]byte, 3200))
+}
+
+// waitStatus 等待任务进入指定状态
+func waitStatus(t *testing.T, m *Manager, id string, status Status) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("读取任务失败: %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务状态期望 %s，得到 %s", status, job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManagerSubmit(t *testing.T) {
+	m, err := NewManager(NewMemoryStore(), transcribe.NewFakeTranscriber("你好"), Config{})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	job, err := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	if err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+	if job.ID == "" || job.Status != StatusQueued || job.Size != 3200 {
+		t.Errorf("新任务错误: %+v", job)
+	}
+
+	job = waitStatus(t, m, job.ID, StatusSucceeded)
+	if job.Result == nil || job.Result.Text != "你好" || job.Progress != 1 || job.StartedAt == nil || job.FinishedAt == nil {
+		t.Errorf("完成的任务错误: %+v", job)
+	}
+
+	if _, err := m.store.Audio(job.ID); err != ErrNotFound {
+		t.Errorf("结束的任务不应该保留音频: %v", err)
+	}
+
+	if _, err := m.Get("missing"); err != ErrNotFound {
+		t.Errorf("期望 ErrNotFound，得到 %v", err)
+	}
+}
+
+func TestManagerFailure(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("")
+	fake.Err = errors.New("解码失败")
+	m, err := NewManager(NewMemoryStore(), fake, Config{})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	job, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	job = waitStatus(t, m, job.ID, StatusFailed)
+	if job.Error != "解码失败" || job.Result != nil {
+		t.Errorf("失败的任务错误: %+v", job)
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Gate = make(chan struct{})
+	m, err := NewManager(NewMemoryStore(), fake, Config{Workers: 1})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	running, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	waitStatus(t, m, running.ID, StatusRunning)
+	queued, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+
+	// 取消排队的任务，它不会再被处理
+	if job, err := m.Cancel(queued.ID); err != nil || job.Status != StatusCanceled {
+		t.Fatalf("取消排队的任务失败: %+v, %v", job, err)
+	}
+	if _, err := m.store.Audio(queued.ID); err != ErrNotFound {
+		t.Errorf("取消的任务不应该保留音频: %v", err)
+	}
+
+	// 取消正在处理的任务，解码立即停止，不用等到解码结束
+	if job, err := m.Cancel(running.ID); err != nil || job.Status != StatusCanceled {
+		t.Fatalf("取消正在处理的任务失败: %+v, %v", job, err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := m.store.Audio(running.ID); err == ErrNotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Error("取消后 worker 应该停止解码")
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(fake.Gate)
+
+	done, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	waitStatus(t, m, done.ID, StatusSucceeded)
+	if job, _ := m.Get(running.ID); job.Status != StatusCanceled || job.Result != nil {
+		t.Errorf("取消的任务不应该保存结果: %+v", job)
+	}
+	if fake.Requests() != 2 {
+		t.Errorf("期望解码 2 次，得到 %d", fake.Requests())
+	}
+
+	// 已经结束的任务被删除
+	if _, err := m.Cancel(done.ID); err != nil {
+		t.Fatalf("删除任务失败: %v", err)
+	}
+	if _, err := m.Get(done.ID); err != ErrNotFound {
+		t.Errorf("删除后期望 ErrNotFound，得到 %v", err)
+	}
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Gate = make(chan struct{})
+	m, err := NewManager(NewMemoryStore(), fake, Config{Workers: 1, QueueSize: 1})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	running, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	waitStatus(t, m, running.ID, StatusRunning)
+	if _, err := m.Submit(pcmAudio(), Options{Format: "pcm"}); err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+	if _, err := m.Submit(pcmAudio(), Options{Format: "pcm"}); err != ErrQueueFull {
+		t.Errorf("期望 ErrQueueFull，得到 %v", err)
+	}
+}
+
+func TestManagerRecover(t *testing.T) {
+	store, err := NewDiskStore(filepath.Join(t.TempDir(), "jobs"))
+	if err != nil {
+		t.Fatalf("创建磁盘存储失败: %v", err)
+	}
+	now := time.Now().UTC()
+	store.Create(&Job{ID: "queued", Status: StatusQueued, Options: Options{Format: "pcm"}, CreatedAt: now}, pcmAudio())
+	store.Create(&Job{ID: "running", Status: StatusRunning, Options: Options{Format: "pcm"}, CreatedAt: now}, pcmAudio())
+
+	// 重启后排队的任务继续处理，处理到一半的任务标记为失败
+	m, err := NewManager(store, transcribe.NewFakeTranscriber("你好"), Config{})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	waitStatus(t, m, "queued", StatusSucceeded)
+	if job := waitStatus(t, m, "running", StatusFailed); job.Error == "" {
+		t.Errorf("中断的任务应该有错误信息: %+v", job)
+	}
+}
+
+func TestManagerCloseInterruptsRunning(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatalf("创建磁盘存储失败: %v", err)
+	}
+	// 解码一直不结束
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Gate = make(chan struct{})
+	m, err := NewManager(store, fake, Config{})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	running, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	waitStatus(t, m, running.ID, StatusRunning)
+
+	// Close 打断正在处理的任务，不等解码结束
+	closed := make(chan error, 1)
+	go func() {
+		closed <- m.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("关闭任务管理器失败: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close 应该打断正在处理的任务")
+	}
+
+	// 被打断的任务保留音频并重新排队，下次启动时处理
+	store, err = NewDiskStore(dir)
+	if err != nil {
+		t.Fatalf("重新打开磁盘存储失败: %v", err)
+	}
+	m, err = NewManager(store, transcribe.NewFakeTranscriber("你好"), Config{})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+	if job := waitStatus(t, m, running.ID, StatusSucceeded); job.Result == nil || job.Result.Text != "你好" {
+		t.Errorf("重新排队的任务结果错误: %+v", job)
+	}
+}
+
+func TestManagerRetention(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	m, err := NewManager(NewMemoryStore(), fake, Config{Workers: 1, Retention: time.Hour})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	done, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	waitStatus(t, m, done.ID, StatusSucceeded)
+
+	// 排队和处理中的任务不会过期
+	fake.Gate = make(chan struct{})
+	defer close(fake.Gate)
+	running, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	waitStatus(t, m, running.ID, StatusRunning)
+	queued, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+
+	m.sweep(time.Now())
+	if _, err := m.Get(done.ID); err != nil {
+		t.Fatalf("保留期内的任务不应该删除: %v", err)
+	}
+
+	m.sweep(time.Now().Add(2 * time.Hour))
+	if _, err := m.Get(done.ID); err != ErrNotFound {
+		t.Errorf("过期的任务应该删除，得到 %v", err)
+	}
+	for _, id := range []string{running.ID, queued.ID} {
+		if _, err := m.Get(id); err != nil {
+			t.Errorf("未结束的任务 %s 不应该删除: %v", id, err)
+		}
+	}
+}
+
+func TestManagerSweeper(t *testing.T) {
+	m, err := NewManager(NewMemoryStore(), transcribe.NewFakeTranscriber("你好"), Config{Retention: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	job, _ := m.Submit(pcmAudio(), Options{Format: "pcm"})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := m.Get(job.ID); err == ErrNotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("过期的任务没有被自动删除")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
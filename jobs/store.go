@@ -0,0 +1,169 @@
+package jobs
+
+import (
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Store 任务存储，保存任务状态和待转录的音频
+// Get 和 List 返回的任务是副本，修改后需要调用 Update 保存
+type Store interface {
+	// Create 保存新任务和它的音频，音频从 audio 中流式读取
+	Create(job *Job, audio io.Reader) error
+	// Get 返回任务，不存在时返回 ErrNotFound
+	Get(id string) (*Job, error)
+	// Update 保存任务的最新状态
+	Update(job *Job) error
+	// Audio 打开任务的音频，音频已被删除时返回 ErrNotFound
+	Audio(id string) (io.ReadCloser, error)
+	// DeleteAudio 删除任务的音频并保留任务状态，任务结束后音频不再需要
+	DeleteAudio(id string) error
+	// Delete 删除任务和它的音频
+	Delete(id string) error
+	// List 按创建时间返回所有任务
+	List() ([]*Job, error)
+	// Close 释放存储占用的资源
+	Close() error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore 内存中的任务存储，重启后任务丢失
+// 任务状态保存在内存中，音频写入临时文件，长录音不会占用内存；Close 时删除临时文件
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+	// audio 每个任务音频的临时文件路径
+	audio map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:  make(map[string]*Job),
+		audio: make(map[string]string),
+	}
+}
+
+func (s *MemoryStore) Create(job *Job, audio io.Reader) error {
+	f, err := os.CreateTemp("", "transerver-job-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, audio); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = job.clone()
+	s.audio[job.ID] = f.Name()
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return job.clone(), nil
+}
+
+func (s *MemoryStore) Update(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return ErrNotFound
+	}
+	s.jobs[job.ID] = job.clone()
+	return nil
+}
+
+func (s *MemoryStore) Audio(id string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	path, ok := s.audio[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return os.Open(path)
+}
+
+func (s *MemoryStore) DeleteAudio(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrNotFound
+	}
+	return s.removeAudio(id)
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.jobs, id)
+	return s.removeAudio(id)
+}
+
+// removeAudio 删除任务音频的临时文件，调用方需要持有 s.mu
+func (s *MemoryStore) removeAudio(id string) error {
+	path, ok := s.audio[id]
+	if !ok {
+		return nil
+	}
+	delete(s.audio, id)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *MemoryStore) List() ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.clone())
+	}
+	sortByCreation(jobs)
+	return jobs, nil
+}
+
+// Close 删除所有音频临时文件，任务状态仍然可以读取
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for id := range s.audio {
+		if err := s.removeAudio(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func sortByCreation(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	disk, err := NewDiskStore(filepath.Join(t.TempDir(), "jobs"))
+	if err != nil {
+		t.Fatalf("创建磁盘存储失败: %v", err)
+	}
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"disk":   disk,
+	}
+}
+
+func TestStore(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			first := &Job{ID: "b", Status: StatusQueued, Options: Options{Format: "wav"}, CreatedAt: created.Add(time.Second)}
+			second := &Job{ID: "a", Status: StatusQueued, CreatedAt: created}
+			if err := store.Create(first, strings.NewReader("audio-b")); err != nil {
+				t.Fatalf("创建任务失败: %v", err)
+			}
+			if err := store.Create(second, strings.NewReader("audio-a")); err != nil {
+				t.Fatalf("创建任务失败: %v", err)
+			}
+
+			job, err := store.Get("b")
+			if err != nil || job.Options.Format != "wav" || !job.CreatedAt.Equal(first.CreatedAt) {
+				t.Fatalf("读取任务错误: %+v, %v", job, err)
+			}
+
+			// 修改返回的副本不影响存储
+			job.Status = StatusSucceeded
+			if stored, _ := store.Get("b"); stored.Status != StatusQueued {
+				t.Errorf("未调用 Update 时状态不应该改变: %s", stored.Status)
+			}
+
+			job.Result = &transcribe.TranscriptionResult{Text: "你好"}
+			if err := store.Update(job); err != nil {
+				t.Fatalf("更新任务失败: %v", err)
+			}
+			if stored, _ := store.Get("b"); stored.Status != StatusSucceeded || stored.Result.Text != "你好" {
+				t.Errorf("更新后的任务错误: %+v", stored)
+			}
+
+			audio, err := store.Audio("b")
+			if err != nil {
+				t.Fatalf("读取音频失败: %v", err)
+			}
+			data, _ := io.ReadAll(audio)
+			audio.Close()
+			if string(data) != "audio-b" {
+				t.Errorf("音频内容错误: %q", data)
+			}
+
+			// 删除音频后任务状态仍然保留
+			if err := store.DeleteAudio("b"); err != nil {
+				t.Fatalf("删除音频失败: %v", err)
+			}
+			if _, err := store.Audio("b"); err != ErrNotFound {
+				t.Errorf("删除音频后期望 ErrNotFound，得到 %v", err)
+			}
+			if _, err := store.Get("b"); err != nil {
+				t.Errorf("删除音频后应该仍然可以读取任务: %v", err)
+			}
+			if err := store.DeleteAudio("b"); err != nil {
+				t.Errorf("重复删除音频不应该失败: %v", err)
+			}
+			if err := store.DeleteAudio("missing"); err != ErrNotFound {
+				t.Errorf("删除不存在的任务的音频期望 ErrNotFound，得到 %v", err)
+			}
+
+			// 按创建时间排序
+			jobs, err := store.List()
+			if err != nil || len(jobs) != 2 || jobs[0].ID != "a" || jobs[1].ID != "b" {
+				t.Errorf("任务列表错误: %+v, %v", jobs, err)
+			}
+
+			if err := store.Delete("b"); err != nil {
+				t.Fatalf("删除任务失败: %v", err)
+			}
+			if _, err := store.Get("b"); err != ErrNotFound {
+				t.Errorf("删除后期望 ErrNotFound，得到 %v", err)
+			}
+			if _, err := store.Audio("b"); err != ErrNotFound {
+				t.Errorf("删除后音频期望 ErrNotFound，得到 %v", err)
+			}
+			if err := store.Delete("b"); err != ErrNotFound {
+				t.Errorf("重复删除期望 ErrNotFound，得到 %v", err)
+			}
+			if err := store.Update(&Job{ID: "missing"}); err != ErrNotFound {
+				t.Errorf("更新不存在的任务期望 ErrNotFound，得到 %v", err)
+			}
+		})
+	}
+}
+
+func TestDiskStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatalf("创建磁盘存储失败: %v", err)
+	}
+	if err := store.Create(&Job{ID: "abc", Status: StatusQueued}, strings.NewReader("audio")); err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+	// 创建到一半的任务目录
+	if err := os.Mkdir(filepath.Join(dir, "partial"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开目录后任务仍然存在
+	reopened, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatalf("重新打开磁盘存储失败: %v", err)
+	}
+	jobs, err := reopened.List()
+	if err != nil || len(jobs) != 1 || jobs[0].ID != "abc" {
+		t.Errorf("重新打开后的任务列表错误: %+v, %v", jobs, err)
+	}
+
+	// ID 不能跳出存储目录
+	for _, id := range []string{"", ".", "..", "../abc", "a/b"} {
+		if _, err := reopened.Get(id); err != ErrNotFound {
+			t.Errorf("ID %q 期望 ErrNotFound，得到 %v", id, err)
+		}
+	}
+}
+
+func TestMemoryStoreSpoolsAudio(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Create(&Job{ID: "abc", Status: StatusQueued}, strings.NewReader("audio")); err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+
+	// 音频写入临时文件，不保存在内存中
+	path := store.audio["abc"]
+	if data, err := os.ReadFile(path); err != nil || string(data) != "audio" {
+		t.Fatalf("音频应该写入临时文件: %q, %v", data, err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("关闭存储失败: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("关闭后应该删除临时文件: %v", err)
+	}
+	if _, err := store.Get("abc"); err != nil {
+		t.Errorf("关闭后应该仍然可以读取任务: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrInvalidCallbackURL callback_url 不是 http 或 https 的绝对地址，或者指向内网地址
+var ErrInvalidCallbackURL = errors.New("无效的 callback_url")
+
+// ErrCallbackDisabled 没有配置签名密钥，不接受 callback_url，避免发送接收方无法校验的回调
+var ErrCallbackDisabled = errors.New("未设置 jobs.webhook.secret，不接受 callback_url")
+
+// errPrivateAddress 回调请求要连接的地址不是公网地址
+var errPrivateAddress = errors.New("不允许回调内网地址")
+
+// sharedAddressSpace 运营商级 NAT 地址（RFC 6598），云平台内部也常用，不属于 net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// 回调请求的头部
+const (
+	// SignatureHeader 签名，格式为 sha256=<hex>，见 Sign
+	SignatureHeader = "X-Transerver-Signature"
+	// TimestampHeader 发送时间（Unix 秒），参与签名，接收方可以据此拒绝过期的请求
+	TimestampHeader = "X-Transerver-Timestamp"
+	// EventHeader 事件类型，例如 job.succeeded
+	EventHeader = "X-Transerver-Event"
+)
+
+// WebhookConfig 任务结束时回调的配置，为 0 的字段使用默认值
+type WebhookConfig struct {
+	// Secret 签名密钥，为空时不接受 callback_url
+	Secret string
+	// MaxAttempts 最多发送次数，默认 5
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间，之后每次加倍，默认 1 秒
+	InitialBackoff time.Duration
+	// MaxBackoff 重试等待时间的上限，默认 1 分钟
+	MaxBackoff time.Duration
+	// Timeout 单次请求的超时时间，默认 10 秒
+	Timeout time.Duration
+	// AllowPrivateNetworks 允许回调回环、私有和链路本地地址，只在接收方部署在内网时打开
+	AllowPrivateNetworks bool
+}
+
+func (c WebhookConfig) withDefaults() WebhookConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = time.Minute
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return c
+}
+
+// Delivery 一次回调请求的结果
+type Delivery struct {
+	Attempt int       `json:"attempt"`
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	// StatusCode 接收方返回的状态码，请求失败时为 0
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WebhookEvent 回调请求的 JSON 内容
+type WebhookEvent struct {
+	// Event 事件类型：job.succeeded、job.failed 或 job.canceled
+	Event string `json:"event"`
+	Job   *Job   `json:"job"`
+}
+
+// Sign 计算回调请求的签名：对 "<timestamp>.<body>" 做 HMAC-SHA256
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验回调请求的签名，供接收方使用
+func VerifySignature(secret, signature, timestamp string, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// parseCallbackURL 解析回调地址，只接受 http 或 https 的绝对地址
+func parseCallbackURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCallbackURL, raw)
+	}
+	return u, nil
+}
+
+// publicIP 返回 ip 是否为公网地址，回环、私有、链路本地、组播和未指定地址都不是
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip))
+}
+
+// denyPrivate 在建立连接前检查实际连接的地址
+// 提交任务时已经检查过域名，这里防止域名之后改为解析到内网地址（DNS rebinding）
+func denyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+// webhook 发送回调请求，失败时按指数退避重试
+type webhook struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+func newWebhook(config WebhookConfig) *webhook {
+	config = config.withDefaults()
+	client := &http.Client{
+		Timeout: config.Timeout,
+		// 不跟随重定向，3xx 按失败处理，避免接收方把请求转到内网地址
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if !config.AllowPrivateNetworks {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: denyPrivate}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		// 经过代理时连接的是代理而不是接收方，无法检查目标地址
+		transport.Proxy = nil
+		client.Transport = transport
+	}
+	return &webhook{config: config, client: client}
+}
+
+// validate 检查回调地址是否为 http 或 https 的绝对地址，并且域名只解析到公网地址
+// 没有配置签名密钥时不接受任何回调地址
+func (w *webhook) validate(raw string) error {
+	u, err := parseCallbackURL(raw)
+	if err != nil {
+		return err
+	}
+	if w.config.Secret == "" {
+		return ErrCallbackDisabled
+	}
+	if w.config.AllowPrivateNetworks {
+		return nil
+	}
+
+	host := u.Hostname()
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: 无法解析 %s: %v", ErrInvalidCallbackURL, host, err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("%w: %s 指向内网地址 %s", ErrInvalidCallbackURL, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// deliver 发送回调直到成功、遇到不可重试的错误、达到最多次数或者 ctx 被取消
+// 每次请求的结果通过 record 记录
+func (w *webhook) deliver(ctx context.Context, callbackURL, event string, body []byte, record func(Delivery)) {
+	backoff := w.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		delivery, retry := w.post(ctx, callbackURL, event, body)
+		delivery.Attempt = attempt
+		record(delivery)
+
+		if delivery.Success || !retry || attempt >= w.config.MaxAttempts {
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		backoff *= 2
+		if backoff > w.config.MaxBackoff {
+			backoff = w.config.MaxBackoff
+		}
+	}
+}
+
+// post 发送一次回调请求，返回结果和失败时是否应该重试
+// 网络错误、5xx、408 和 429 可以重试，其他 4xx 说明请求本身不被接受，不再重试；
+// 重定向不跟随，连接被拒绝的内网地址同样不再重试
+func (w *webhook) post(ctx context.Context, callbackURL, event string, body []byte) (Delivery, bool) {
+	delivery := Delivery{Time: time.Now().UTC()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+	// 之前提交的任务在去掉密钥后重启时仍然带有回调地址，同样不发送
+	if w.config.Secret == "" {
+		delivery.Error = ErrCallbackDisabled.Error()
+		return delivery, false
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(w.config.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, !errors.Is(err, errPrivateAddress)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Success = true
+		return delivery, false
+	}
+	delivery.Error = resp.Status
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return delivery, true
+	}
+	return delivery, false
+}
@@ -0,0 +1,308 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// webhookReceiver 记录收到的回调，前 failures 次返回 status
+type webhookReceiver struct {
+	t        *testing.T
+	failures int
+	status   int
+
+	mu     sync.Mutex
+	events []WebhookEvent
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if !VerifySignature("secret", req.Header.Get(SignatureHeader), req.Header.Get(TimestampHeader), body) {
+		r.t.Errorf("回调签名错误: %s", req.Header.Get(SignatureHeader))
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		r.t.Errorf("无法解析回调内容: %v", err)
+	}
+	if req.Header.Get(EventHeader) != event.Event {
+		r.t.Errorf("事件头 %s 与内容 %s 不一致", req.Header.Get(EventHeader), event.Event)
+	}
+
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	failed := len(r.events) <= r.failures
+	r.mu.Unlock()
+
+	if failed {
+		w.WriteHeader(r.status)
+	}
+}
+
+// waitDeliveries 等待任务记录 n 次回调
+func waitDeliveries(t *testing.T, m *Manager, id string, n int) []Delivery {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("读取任务失败: %v", err)
+		}
+		if len(job.Deliveries) >= n {
+			return job.Deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("期望 %d 次回调，得到 %+v", n, job.Deliveries)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func webhookManager(t *testing.T, transcriber transcribe.Transcriber, maxAttempts int) *Manager {
+	m, err := NewManager(NewMemoryStore(), transcriber, Config{
+		Webhook: WebhookConfig{
+			Secret: "secret",
+			// 测试接收方监听在回环地址
+			AllowPrivateNetworks: true,
+			MaxAttempts:          maxAttempts,
+			InitialBackoff:       time.Millisecond,
+			MaxBackoff:           4 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestWebhookRetry(t *testing.T) {
+	receiver := &webhookReceiver{t: t, failures: 2, status: http.StatusServiceUnavailable}
+	ts := httptest.NewServer(receiver)
+	defer ts.Close()
+
+	m := webhookManager(t, transcribe.NewFakeTranscriber("你好"), 5)
+	job, err := m.Submit(pcmAudio(), Options{Format: "pcm", CallbackURL: ts.URL})
+	if err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+
+	// 前两次失败后重试成功
+	deliveries := waitDeliveries(t, m, job.ID, 3)
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusServiceUnavailable || deliveries[0].Attempt != 1 {
+		t.Errorf("第一次回调记录错误: %+v", deliveries[0])
+	}
+	if !deliveries[2].Success || deliveries[2].StatusCode != http.StatusOK || deliveries[2].Attempt != 3 {
+		t.Errorf("第三次回调记录错误: %+v", deliveries[2])
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	event := receiver.events[2]
+	if event.Event != "job.succeeded" || event.Job.ID != job.ID || event.Job.Result.Text != "你好" {
+		t.Errorf("回调内容错误: %+v", event)
+	}
+}
+
+func TestWebhookGiveUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		// 4xx 不再重试
+		{"不可重试", http.StatusBadRequest, 1},
+		// 一直失败时达到最多次数后停止
+		{"达到最多次数", http.StatusInternalServerError, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &webhookReceiver{t: t, failures: 100, status: tt.status}
+			ts := httptest.NewServer(receiver)
+			defer ts.Close()
+
+			fake := transcribe.NewFakeTranscriber("")
+			fake.Err = errors.New("解码失败")
+			m := webhookManager(t, fake, 3)
+			job, _ := m.Submit(pcmAudio(), Options{Format: "pcm", CallbackURL: ts.URL})
+
+			waitDeliveries(t, m, job.ID, tt.attempts)
+			time.Sleep(20 * time.Millisecond)
+
+			receiver.mu.Lock()
+			defer receiver.mu.Unlock()
+			if len(receiver.events) != tt.attempts {
+				t.Errorf("期望发送 %d 次，得到 %d", tt.attempts, len(receiver.events))
+			}
+			if receiver.events[0].Event != "job.failed" || receiver.events[0].Job.Error != "解码失败" {
+				t.Errorf("回调内容错误: %+v", receiver.events[0])
+			}
+		})
+	}
+}
+
+func TestWebhookCanceled(t *testing.T) {
+	receiver := &webhookReceiver{t: t}
+	ts := httptest.NewServer(receiver)
+	defer ts.Close()
+
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Gate = make(chan struct{})
+	m := webhookManager(t, fake, 1)
+	defer close(fake.Gate)
+
+	job, _ := m.Submit(pcmAudio(), Options{Format: "pcm", CallbackURL: ts.URL})
+	m.Cancel(job.ID)
+
+	if deliveries := waitDeliveries(t, m, job.ID, 1); !deliveries[0].Success {
+		t.Errorf("回调记录错误: %+v", deliveries[0])
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if receiver.events[0].Event != "job.canceled" {
+		t.Errorf("期望 job.canceled，得到 %s", receiver.events[0].Event)
+	}
+}
+
+func TestInvalidCallbackURL(t *testing.T) {
+	m := webhookManager(t, transcribe.NewFakeTranscriber("你好"), 1)
+
+	for _, callbackURL := range []string{"ftp://example.com/hook", "/hook", "http://"} {
+		if _, err := m.Submit(pcmAudio(), Options{Format: "pcm", CallbackURL: callbackURL}); !errors.Is(err, ErrInvalidCallbackURL) {
+			t.Errorf("%s 期望 ErrInvalidCallbackURL，得到 %v", callbackURL, err)
+		}
+	}
+}
+
+func TestCallbackPrivateAddress(t *testing.T) {
+	w := newWebhook(WebhookConfig{Secret: "secret"})
+
+	for _, callbackURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if err := w.validate(callbackURL); !errors.Is(err, ErrInvalidCallbackURL) {
+			t.Errorf("%s 期望 ErrInvalidCallbackURL，得到 %v", callbackURL, err)
+		}
+	}
+	if err := w.validate("https://93.184.216.34/hook"); err != nil {
+		t.Errorf("公网地址应该通过检查: %v", err)
+	}
+
+	// 允许内网地址时只检查格式
+	w = newWebhook(WebhookConfig{Secret: "secret", AllowPrivateNetworks: true})
+	if err := w.validate("http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("允许内网地址时应该通过检查: %v", err)
+	}
+}
+
+func TestWebhookDialPrivate(t *testing.T) {
+	receiver := &webhookReceiver{t: t}
+	ts := httptest.NewServer(receiver)
+	defer ts.Close()
+
+	// 提交时检查过的域名之后解析到内网地址，连接时同样拒绝，并且不再重试
+	w := newWebhook(WebhookConfig{Secret: "secret"})
+	delivery, retry := w.post(context.Background(), ts.URL, "job.succeeded", []byte(`{}`))
+	if delivery.Success || retry {
+		t.Errorf("期望拒绝连接且不重试，得到 %+v, retry=%v", delivery, retry)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.events) != 0 {
+		t.Errorf("不应该发送到内网地址，收到 %d 次", len(receiver.events))
+	}
+}
+
+func TestWebhookRedirect(t *testing.T) {
+	receiver := &webhookReceiver{t: t}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	m := webhookManager(t, transcribe.NewFakeTranscriber("你好"), 3)
+	job, err := m.Submit(pcmAudio(), Options{Format: "pcm", CallbackURL: redirect.URL})
+	if err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+
+	// 重定向不跟随，按失败处理且不重试
+	deliveries := waitDeliveries(t, m, job.ID, 1)
+	time.Sleep(20 * time.Millisecond)
+	if job, _ := m.Get(job.ID); len(job.Deliveries) != 1 {
+		t.Errorf("期望发送 1 次，得到 %d", len(job.Deliveries))
+	}
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("回调记录错误: %+v", deliveries[0])
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.events) != 0 {
+		t.Errorf("不应该跟随重定向，目标收到 %d 次", len(receiver.events))
+	}
+}
+
+func TestCallbackUnsigned(t *testing.T) {
+	receiver := &webhookReceiver{t: t}
+	ts := httptest.NewServer(receiver)
+	defer ts.Close()
+
+	// 没有签名密钥时不接受回调地址
+	m, err := NewManager(NewMemoryStore(), transcribe.NewFakeTranscriber("你好"), Config{
+		Webhook: WebhookConfig{AllowPrivateNetworks: true},
+	})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	defer m.Close()
+	if _, err := m.Submit(pcmAudio(), Options{Format: "pcm", CallbackURL: ts.URL}); !errors.Is(err, ErrCallbackDisabled) {
+		t.Errorf("期望 ErrCallbackDisabled，得到 %v", err)
+	}
+
+	// 已经保存了回调地址的任务也不发送不签名的回调
+	w := newWebhook(WebhookConfig{AllowPrivateNetworks: true})
+	delivery, retry := w.post(context.Background(), ts.URL, "job.succeeded", []byte(`{}`))
+	if delivery.Success || retry || delivery.Error != ErrCallbackDisabled.Error() {
+		t.Errorf("期望不发送且不重试，得到 %+v, retry=%v", delivery, retry)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.events) != 0 {
+		t.Errorf("不应该发送不签名的回调，收到 %d 次", len(receiver.events))
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"job.succeeded"}`)
+	signature := Sign("secret", "1700000000", body)
+	if !VerifySignature("secret", signature, "1700000000", body) {
+		t.Error("签名校验失败")
+	}
+	if VerifySignature("other", signature, "1700000000", body) {
+		t.Error("密钥不同时签名校验应该失败")
+	}
+	if VerifySignature("secret", signature, "1700000001", body) {
+		t.Error("时间戳不同时签名校验应该失败")
+	}
+}
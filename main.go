@@ -1,59 +1,240 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/layzdonw/transerver/config"
+	"github.com/layzdonw/transerver/jobs"
 	"github.com/layzdonw/transerver/server"
 	"github.com/layzdonw/transerver/transcribe"
 	"github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout 收到退出信号后等待进行中的请求结束的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// 命令行参数
 	configPath := flag.String("config", "config.yaml", "配置文件路径")
 	flag.Parse()
 
+	if err := run(*configPath); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// run 启动服务直到收到 SIGINT 或 SIGTERM，返回前依次关闭服务器、任务管理器和转录池
+func run(configPath string) error {
 	// 加载配置
-	if err := config.LoadConfig(*configPath); err != nil {
-		logrus.Fatalf("加载配置失败: %v", err)
+	if err := config.LoadConfig(configPath); err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
 	}
 
 	// 创建转录器
 	cfg := config.AppConfig.Sherpa
-	var transcriber *transcribe.SherpaTranscriber
+	logrus.Infof("加载 %s 模型", transcribe.NormalizeModelType(cfg.ModelType))
+	transcriber, err := transcribe.NewSherpaTranscriberWithModel(
+		modelConfig(cfg.ModelType, cfg.ModelPath, cfg.TokensPath, cfg.ModelFiles, cfg),
+		cfg.SampleRate,
+	)
+	if err != nil {
+		return fmt.Errorf("创建转录器失败: %v", err)
+	}
+	if !transcribe.SupportsOnline(cfg.ModelType) {
+		logrus.Warn("当前模型没有流式实现，实时转录不可用")
+	}
+
+	// 限制并发解码数量，关闭转录池时关闭转录器
+	poolCfg := config.AppConfig.Pool
+	pool := transcribe.NewPool(transcriber, transcribe.PoolConfig{
+		Workers:      poolCfg.Workers,
+		QueueSize:    poolCfg.QueueSize,
+		QueueTimeout: poolCfg.QueueTimeout,
+		MaxSessions:  poolCfg.MaxSessions,
+	})
+	defer pool.Close()
 
 	if cfg.EnableDiarization {
 		logrus.Info("启用说话人分离功能")
-		transcriber = transcribe.NewSherpaTranscriberWithDiarization(
-			cfg.ModelPath,
-			cfg.TokensPath,
-			cfg.DiarizationModelPath,
-			cfg.SampleRate,
-			cfg.NumThreads,
-			cfg.DecodingMethod,
-		)
+		diarizer, err := transcribe.NewSherpaDiarizer(cfg.DiarizationModelPath, cfg.NumThreads, 0, 0)
+		if err != nil {
+			return fmt.Errorf("创建说话人分离器失败: %v", err)
+		}
+		transcriber.SetDiarizer(diarizer)
 	} else {
 		logrus.Info("使用标准转录功能")
-		transcriber = transcribe.NewSherpaTranscriber(
-			cfg.ModelPath,
-			cfg.TokensPath,
-			cfg.SampleRate,
-			cfg.NumThreads,
-			cfg.DecodingMethod,
+	}
+
+	// 加载离线识别器，用于批量转录
+	if cfg.Offline.Enabled {
+		logrus.Infof("启用离线识别模型: %s", cfg.Offline.ModelType)
+		err := transcriber.EnableOfflineRecognizer(
+			modelConfig(cfg.Offline.ModelType, cfg.Offline.ModelPath, cfg.Offline.TokensPath, cfg.Offline.ModelFiles, cfg),
 		)
+		if err != nil {
+			return fmt.Errorf("加载离线识别模型失败: %v", err)
+		}
 	}
+	if err := transcriber.SetBatchMode(cfg.BatchMode); err != nil {
+		return fmt.Errorf("设置批量转录模式失败: %v", err)
+	}
+
+	transcriber.SetMaxCustomRecognizers(cfg.CustomRecognizers)
 
-	if transcriber == nil {
-		logrus.Fatalf("创建转录器失败")
+	// 长音频按语音检测切分为句子后识别
+	if cfg.VAD.Enabled {
+		segmenterCfg := transcribe.SegmenterConfig{
+			Threshold:  cfg.VAD.Threshold,
+			MinSilence: cfg.VAD.MinSilence,
+			MinSpeech:  cfg.VAD.MinSpeech,
+			MaxSegment: cfg.VAD.MaxSegment,
+		}
+		var segmenter transcribe.Segmenter
+		if cfg.VAD.ModelPath != "" {
+			logrus.Infof("启用 Silero VAD 切分: %s", cfg.VAD.ModelPath)
+			segmenter, err = transcribe.NewSileroSegmenter(cfg.VAD.ModelPath, cfg.SampleRate, cfg.NumThreads, segmenterCfg)
+			if err != nil {
+				return fmt.Errorf("加载 VAD 模型失败: %v", err)
+			}
+		} else {
+			logrus.Info("启用基于能量的语音检测切分")
+			segmenter = transcribe.NewEnergySegmenter(segmenterCfg)
+		}
+		transcriber.SetSegmenter(segmenter, cfg.VAD.Workers)
 	}
 
 	// 创建服务器
-	srv := server.NewServer(transcriber)
+	srv := server.NewServer(pool)
+	defer srv.Close()
+	srv.SetMaxUploadSize(config.AppConfig.Server.MaxUploadMB << 20)
+
+	// 异步任务，用于长录音；服务器停止后、转录池关闭前关闭
+	if jobsCfg := config.AppConfig.Jobs; jobsCfg.Enabled {
+		manager, err := newJobManager(jobsCfg, pool)
+		if err != nil {
+			return fmt.Errorf("创建任务管理器失败: %v", err)
+		}
+		defer manager.Close()
+		srv.SetJobManager(manager)
+	}
+
+	// 启动 HTTP 和 gRPC 服务，任何一个出错时关闭另一个并返回错误
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 启动服务器
 	logrus.Info("启动转录服务器...")
-	if err := srv.Start(); err != nil {
-		logrus.Fatalf("服务器启动失败: %v", err)
+	failed := make(chan error, 2)
+	var wg sync.WaitGroup
+	serve := func(name string, start func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("%s失败: %v", name, err)
+			}
+		}()
+	}
+	serve("HTTP 服务", srv.Start)
+	serve("gRPC 服务", srv.StartGRPC)
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		logrus.Info("收到退出信号，正在关闭服务器...")
+	case serveErr = <-failed:
+		logrus.Errorf("%v，正在关闭服务器...", serveErr)
+	}
+
+	// 等进行中的请求结束，之后由 defer 关闭任务管理器和转录池
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logrus.Errorf("关闭服务器失败: %v", err)
+	}
+	wg.Wait()
+	logrus.Info("服务器已停止")
+	return serveErr
+}
+
+// newJobManager 按配置创建任务存储和任务管理器
+func newJobManager(cfg config.JobsConfig, transcriber transcribe.Transcriber) (*jobs.Manager, error) {
+	var store jobs.Store
+	switch cfg.Store {
+	case "", "memory":
+		store = jobs.NewMemoryStore()
+	case "disk":
+		disk, err := jobs.NewDiskStore(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		store = disk
+	default:
+		return nil, fmt.Errorf("不支持的任务存储: %s", cfg.Store)
+	}
+	logrus.Infof("启用异步任务，使用 %s 存储", cfg.Store)
+
+	if cfg.Webhook.Secret == "" {
+		logrus.Warn("未设置 jobs.webhook.secret，提交任务时不接受 callback_url")
+	}
+
+	return jobs.NewManager(store, transcriber, jobs.Config{
+		Workers:   cfg.Workers,
+		QueueSize: cfg.QueueSize,
+		Retention: cfg.Retention,
+		Webhook: jobs.WebhookConfig{
+			Secret:               cfg.Webhook.Secret,
+			MaxAttempts:          cfg.Webhook.MaxAttempts,
+			InitialBackoff:       cfg.Webhook.InitialBackoff,
+			MaxBackoff:           cfg.Webhook.MaxBackoff,
+			Timeout:              cfg.Webhook.Timeout,
+			AllowPrivateNetworks: cfg.Webhook.AllowPrivateNetworks,
+		},
+	})
+}
+
+// modelConfig 把配置文件中的模型设置转换为转录引擎的模型配置
+func modelConfig(modelType, modelPath, tokensPath string, files config.ModelFiles, cfg config.SherpaConfig) transcribe.ModelConfig {
+	model := transcribe.ModelConfig{
+		Type:            modelType,
+		Dir:             modelPath,
+		Tokens:          tokensPath,
+		Encoder:         files.Encoder,
+		Decoder:         files.Decoder,
+		Joiner:          files.Joiner,
+		Model:           files.Model,
+		Preprocessor:    files.Preprocessor,
+		UncachedDecoder: files.UncachedDecoder,
+		CachedDecoder:   files.CachedDecoder,
+		Language:        files.Language,
+		NumThreads:      cfg.NumThreads,
+		DecodingMethod:  cfg.DecodingMethod,
+		MaxActivePaths:  cfg.MaxActivePaths,
+		DisableEndpoint: !cfg.EnableEndpoint,
+		Endpoint: transcribe.EndpointRules{
+			Rule1MinTrailingSilence: cfg.Rule1MinTrailingSilence,
+			Rule2MinTrailingSilence: cfg.Rule2MinTrailingSilence,
+			Rule3MinUtteranceLength: cfg.Rule3MinUtteranceLength,
+		},
+		HotwordsScore: cfg.HotwordsScore,
+		ModelingUnit:  cfg.ModelingUnit,
+		BpeVocab:      cfg.BpeVocab,
+	}
+	// 只有 transducer 模型支持热词和 modified_beam_search，离线模型是 whisper 等其他类型时
+	// 不使用热词文件并使用 greedy_search
+	if transcribe.NormalizeModelType(modelType) == transcribe.ModelTransducer {
+		model.HotwordsFile = cfg.HotwordsFile
+	} else if model.DecodingMethod == transcribe.DecodingModifiedBeamSearch {
+		logrus.Warnf("%s 模型不支持 modified_beam_search，使用 greedy_search", transcribe.NormalizeModelType(modelType))
+		model.DecodingMethod = transcribe.DecodingGreedySearch
 	}
+	return model
 }
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// 上传文件扩展名对应的音频格式
+var extensionFormats = map[string]string{
+	".wav":  "wav",
+	".wave": "wav",
+	".flac": "flac",
+	".mp3":  "mp3",
+	".ogg":  "ogg",
+	".oga":  "ogg",
+	".opus": "ogg",
+	".pcm":  "pcm",
+	".raw":  "pcm",
+}
+
+// Content-Type 对应的音频格式，application/octet-stream 等通用类型不参与判断
+var contentTypeFormats = map[string]string{
+	"audio/wav":       "wav",
+	"audio/wave":      "wav",
+	"audio/x-wav":     "wav",
+	"audio/vnd.wave":  "wav",
+	"audio/flac":      "flac",
+	"audio/x-flac":    "flac",
+	"audio/mpeg":      "mp3",
+	"audio/mp3":       "mp3",
+	"audio/ogg":       "ogg",
+	"audio/opus":      "ogg",
+	"application/ogg": "ogg",
+	"audio/l16":       "pcm",
+	"audio/pcm":       "pcm",
+}
+
+// formatHints 客户端提供的格式线索
+type formatHints struct {
+	Filename    string
+	ContentType string
+	// Format 请求中显式指定的格式
+	Format string
+}
+
+// formatMismatchError 声明的格式与文件内容不符、无法确定格式，或者识别出的格式无法解码
+type formatMismatchError struct {
+	message string
+}
+
+func (e *formatMismatchError) Error() string {
+	return e.message
+}
+
+// resolveFormat 根据文件头魔数和客户端提供的线索确定音频格式
+// 魔数能识别时以魔数为准，所有线索都必须与之一致；
+// 无法识别时（原始 PCM 或未知格式）依次使用文件名、Content-Type 和显式的 format 字段
+func resolveFormat(data []byte, hints formatHints) (string, error) {
+	sniffed := transcribe.SniffFormat(data)
+
+	type hint struct {
+		source string
+		format string
+	}
+	var declared []hint
+	if ext := strings.ToLower(filepath.Ext(hints.Filename)); ext != "" {
+		if format, ok := extensionFormats[ext]; ok {
+			declared = append(declared, hint{"文件名", format})
+		}
+	}
+	if hints.ContentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(hints.ContentType); err == nil {
+			if format, ok := contentTypeFormats[strings.ToLower(mediaType)]; ok {
+				declared = append(declared, hint{"Content-Type", format})
+			}
+		}
+	}
+	if hints.Format != "" {
+		declared = append(declared, hint{"format 字段", transcribe.NormalizeFormat(hints.Format)})
+	}
+
+	if sniffed != "" {
+		// 例如 MPEG Layer I/II，识别出来了但没有解码器
+		if !transcribe.HasDecoder(sniffed) {
+			return "", &formatMismatchError{message: fmt.Sprintf("不支持的音频格式: %s", sniffed)}
+		}
+		for _, h := range declared {
+			if h.format != sniffed {
+				return "", &formatMismatchError{
+					message: fmt.Sprintf("%s声明的格式 %s 与文件内容 %s 不符", h.source, h.format, sniffed),
+				}
+			}
+		}
+		return sniffed, nil
+	}
+
+	if len(declared) == 0 {
+		return "", &formatMismatchError{message: "无法识别音频格式，原始 PCM 请指定 format 为 pcm"}
+	}
+
+	// 没有魔数时只能是原始 PCM，或者是通过注册解码器支持的其他格式
+	format := declared[0].format
+	for _, h := range declared[1:] {
+		if h.format != format {
+			return "", &formatMismatchError{
+				message: fmt.Sprintf("%s声明的格式 %s 与%s声明的格式 %s 不一致", h.source, h.format, declared[0].source, format),
+			}
+		}
+	}
+	switch format {
+	case "wav", "flac", "ogg", "mp3":
+		return "", &formatMismatchError{
+			message: fmt.Sprintf("%s声明的格式为 %s，但文件内容不是有效的 %s 数据", declared[0].source, format, format),
+		}
+	}
+	return format, nil
+}
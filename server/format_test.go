@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testWAV 生成 16 位单声道的静音 WAV 文件
+func testWAV(sampleRate, samples int) []byte {
+	data := make([]byte, 44+samples*2)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+samples*2))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], 1)
+	binary.LittleEndian.PutUint32(data[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(sampleRate*2))
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(samples*2))
+	return data
+}
+
+// testMP2 生成两个静音的 MPEG-1 Layer II 帧（384kbps, 32kHz），服务端不能解码
+func testMP2() []byte {
+	frame := make([]byte, 1728)
+	copy(frame, []byte{0xff, 0xfd, 0xe8, 0xc0})
+	return append(frame, frame...)
+}
+
+func TestResolveFormat(t *testing.T) {
+	wav := testWAV(16000, 10)
+	flac := []byte("fLaC\x00\x00\x00\x22")
+	raw := []byte{0x01, 0x00, 0x02, 0x00}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		hints    formatHints
+		expected string
+	}{
+		{"仅魔数", wav, formatHints{}, "wav"},
+		{"以 FF FF 开始的 PCM", []byte{0xff, 0xff, 0, 0, 1, 0}, formatHints{Format: "pcm"}, "pcm"},
+		{"魔数与文件名一致", flac, formatHints{Filename: "a.FLAC"}, "flac"},
+		{"通用 Content-Type 不参与判断", wav, formatHints{ContentType: "application/octet-stream"}, "wav"},
+		{"未知扩展名不参与判断", wav, formatHints{Filename: "recording.dat"}, "wav"},
+		{"Opus 别名", []byte("OggS"), formatHints{Filename: "note.opus", Format: "opus"}, "ogg"},
+		{"原始 PCM 由 format 字段指定", raw, formatHints{Format: "pcm"}, "pcm"},
+		{"原始 PCM 由 Content-Type 指定", raw, formatHints{ContentType: "audio/L16; rate=16000"}, "pcm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := resolveFormat(tt.data, tt.hints)
+			if err != nil {
+				t.Fatalf("确定格式失败: %v", err)
+			}
+			if format != tt.expected {
+				t.Errorf("期望: %s, 实际: %s", tt.expected, format)
+			}
+		})
+	}
+}
+
+func TestResolveFormatMismatch(t *testing.T) {
+	wav := testWAV(16000, 10)
+	raw := []byte{0x01, 0x00, 0x02, 0x00}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		hints formatHints
+	}{
+		{"文件名与内容不符", wav, formatHints{Filename: "a.mp3"}},
+		{"Content-Type 与内容不符", wav, formatHints{ContentType: "audio/flac"}},
+		{"format 字段与内容不符", wav, formatHints{Format: "pcm"}},
+		{"声明为 WAV 但不是 WAV", raw, formatHints{Format: "wav"}},
+		{"MPEG Layer II", testMP2(), formatHints{}},
+		{"MPEG Layer II 声明为 MP3", testMP2(), formatHints{Filename: "a.mp3"}},
+		{"线索互相矛盾", raw, formatHints{Filename: "a.pcm", ContentType: "audio/mpeg"}},
+		{"没有任何线索", raw, formatHints{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveFormat(tt.data, tt.hints)
+			var mismatch *formatMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Errorf("期望 formatMismatchError，实际: %v", err)
+			}
+		})
+	}
+}
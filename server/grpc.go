@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+
+	transcribev1 "github.com/layzdonw/transerver/api/transcribe/v1"
+	"github.com/layzdonw/transerver/config"
+	"github.com/layzdonw/transerver/transcribe"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService 转录服务的 gRPC 实现，与 HTTP 接口共用转录引擎
+type grpcService struct {
+	transcribev1.UnimplementedTranscriberServer
+	server *Server
+}
+
+// NewGRPCServer 创建注册了转录服务的 gRPC 服务器
+// 单条消息的大小上限与上传大小上限相同，Close 时结束所有流式识别
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	maxSize := math.MaxInt32
+	if s.maxUploadSize > 0 && s.maxUploadSize < math.MaxInt32 {
+		maxSize = int(s.maxUploadSize)
+	}
+	opts = append([]grpc.ServerOption{grpc.MaxRecvMsgSize(maxSize)}, opts...)
+
+	server := grpc.NewServer(opts...)
+	transcribev1.RegisterTranscriberServer(server, &grpcService{server: s})
+	return server
+}
+
+// StartGRPC 按配置监听 gRPC 端口或 Unix socket 并开始服务
+func (s *Server) StartGRPC() error {
+	cfg := config.AppConfig.Server
+	if !cfg.GRPC.Enabled {
+		s.logger.Info("gRPC 服务未启用")
+		return nil
+	}
+
+	listener, err := listen(cfg.UseUnixSocket, cfg.GRPC.UnixSocket, cfg.Host, cfg.GRPC.Port)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	// 与 Start 相同，Shutdown 之后不再开始服务
+	server := s.NewGRPCServer()
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.grpc = server
+	s.mu.Unlock()
+
+	s.logger.Infof("gRPC 服务器启动在: %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+func (g *grpcService) Recognize(ctx context.Context, req *transcribev1.RecognizeRequest) (*transcribev1.RecognizeResponse, error) {
+	switch req.Mode {
+	case "", transcribe.ModeOnline, transcribe.ModeOffline:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "无效的识别模式: %s", req.Mode)
+	}
+	if req.SampleRate < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "无效的采样率: %d", req.SampleRate)
+	}
+
+	decoding := transcribe.DecodingOptions{
+		Hotwords:       req.Hotwords,
+		HotwordsScore:  req.HotwordsScore,
+		DecodingMethod: req.DecodingMethod,
+		MaxActivePaths: int(req.MaxActivePaths),
+	}
+	if err := decoding.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	format, err := resolveFormat(req.Audio, formatHints{Format: req.Format})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := g.server.transcriber.TranscribeAudio(req.Audio, transcribe.TranscribeOptions{
+		Format:     format,
+		SampleRate: int(req.SampleRate),
+		Mode:       req.Mode,
+		Timestamps: req.Timestamps,
+
+		DecodingOptions: decoding,
+	})
+	if err != nil {
+		g.server.logger.Errorf("转录失败: %v", err)
+		return nil, grpcError("转录失败", err)
+	}
+	return newRecognizeResponse(result), nil
+}
+
+func (g *grpcService) StreamingRecognize(stream transcribev1.Transcriber_StreamingRecognizeServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	cfg := first.GetConfig()
+	if cfg == nil {
+		return status.Error(codes.InvalidArgument, "第一条消息必须是 config")
+	}
+	streamConfig := StreamConfig{
+		SampleRate: int(cfg.SampleRate),
+		Format:     cfg.Format,
+		Language:   cfg.Language,
+	}
+	if streamConfig.Format == "" {
+		streamConfig.Format = "pcm"
+	}
+	streamConfig.Options = &StreamOptions{PartialResults: cfg.PartialResults, Timestamps: cfg.Timestamps}
+	streamConfig.Options.setDecoding(transcribe.DecodingOptions{
+		Hotwords:       cfg.Hotwords,
+		HotwordsScore:  cfg.HotwordsScore,
+		DecodingMethod: cfg.DecodingMethod,
+		MaxActivePaths: int(cfg.MaxActivePaths),
+		EnableEndpoint: cfg.EnableEndpoint,
+		Endpoint: transcribe.EndpointRules{
+			Rule1MinTrailingSilence: cfg.Rule1MinTrailingSilence,
+			Rule2MinTrailingSilence: cfg.Rule2MinTrailingSilence,
+			Rule3MinUtteranceLength: cfg.Rule3MinUtteranceLength,
+		},
+	})
+	if err := validateStreamConfig(&streamConfig); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if streamConfig.SampleRate == 0 {
+		streamConfig.SampleRate = g.server.transcriber.GetSampleRate()
+	}
+
+	decoding, _ := streamConfig.decodingOptions()
+	session, err := g.server.transcriber.NewSession(decoding)
+	if err != nil {
+		g.server.logger.Errorf("无法创建识别会话: %v", err)
+		return grpcError("无法创建识别会话", err)
+	}
+	defer session.Close()
+
+	err = stream.Send(&transcribev1.StreamingRecognizeResponse{
+		Event: &transcribev1.StreamingRecognizeResponse_Ready{Ready: &transcribev1.Ready{
+			Version:         ProtocolVersion,
+			SampleRate:      int32(streamConfig.SampleRate),
+			ModelSampleRate: int32(g.server.transcriber.GetSampleRate()),
+			Format:          streamConfig.Format,
+			Language:        streamConfig.Language,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// 在单独的 goroutine 中接收消息，服务器关闭时不必等客户端发送下一条消息
+	ctx := stream.Context()
+	requests := make(chan *transcribev1.StreamingRecognizeRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	driver := streamDriver{
+		session:    session,
+		partials:   streamConfig.partialResults(),
+		timestamps: streamConfig.timestamps(),
+		emit: func(event ResultEvent) error {
+			return stream.Send(&transcribev1.StreamingRecognizeResponse{
+				Event: &transcribev1.StreamingRecognizeResponse_Result{Result: newStreamResult(event)},
+			})
+		},
+	}
+	for {
+		var req *transcribev1.StreamingRecognizeRequest
+		select {
+		case req = <-requests:
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			// 客户端关闭发送端，返回剩余的结果后结束
+			return streamStatus(driver.flush())
+		case <-g.server.ctx.Done():
+			return status.Error(codes.Unavailable, "服务器正在关闭")
+		}
+
+		var err error
+		switch r := req.Request.(type) {
+		case *transcribev1.StreamingRecognizeRequest_Config:
+			return status.Error(codes.FailedPrecondition, "会话已经开始")
+		case *transcribev1.StreamingRecognizeRequest_Audio:
+			err = driver.acceptChunk(&streamConfig, r.Audio)
+		case *transcribev1.StreamingRecognizeRequest_Flush:
+			err = driver.flush()
+		default:
+			return status.Error(codes.InvalidArgument, "消息缺少 config、audio 或 flush")
+		}
+		if err != nil {
+			return streamStatus(err)
+		}
+	}
+}
+
+// newStreamResult 把结果事件转换为 gRPC 消息
+func newStreamResult(event ResultEvent) *transcribev1.Result {
+	return &transcribev1.Result{
+		Final:      event.Type == EventFinal,
+		Utterance:  int32(event.Utterance),
+		Text:       event.Text,
+		Start:      event.Start,
+		End:        event.End,
+		Confidence: event.Confidence,
+		Words:      newProtoWords(event.Words),
+		Tokens:     newProtoTokens(event.Tokens),
+	}
+}
+
+// streamStatus 把会话驱动返回的协议错误映射为 gRPC 状态码，发送消息的错误原样返回
+func streamStatus(err error) error {
+	perr, ok := err.(*protocolError)
+	if !ok {
+		return err
+	}
+	code := codes.Internal
+	if perr.code == ErrorInvalidAudio || perr.code == ErrorInvalidMessage || perr.code == ErrorTimestampsUnavailable {
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, perr.message)
+}
+
+// grpcError 把转录错误映射为 gRPC 状态码，与 HTTP 接口的 errorStatus 对应
+func grpcError(message string, err error) error {
+	code := codes.Internal
+	var unsupported *transcribe.UnsupportedFormatError
+	switch {
+	case errors.As(err, &unsupported), errors.Is(err, transcribe.ErrModeUnavailable), errors.Is(err, transcribe.ErrInvalidOptions),
+		errors.Is(err, transcribe.ErrTimestampsUnavailable):
+		code = codes.InvalidArgument
+	case errors.Is(err, transcribe.ErrQueueFull):
+		code = codes.ResourceExhausted
+	case errors.Is(err, transcribe.ErrQueueTimeout), errors.Is(err, transcribe.ErrPoolClosed), errors.Is(err, transcribe.ErrTooManyRecognizers):
+		code = codes.Unavailable
+	}
+	return status.Errorf(code, "%s: %v", message, err)
+}
+
+// newRecognizeResponse 把转录结果转换为 gRPC 响应
+func newRecognizeResponse(result *transcribe.TranscriptionResult) *transcribev1.RecognizeResponse {
+	resp := &transcribev1.RecognizeResponse{
+		Text:       result.Text,
+		Confidence: result.Confidence,
+		Duration:   result.Duration,
+	}
+	for _, seg := range result.SpeakerSegments {
+		resp.SpeakerSegments = append(resp.SpeakerSegments, &transcribev1.SpeakerSegment{
+			SpeakerId:  int32(seg.SpeakerID),
+			Start:      seg.Start,
+			End:        seg.End,
+			Text:       seg.Text,
+			Confidence: seg.Confidence,
+		})
+	}
+	for _, seg := range result.Segments {
+		resp.Segments = append(resp.Segments, &transcribev1.Segment{
+			Start:      seg.Start,
+			End:        seg.End,
+			Text:       seg.Text,
+			Confidence: seg.Confidence,
+		})
+	}
+	resp.Words = newProtoWords(result.Words)
+	resp.Tokens = newProtoTokens(result.Tokens)
+	return resp
+}
+
+func newProtoWords(words []transcribe.Word) []*transcribev1.Word {
+	var out []*transcribev1.Word
+	for _, word := range words {
+		out = append(out, &transcribev1.Word{
+			Text:       word.Text,
+			Start:      word.Start,
+			End:        word.End,
+			Confidence: word.Confidence,
+		})
+	}
+	return out
+}
+
+func newProtoTokens(tokens []transcribe.Token) []*transcribev1.Token {
+	var out []*transcribev1.Token
+	for _, token := range tokens {
+		out = append(out, &transcribev1.Token{
+			Text:       token.Text,
+			Start:      token.Start,
+			End:        token.End,
+			Confidence: token.Confidence,
+		})
+	}
+	return out
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	transcribev1 "github.com/layzdonw/transerver/api/transcribe/v1"
+	"github.com/layzdonw/transerver/config"
+	"github.com/layzdonw/transerver/transcribe"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// grpcTestClient 在内存连接上启动 gRPC 服务并返回客户端
+func grpcTestClient(t *testing.T, fake *transcribe.FakeTranscriber) (transcribev1.TranscriberClient, *Server) {
+	t.Helper()
+
+	srv := NewServer(fake)
+	listener := bufconn.Listen(1 << 20)
+	server := srv.NewGRPCServer()
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("无法创建 gRPC 客户端: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Close()
+		server.Stop()
+	})
+	return transcribev1.NewTranscriberClient(conn), srv
+}
+
+func TestGRPCRecognize(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Tokens = []transcribe.Token{{Text: "你", Start: 0, End: 0.05, Confidence: 0.9}, {Text: "好", Start: 0.05, End: 0.1, Confidence: 0.8}}
+	client, _ := grpcTestClient(t, fake)
+
+	resp, err := client.Recognize(context.Background(), &transcribev1.RecognizeRequest{
+		Audio:      testWAV(16000, 1600),
+		Timestamps: true,
+	})
+	if err != nil {
+		t.Fatalf("识别失败: %v", err)
+	}
+	if resp.Text != "你好" || resp.Duration != 0.1 {
+		t.Errorf("识别结果不正确: %+v", resp)
+	}
+	if len(resp.Tokens) != 2 || resp.Tokens[1].Text != "好" || resp.Tokens[1].Start != 0.05 {
+		t.Errorf("token 不正确: %+v", resp.Tokens)
+	}
+	if opts := fake.LastOptions(); opts.Format != "wav" || !opts.Timestamps {
+		t.Errorf("转录选项不正确: %+v", opts)
+	}
+}
+
+func TestGRPCRecognizeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *transcribev1.RecognizeRequest
+		err      error
+		expected codes.Code
+	}{
+		{"无效的识别模式", &transcribev1.RecognizeRequest{Audio: pcm16(160, 0), Format: "pcm", Mode: "batch"}, nil, codes.InvalidArgument},
+		{"不支持的格式", &transcribev1.RecognizeRequest{Audio: pcm16(160, 0), Format: "aac"}, nil, codes.InvalidArgument},
+		{"不支持的解码方法", &transcribev1.RecognizeRequest{Audio: pcm16(160, 0), Format: "pcm", DecodingMethod: "beam"}, nil, codes.InvalidArgument},
+		{"队列已满", &transcribev1.RecognizeRequest{Audio: pcm16(160, 0), Format: "pcm"}, transcribe.ErrQueueFull, codes.ResourceExhausted},
+		{"转录池已关闭", &transcribev1.RecognizeRequest{Audio: pcm16(160, 0), Format: "pcm"}, transcribe.ErrPoolClosed, codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := transcribe.NewFakeTranscriber("你好")
+			fake.Err = tt.err
+			client, _ := grpcTestClient(t, fake)
+
+			_, err := client.Recognize(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.expected {
+				t.Errorf("期望状态码 %v，得到 %v (%v)", tt.expected, code, err)
+			}
+		})
+	}
+}
+
+func TestGRPCStreamingRecognize(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	client, _ := grpcTestClient(t, fake)
+
+	stream, err := client.StreamingRecognize(context.Background())
+	if err != nil {
+		t.Fatalf("无法开始流式识别: %v", err)
+	}
+	err = stream.Send(&transcribev1.StreamingRecognizeRequest{
+		Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{SampleRate: 8000}},
+	})
+	if err != nil {
+		t.Fatalf("发送配置失败: %v", err)
+	}
+
+	recv := func() *transcribev1.StreamingRecognizeResponse {
+		t.Helper()
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("接收消息失败: %v", err)
+		}
+		return resp
+	}
+	sendAudio := func(data []byte) {
+		t.Helper()
+		err := stream.Send(&transcribev1.StreamingRecognizeRequest{
+			Request: &transcribev1.StreamingRecognizeRequest_Audio{Audio: data},
+		})
+		if err != nil {
+			t.Fatalf("发送音频失败: %v", err)
+		}
+	}
+
+	if ready := recv().GetReady(); ready == nil || ready.SampleRate != 8000 || ready.Format != "pcm" {
+		t.Fatalf("期望 ready 消息，得到 %+v", ready)
+	}
+
+	sendAudio(pcm16(800, 1000))
+	if result := recv().GetResult(); result == nil || result.Final || result.Text != "你好" {
+		t.Fatalf("期望 partial 结果，得到 %+v", result)
+	}
+	sendAudio(pcm16(800, 0))
+	if result := recv().GetResult(); result == nil || !result.Final || result.Utterance != 0 {
+		t.Fatalf("期望 final 结果，得到 %+v", result)
+	}
+
+	// flush 立即结束当前句子
+	sendAudio(pcm16(800, 1000))
+	if result := recv().GetResult(); result == nil || result.Final {
+		t.Fatalf("期望 partial 结果，得到 %+v", result)
+	}
+	err = stream.Send(&transcribev1.StreamingRecognizeRequest{
+		Request: &transcribev1.StreamingRecognizeRequest_Flush{Flush: &transcribev1.Flush{}},
+	})
+	if err != nil {
+		t.Fatalf("发送 flush 失败: %v", err)
+	}
+	if result := recv().GetResult(); result == nil || !result.Final || result.Utterance != 1 {
+		t.Fatalf("期望 final 结果，得到 %+v", result)
+	}
+
+	// 关闭发送端后返回剩余结果并结束
+	sendAudio(pcm16(800, 1000))
+	stream.CloseSend()
+	if result := recv().GetResult(); result == nil || result.Final {
+		t.Fatalf("期望 partial 结果，得到 %+v", result)
+	}
+	if result := recv().GetResult(); result == nil || !result.Final || result.Utterance != 2 {
+		t.Fatalf("期望 final 结果，得到 %+v", result)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("期望流结束，得到 %v", err)
+	}
+
+	sessions := fake.Sessions()
+	if len(sessions) != 1 || !sessions[0].Closed() {
+		t.Fatalf("识别会话应该被关闭")
+	}
+}
+
+func TestGRPCStreamingRecognizeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []*transcribev1.StreamingRecognizeRequest
+		expected codes.Code
+	}{
+		{
+			"第一条消息不是配置",
+			[]*transcribev1.StreamingRecognizeRequest{
+				{Request: &transcribev1.StreamingRecognizeRequest_Audio{Audio: pcm16(160, 0)}},
+			},
+			codes.InvalidArgument,
+		},
+		{
+			"不支持的格式",
+			[]*transcribev1.StreamingRecognizeRequest{
+				{Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{Format: "aac"}}},
+			},
+			codes.InvalidArgument,
+		},
+		{
+			"采样没有对齐",
+			[]*transcribev1.StreamingRecognizeRequest{
+				{Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{}}},
+				{Request: &transcribev1.StreamingRecognizeRequest_Audio{Audio: []byte{1, 2, 3}}},
+			},
+			codes.InvalidArgument,
+		},
+		{
+			"重复发送配置",
+			[]*transcribev1.StreamingRecognizeRequest{
+				{Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{}}},
+				{Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{}}},
+			},
+			codes.FailedPrecondition,
+		},
+		{
+			"模型不提供时间戳",
+			[]*transcribev1.StreamingRecognizeRequest{
+				{Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{Timestamps: true}}},
+				{Request: &transcribev1.StreamingRecognizeRequest_Audio{Audio: pcm16(1600, 1000)}},
+			},
+			codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := grpcTestClient(t, transcribe.NewFakeTranscriber("你好"))
+
+			stream, err := client.StreamingRecognize(context.Background())
+			if err != nil {
+				t.Fatalf("无法开始流式识别: %v", err)
+			}
+			for _, req := range tt.requests {
+				if err := stream.Send(req); err != nil {
+					break
+				}
+			}
+
+			// ready 之后的第一个错误就是会话的结果
+			for {
+				_, err = stream.Recv()
+				if err != nil {
+					break
+				}
+			}
+			if code := status.Code(err); code != tt.expected {
+				t.Errorf("期望状态码 %v，得到 %v (%v)", tt.expected, code, err)
+			}
+		})
+	}
+}
+
+func TestGRPCStreamingRecognizeShutdown(t *testing.T) {
+	client, srv := grpcTestClient(t, transcribe.NewFakeTranscriber("你好"))
+
+	stream, err := client.StreamingRecognize(context.Background())
+	if err != nil {
+		t.Fatalf("无法开始流式识别: %v", err)
+	}
+	stream.Send(&transcribev1.StreamingRecognizeRequest{
+		Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{}},
+	})
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("期望 ready 消息，得到 %v", err)
+	}
+
+	srv.Close()
+	_, err = stream.Recv()
+	if code := status.Code(err); code != codes.Unavailable {
+		t.Errorf("期望状态码 %v，得到 %v (%v)", codes.Unavailable, code, err)
+	}
+}
+
+func TestGRPCRecognizeHotwords(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	client, _ := grpcTestClient(t, fake)
+
+	_, err := client.Recognize(context.Background(), &transcribev1.RecognizeRequest{
+		Audio:         pcm16(1600, 0),
+		Format:        "pcm",
+		Hotwords:      []string{"语音识别"},
+		HotwordsScore: 2,
+	})
+	if err != nil {
+		t.Fatalf("识别失败: %v", err)
+	}
+	if opts := fake.LastOptions(); len(opts.Hotwords) != 1 || opts.HotwordsScore != 2 {
+		t.Errorf("热词没有传给转录引擎: %+v", opts.DecodingOptions)
+	}
+
+	_, err = client.Recognize(context.Background(), &transcribev1.RecognizeRequest{
+		Audio:         pcm16(1600, 0),
+		Format:        "pcm",
+		HotwordsScore: 100,
+	})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("期望状态码 %v，得到 %v (%v)", codes.InvalidArgument, code, err)
+	}
+}
+
+func TestGRPCStreamingRecognizeDecodingOptions(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	client, _ := grpcTestClient(t, fake)
+
+	stream, err := client.StreamingRecognize(context.Background())
+	if err != nil {
+		t.Fatalf("无法开始流式识别: %v", err)
+	}
+	enable := false
+	err = stream.Send(&transcribev1.StreamingRecognizeRequest{
+		Request: &transcribev1.StreamingRecognizeRequest_Config{Config: &transcribev1.StreamingConfig{
+			DecodingMethod:          transcribe.DecodingModifiedBeamSearch,
+			MaxActivePaths:          8,
+			EnableEndpoint:          &enable,
+			Rule3MinUtteranceLength: 15,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("发送配置失败: %v", err)
+	}
+	if resp, err := stream.Recv(); err != nil || resp.GetReady() == nil {
+		t.Fatalf("期望 ready 消息，得到 %+v %v", resp, err)
+	}
+
+	opts := fake.Sessions()[0].Options()
+	if opts.DecodingMethod != transcribe.DecodingModifiedBeamSearch || opts.MaxActivePaths != 8 {
+		t.Errorf("会话的解码参数错误: %+v", opts)
+	}
+	if opts.EnableEndpoint == nil || *opts.EnableEndpoint || opts.Endpoint.Rule3MinUtteranceLength != 15 {
+		t.Errorf("会话的端点检测选项错误: %+v", opts)
+	}
+	stream.CloseSend()
+}
+
+func TestStartGRPCEnabled(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	// 未启用时使用 Unix socket 也不启动
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	config.AppConfig.Server = config.ServerConfig{
+		UseUnixSocket: true,
+		GRPC:          config.GRPCConfig{UnixSocket: socket},
+	}
+	srv := NewServer(transcribe.NewFakeTranscriber("你好"))
+	defer srv.Close()
+	if err := srv.StartGRPC(); err != nil {
+		t.Fatalf("未启用时不应该返回错误: %v", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("未启用时不应该创建 socket: %v", err)
+	}
+
+	// 启用后监听 socket，Shutdown 之后返回
+	config.AppConfig.Server.GRPC.Enabled = true
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.StartGRPC()
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("启用后没有创建 socket")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown 失败: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Shutdown 之后期望正常返回，得到 %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/layzdonw/transerver/jobs"
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// sniffSize 判断音频格式时读取的文件头长度
+const sniffSize = transcribe.SniffSize
+
+type JobResponse struct {
+	Success bool      `json:"success"`
+	Job     *jobs.Job `json:"job,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// SetJobManager 启用异步任务接口
+func (s *Server) SetJobManager(manager *jobs.Manager) {
+	s.jobs = manager
+}
+
+// createJobHandler 创建异步转录任务
+// 支持 multipart/form-data（字段与 /transcribe 相同），或者请求体直接是音频、参数放在查询字符串中
+func (s *Server) createJobHandler(c *gin.Context) {
+	if s.jobs == nil {
+		s.jobsDisabled(c)
+		return
+	}
+
+	var (
+		audio io.Reader
+		hints formatHints
+		field func(string) string
+	)
+	if c.ContentType() == "multipart/form-data" {
+		upload, err := readMultipartUpload(c.Request)
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), JobResponse{
+				Success: false,
+				Error:   "无法获取音频文件: " + err.Error(),
+			})
+			return
+		}
+
+		// 音频之后还有表单字段时保存失败，不会创建参数不完整的任务
+		audio = upload.audioReader()
+		field = upload.field
+		hints = formatHints{
+			Filename:    upload.audio.FileName(),
+			ContentType: upload.audio.Header.Get("Content-Type"),
+		}
+	} else {
+		audio = c.Request.Body
+		field = c.Query
+		hints = formatHints{ContentType: c.GetHeader("Content-Type")}
+	}
+	hints.Format = field("format")
+
+	opts, err := parseJobOptions(field)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 只读取文件头来确定格式，音频直接写入任务存储
+	reader := bufio.NewReaderSize(audio, sniffSize)
+	header, _ := reader.Peek(sniffSize)
+	if len(header) == 0 {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Success: false,
+			Error:   "音频数据为空",
+		})
+		return
+	}
+	opts.Format, err = resolveFormat(header, hints)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, JobResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	job, err := s.jobs.Submit(reader, opts)
+	if err != nil {
+		s.logger.Errorf("创建任务失败: %v", err)
+		c.JSON(jobErrorStatus(err), JobResponse{
+			Success: false,
+			Error:   "创建任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.Header("Location", "/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, JobResponse{
+		Success: true,
+		Job:     job,
+	})
+}
+
+// parseJobOptions 读取任务的转录参数
+func parseJobOptions(field func(string) string) (jobs.Options, error) {
+	var opts jobs.Options
+
+	if v := field("sample_rate"); v != "" {
+		sampleRate, err := strconv.Atoi(v)
+		if err != nil || sampleRate <= 0 {
+			return opts, fmt.Errorf("无效的采样率: %s", v)
+		}
+		opts.SampleRate = sampleRate
+	}
+
+	opts.Mode = field("mode")
+	switch opts.Mode {
+	case "", transcribe.ModeOnline, transcribe.ModeOffline:
+	default:
+		return opts, fmt.Errorf("无效的识别模式: %s", opts.Mode)
+	}
+
+	if v := field("timestamps"); v != "" {
+		timestamps, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("无效的 timestamps 参数: %s", v)
+		}
+		opts.Timestamps = timestamps
+	}
+
+	decoding, err := parseDecodingFields(field)
+	if err != nil {
+		return opts, err
+	}
+	opts.Hotwords = decoding.Hotwords
+	opts.HotwordsScore = decoding.HotwordsScore
+	opts.DecodingMethod = decoding.DecodingMethod
+	opts.MaxActivePaths = decoding.MaxActivePaths
+
+	opts.CallbackURL = field("callback_url")
+	return opts, nil
+}
+
+// getJobHandler 返回任务状态
+// 指定 output_format 时直接返回渲染后的转录结果，任务尚未成功时返回 409
+func (s *Server) getJobHandler(c *gin.Context) {
+	if s.jobs == nil {
+		s.jobsDisabled(c)
+		return
+	}
+
+	job, err := s.jobs.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(jobErrorStatus(err), JobResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req TranscribeRequest
+	req.OutputFormat = c.Query("output_format")
+	if req.OutputFormat == "" || req.OutputFormat == transcribe.OutputJSON {
+		c.JSON(http.StatusOK, JobResponse{
+			Success: true,
+			Job:     job,
+		})
+		return
+	}
+
+	if !transcribe.IsOutputFormat(req.OutputFormat) {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Success: false,
+			Error:   "无效的输出格式: " + req.OutputFormat,
+		})
+		return
+	}
+	if err := parseSubtitleFields(c.Query, &req); err != nil {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if job.Status != jobs.StatusSucceeded {
+		c.JSON(http.StatusConflict, JobResponse{
+			Success: false,
+			Job:     job,
+			Error:   "任务尚未成功完成",
+		})
+		return
+	}
+
+	data, err := transcribe.RenderTranscript(job.Result, req.OutputFormat, transcribe.SubtitleOptions{
+		MaxLineLength: req.MaxLineLength,
+		MaxLines:      req.MaxLines,
+		MaxDuration:   req.MaxCueDuration,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, JobResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, transcribe.OutputContentType(req.OutputFormat), data)
+}
+
+// deleteJobHandler 取消未结束的任务，或者删除已结束的任务
+func (s *Server) deleteJobHandler(c *gin.Context) {
+	if s.jobs == nil {
+		s.jobsDisabled(c)
+		return
+	}
+
+	job, err := s.jobs.Cancel(c.Param("id"))
+	if err != nil {
+		c.JSON(jobErrorStatus(err), JobResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, JobResponse{
+		Success: true,
+		Job:     job,
+	})
+}
+
+func (s *Server) jobsDisabled(c *gin.Context) {
+	c.JSON(http.StatusNotFound, JobResponse{
+		Success: false,
+		Error:   "异步任务未启用",
+	})
+}
+
+// jobErrorStatus 把任务错误映射为 HTTP 状态码
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobs.ErrInvalidCallbackURL), errors.Is(err, jobs.ErrCallbackDisabled), errors.Is(err, errFieldAfterAudio):
+		return http.StatusBadRequest
+	case errors.Is(err, jobs.ErrQueueFull):
+		return http.StatusTooManyRequests
+	case errors.Is(err, jobs.ErrClosed):
+		return http.StatusServiceUnavailable
+	case isTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
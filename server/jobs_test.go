@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/layzdonw/transerver/jobs"
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// jobTestServer 创建启用异步任务的测试服务器
+func jobTestServer(t *testing.T, transcriber transcribe.Transcriber) *Server {
+	gin.SetMode(gin.TestMode)
+
+	manager, err := jobs.NewManager(jobs.NewMemoryStore(), transcriber, jobs.Config{})
+	if err != nil {
+		t.Fatalf("创建任务管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+
+	srv := NewServer(transcriber)
+	srv.SetJobManager(manager)
+	return srv
+}
+
+func doJobRequest(t *testing.T, srv *Server, req *http.Request) (*httptest.ResponseRecorder, JobResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+
+	var response JobResponse
+	if w.Header().Get("Content-Type") == "application/json; charset=utf-8" {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("无法解析响应 JSON: %v", err)
+		}
+	}
+	return w, response
+}
+
+// waitJob 轮询任务直到结束
+func waitJob(t *testing.T, srv *Server, id string) *jobs.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		req, _ := http.NewRequest("GET", "/jobs/"+id, nil)
+		w, response := doJobRequest(t, srv, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("查询任务期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if response.Job.Status.Done() {
+			return response.Job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务没有结束: %+v", response.Job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Tokens = []transcribe.Token{{Text: "你", Start: 0, End: 0.05}, {Text: "好", Start: 0.05, End: 0.1}}
+	srv := jobTestServer(t, fake)
+
+	// 请求体直接是音频
+	req, _ := http.NewRequest("POST", "/jobs?timestamps=true", bytes.NewReader(testWAV(16000, 1600)))
+	req.Header.Set("Content-Type", "audio/wav")
+	w, response := doJobRequest(t, srv, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	id := response.Job.ID
+	if w.Header().Get("Location") != "/jobs/"+id || response.Job.Options.Format != "wav" || !response.Job.Options.Timestamps {
+		t.Errorf("创建的任务错误: %+v, Location: %s", response.Job, w.Header().Get("Location"))
+	}
+
+	job := waitJob(t, srv, id)
+	if job.Status != jobs.StatusSucceeded || job.Result.Text != "你好" || len(job.Result.Words) != 2 {
+		t.Fatalf("任务结果错误: %+v", job)
+	}
+
+	req, _ = http.NewRequest("GET", "/jobs/"+id+"?output_format=text", nil)
+	w, _ = doJobRequest(t, srv, req)
+	if w.Code != http.StatusOK || w.Body.String() != "你好\n" {
+		t.Errorf("文本输出错误: %d %q", w.Code, w.Body.String())
+	}
+
+	// 删除已经结束的任务
+	req, _ = http.NewRequest("DELETE", "/jobs/"+id, nil)
+	if w, _ := doJobRequest(t, srv, req); w.Code != http.StatusOK {
+		t.Errorf("删除任务期望状态码 %d，得到 %d", http.StatusOK, w.Code)
+	}
+	req, _ = http.NewRequest("GET", "/jobs/"+id, nil)
+	if w, _ := doJobRequest(t, srv, req); w.Code != http.StatusNotFound {
+		t.Errorf("删除后期望状态码 %d，得到 %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestJobMultipart(t *testing.T) {
+	srv := jobTestServer(t, transcribe.NewFakeTranscriber("你好"))
+
+	req := multipartRequest(t, "audio.pcm", "", make([]byte, 3200), map[string]string{"sample_rate": "8000"})
+	req.URL.Path = "/jobs"
+	w, response := doJobRequest(t, srv, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if opts := response.Job.Options; opts.Format != "pcm" || opts.SampleRate != 8000 {
+		t.Errorf("任务选项错误: %+v", opts)
+	}
+	if job := waitJob(t, srv, response.Job.ID); job.Status != jobs.StatusSucceeded || job.Result.Duration != 0.2 {
+		t.Errorf("任务结果错误: %+v", job)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Gate = make(chan struct{})
+	srv := jobTestServer(t, fake)
+	defer close(fake.Gate)
+
+	req, _ := http.NewRequest("POST", "/jobs?format=pcm", bytes.NewReader(make([]byte, 3200)))
+	_, response := doJobRequest(t, srv, req)
+
+	// 任务还没有结果时不能渲染
+	req, _ = http.NewRequest("GET", "/jobs/"+response.Job.ID+"?output_format=srt", nil)
+	if w, _ := doJobRequest(t, srv, req); w.Code != http.StatusConflict {
+		t.Errorf("未完成的任务期望状态码 %d，得到 %d", http.StatusConflict, w.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/jobs/"+response.Job.ID, nil)
+	w, response := doJobRequest(t, srv, req)
+	if w.Code != http.StatusOK || response.Job.Status != jobs.StatusCanceled {
+		t.Errorf("取消任务错误: %d %+v", w.Code, response.Job)
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	srv := jobTestServer(t, transcribe.NewFakeTranscriber("你好"))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     []byte
+		expected int
+	}{
+		{"无法识别格式", "POST", "/jobs", make([]byte, 3200), http.StatusUnsupportedMediaType},
+		{"MPEG Layer II", "POST", "/jobs", testMP2(), http.StatusUnsupportedMediaType},
+		{"空音频", "POST", "/jobs?format=pcm", nil, http.StatusBadRequest},
+		{"无效的识别模式", "POST", "/jobs?format=pcm&mode=fast", make([]byte, 3200), http.StatusBadRequest},
+		{"无效的回调地址", "POST", "/jobs?format=pcm&callback_url=ftp://example.com", make([]byte, 3200), http.StatusBadRequest},
+		{"未设置回调密钥", "POST", "/jobs?format=pcm&callback_url=https://example.com/hook", make([]byte, 3200), http.StatusBadRequest},
+		{"任务不存在", "GET", "/jobs/missing", nil, http.StatusNotFound},
+		{"取消不存在的任务", "DELETE", "/jobs/missing", nil, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewReader(tt.body))
+			if w, _ := doJobRequest(t, srv, req); w.Code != tt.expected {
+				t.Errorf("期望状态码 %d，得到 %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// 超过上传大小限制时不创建任务，没有 Content-Length 时在写入存储时发现
+	srv.SetMaxUploadSize(1000)
+	req, _ := http.NewRequest("POST", "/jobs?format=pcm", io.MultiReader(bytes.NewReader(make([]byte, 3200))))
+	if w, _ := doJobRequest(t, srv, req); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("超过上传大小限制期望状态码 %d，得到 %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	// 未启用异步任务
+	disabled := NewServer(transcribe.NewFakeTranscriber("你好"))
+	req, _ = http.NewRequest("POST", "/jobs?format=pcm", bytes.NewReader(make([]byte, 3200)))
+	if w, _ := doJobRequest(t, disabled, req); w.Code != http.StatusNotFound {
+		t.Errorf("未启用时期望状态码 %d，得到 %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestJobDecodingOptions(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	srv := jobTestServer(t, fake)
+
+	req, _ := http.NewRequest("POST", "/jobs?format=pcm&hotwords=语音识别,转录&hotwords_score=2&max_active_paths=8", bytes.NewReader(make([]byte, 3200)))
+	w, response := doJobRequest(t, srv, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if opts := response.Job.Options; len(opts.Hotwords) != 2 || opts.HotwordsScore != 2 || opts.MaxActivePaths != 8 {
+		t.Errorf("任务选项错误: %+v", opts)
+	}
+	waitJob(t, srv, response.Job.ID)
+	if opts := fake.LastOptions(); len(opts.Hotwords) != 2 || opts.MaxActivePaths != 8 {
+		t.Errorf("热词没有传给转录引擎: %+v", opts.DecodingOptions)
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// ProtocolVersion 实时转录 WebSocket 协议版本
+//
+// 会话流程：
+//  1. 客户端连接 /ws/realtime 后发送 start，服务端回复 ready
+//  2. 客户端持续发送音频，服务端返回 partial 和 final；
+//     音频可以是二进制帧（推荐，直接携带音频数据），也可以是 base64 编码的 audio 消息
+//  3. 客户端可以随时发送 flush，立即结束当前句子并收到它的 final
+//  4. 客户端发送 stop，服务端返回剩余的 final 后发送 closed 并关闭连接
+//
+// 控制消息和事件都是 JSON 文本帧。任何错误都以 error 事件返回；无法继续的错误（例如协议版本不支持）之后会发送 closed。
+const ProtocolVersion = 1
+
+// 客户端消息类型
+const (
+	MessageStart = "start"
+	MessageAudio = "audio"
+	MessageFlush = "flush"
+	MessageStop  = "stop"
+)
+
+// 服务端事件类型
+const (
+	EventReady   = "ready"
+	EventPartial = "partial"
+	EventFinal   = "final"
+	EventError   = "error"
+	EventClosed  = "closed"
+)
+
+// error 事件的错误码
+const (
+	ErrorInvalidMessage     = "invalid_message"
+	ErrorUnsupportedVersion = "unsupported_version"
+	ErrorUnsupportedFormat  = "unsupported_format"
+	ErrorNotStarted         = "not_started"
+	ErrorAlreadyStarted     = "already_started"
+	ErrorInvalidAudio       = "invalid_audio"
+	// ErrorTimestampsUnavailable 请求了时间戳，但模型不提供 token 的时间戳，发送后关闭会话
+	ErrorTimestampsUnavailable = "timestamps_unavailable"
+	ErrorInternal              = "internal"
+)
+
+// ClientMessage 客户端发送的控制消息，Type 决定其余字段的含义
+type ClientMessage struct {
+	Type string `json:"type"`
+	// Version start 消息声明的协议版本，不填按 1 处理
+	Version int `json:"version,omitempty"`
+	// Config start 消息携带的音频配置
+	Config *StreamConfig `json:"config,omitempty"`
+	// Audio audio 消息携带的音频数据，JSON 中为 base64 编码
+	Audio []byte `json:"audio,omitempty"`
+}
+
+// StreamConfig 实时转录的音频配置
+type StreamConfig struct {
+	// SampleRate 音频采样率，不填时使用模型采样率
+	SampleRate int `json:"sample_rate,omitempty"`
+	// Format 音频格式，默认 pcm（16 位小端单声道），f32 为 32 位小端浮点单声道；
+	// 也可以是 wav 等容器格式，此时每个音频帧都必须是完整的文件
+	Format string `json:"format,omitempty"`
+	// Language 识别语言，流式模型不区分语言时只在 ready 中回显
+	Language string         `json:"language,omitempty"`
+	Options  *StreamOptions `json:"options,omitempty"`
+}
+
+// StreamOptions 实时转录选项
+type StreamOptions struct {
+	// PartialResults 是否发送 partial 事件，默认发送
+	PartialResults *bool `json:"partial_results,omitempty"`
+	// Timestamps 是否在结果中返回词和 token 的时间戳，模型不提供时间戳时返回 timestamps_unavailable 错误
+	Timestamps bool `json:"timestamps,omitempty"`
+	// Hotwords 本次会话额外的热词，与服务端的热词文件合并，需要 transducer 模型
+	Hotwords []string `json:"hotwords,omitempty"`
+	// HotwordsScore 热词的加分，不填时使用配置的默认值
+	HotwordsScore float32 `json:"hotwords_score,omitempty"`
+	// DecodingMethod 解码方法，MaxActivePaths 为 modified_beam_search 保留的路径数，不填时使用配置的值
+	DecodingMethod string `json:"decoding_method,omitempty"`
+	MaxActivePaths int    `json:"max_active_paths,omitempty"`
+	// EnableEndpoint 是否按静音自动结束句子，关闭后句子只在 flush 时结束
+	EnableEndpoint *bool `json:"enable_endpoint,omitempty"`
+	// 端点检测规则（秒），不填时使用配置的值
+	Rule1MinTrailingSilence float32 `json:"rule1_min_trailing_silence,omitempty"`
+	Rule2MinTrailingSilence float32 `json:"rule2_min_trailing_silence,omitempty"`
+	Rule3MinUtteranceLength float32 `json:"rule3_min_utterance_length,omitempty"`
+}
+
+// ReadyEvent 服务端接受 start 后发送
+type ReadyEvent struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	// SampleRate 客户端音频的采样率
+	SampleRate int `json:"sample_rate"`
+	// ModelSampleRate 模型采样率，音频会在服务端重采样到该采样率
+	ModelSampleRate int    `json:"model_sample_rate"`
+	Format          string `json:"format"`
+	Language        string `json:"language,omitempty"`
+}
+
+// ResultEvent 识别结果，Type 为 partial 或 final
+// 同一句子的 partial 和 final 使用相同的 utterance，客户端收到 final 后即可提交该句
+type ResultEvent struct {
+	Type      string `json:"type"`
+	Utterance int    `json:"utterance"`
+	Text      string `json:"text"`
+	// Start 和 End 句子在会话音频中的起止时间（秒）
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Confidence 句子中各 token 概率的几何平均，只有 transducer 模型提供
+	Confidence float64 `json:"confidence,omitempty"`
+	// Words 和 Tokens 只在 start 请求时间戳时返回，时间相对于会话音频的开始
+	Words  []transcribe.Word  `json:"words,omitempty"`
+	Tokens []transcribe.Token `json:"tokens,omitempty"`
+}
+
+// ErrorEvent 错误事件
+type ErrorEvent struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ClosedEvent 服务端关闭会话前发送的最后一个事件
+type ClosedEvent struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// protocolError 可以直接转换为 error 事件的错误
+type protocolError struct {
+	code    string
+	message string
+	// fatal 为 true 时发送错误后关闭会话
+	fatal bool
+}
+
+func (e *protocolError) Error() string {
+	return e.message
+}
+
+func newProtocolError(code, format string, args ...interface{}) *protocolError {
+	return &protocolError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// parseClientMessage 解析并校验客户端消息
+func parseClientMessage(data []byte) (*ClientMessage, error) {
+	var msg ClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, newProtocolError(ErrorInvalidMessage, "无效的消息格式: %v", err)
+	}
+
+	switch msg.Type {
+	case MessageStart:
+		if msg.Version != 0 && msg.Version != ProtocolVersion {
+			err := newProtocolError(ErrorUnsupportedVersion, "不支持的协议版本 %d，当前版本为 %d", msg.Version, ProtocolVersion)
+			err.fatal = true
+			return nil, err
+		}
+		if msg.Config == nil {
+			msg.Config = &StreamConfig{}
+		}
+		if msg.Config.Format == "" {
+			msg.Config.Format = "pcm"
+		}
+		if err := validateStreamConfig(msg.Config); err != nil {
+			return nil, err
+		}
+	case MessageAudio, MessageFlush, MessageStop:
+	case "":
+		return nil, newProtocolError(ErrorInvalidMessage, "消息缺少 type 字段")
+	default:
+		return nil, newProtocolError(ErrorInvalidMessage, "未知的消息类型: %s", msg.Type)
+	}
+
+	return &msg, nil
+}
+
+// validateStreamConfig 校验音频配置并规范化格式名称
+func validateStreamConfig(config *StreamConfig) error {
+	if config.SampleRate < 0 {
+		return newProtocolError(ErrorInvalidMessage, "无效的采样率: %d", config.SampleRate)
+	}
+	config.Format = transcribe.NormalizeFormat(config.Format)
+	if !transcribe.HasDecoder(config.Format) {
+		return newProtocolError(ErrorUnsupportedFormat, "不支持的音频格式: %s", config.Format)
+	}
+	if opts, ok := config.decodingOptions(); ok {
+		if err := opts.Validate(); err != nil {
+			return newProtocolError(ErrorInvalidMessage, "%v", err)
+		}
+	}
+	return nil
+}
+
+// decodeStreamChunk 按音频配置解码客户端发送的一段音频
+func decodeStreamChunk(config *StreamConfig, data []byte) (*transcribe.Audio, error) {
+	// 原始采样不能跨帧拆分，否则之后的采样都会错位
+	if size := transcribe.SampleSize(config.Format); size > 0 && len(data)%size != 0 {
+		return nil, newProtocolError(ErrorInvalidAudio, "%s 音频长度 %d 不是 %d 字节的整数倍", config.Format, len(data), size)
+	}
+
+	audio, err := transcribe.DecodeAudio(data, config.Format, config.SampleRate)
+	if err != nil {
+		return nil, newProtocolError(ErrorInvalidAudio, "处理音频数据失败: %v", err)
+	}
+	return audio, nil
+}
+
+// decodingOptions 返回会话的解码选项，ok 为 false 表示使用默认选项
+func (c *StreamConfig) decodingOptions() (opts transcribe.DecodingOptions, ok bool) {
+	if c.Options == nil {
+		return opts, false
+	}
+	o := c.Options
+	opts = transcribe.DecodingOptions{
+		Hotwords:       o.Hotwords,
+		HotwordsScore:  o.HotwordsScore,
+		DecodingMethod: o.DecodingMethod,
+		MaxActivePaths: o.MaxActivePaths,
+		EnableEndpoint: o.EnableEndpoint,
+		Endpoint: transcribe.EndpointRules{
+			Rule1MinTrailingSilence: o.Rule1MinTrailingSilence,
+			Rule2MinTrailingSilence: o.Rule2MinTrailingSilence,
+			Rule3MinUtteranceLength: o.Rule3MinUtteranceLength,
+		},
+	}
+	return opts, !opts.IsZero()
+}
+
+// setDecoding 把解码选项写入会话选项
+func (o *StreamOptions) setDecoding(opts transcribe.DecodingOptions) {
+	o.Hotwords = opts.Hotwords
+	o.HotwordsScore = opts.HotwordsScore
+	o.DecodingMethod = opts.DecodingMethod
+	o.MaxActivePaths = opts.MaxActivePaths
+	o.EnableEndpoint = opts.EnableEndpoint
+	o.Rule1MinTrailingSilence = opts.Endpoint.Rule1MinTrailingSilence
+	o.Rule2MinTrailingSilence = opts.Endpoint.Rule2MinTrailingSilence
+	o.Rule3MinUtteranceLength = opts.Endpoint.Rule3MinUtteranceLength
+}
+
+// partialResults 是否发送 partial 事件，默认发送
+func (c *StreamConfig) partialResults() bool {
+	return c.Options == nil || c.Options.PartialResults == nil || *c.Options.PartialResults
+}
+
+// timestamps 是否在结果中返回时间戳
+func (c *StreamConfig) timestamps() bool {
+	return c.Options != nil && c.Options.Timestamps
+}
+
+// newResultEvent 把流式识别结果转换为 partial 或 final 事件，timestamps 为 true 时带上词和 token
+func newResultEvent(result transcribe.StreamResult, timestamps bool) ResultEvent {
+	event := ResultEvent{
+		Type:       EventPartial,
+		Utterance:  result.Utterance,
+		Text:       result.Text,
+		Start:      result.Start,
+		End:        result.End,
+		Confidence: result.Confidence,
+	}
+	if result.Final {
+		event.Type = EventFinal
+	}
+	if timestamps {
+		event.Words = result.Words
+		event.Tokens = result.Tokens
+	}
+	return event
+}
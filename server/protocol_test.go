@@ -0,0 +1,317 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+func TestParseClientMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		code  string
+		fatal bool
+	}{
+		{"start 默认配置", `{"type":"start"}`, "", false},
+		{"start 指定配置", `{"type":"start","version":1,"config":{"sample_rate":8000,"format":"PCM"}}`, "", false},
+		{"audio", `{"type":"audio","audio":"AAA="}`, "", false},
+		{"无效 JSON", `{"type":`, ErrorInvalidMessage, false},
+		{"缺少 type", `{}`, ErrorInvalidMessage, false},
+		{"未知类型", `{"type":"pause"}`, ErrorInvalidMessage, false},
+		{"不支持的版本", `{"type":"start","version":2}`, ErrorUnsupportedVersion, true},
+		{"不支持的格式", `{"type":"start","config":{"format":"aac"}}`, ErrorUnsupportedFormat, false},
+		{"无效的采样率", `{"type":"start","config":{"sample_rate":-1}}`, ErrorInvalidMessage, false},
+		{"无效的解码方法", `{"type":"start","config":{"options":{"decoding_method":"beam"}}}`, ErrorInvalidMessage, false},
+		{"端点规则超出范围", `{"type":"start","config":{"options":{"rule2_min_trailing_silence":100}}}`, ErrorInvalidMessage, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseClientMessage([]byte(tt.data))
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("解析失败: %v", err)
+				}
+				if msg.Type == MessageStart && msg.Config.Format != "pcm" {
+					t.Errorf("期望格式 pcm，得到 %s", msg.Config.Format)
+				}
+				return
+			}
+
+			perr, ok := err.(*protocolError)
+			if !ok {
+				t.Fatalf("期望协议错误 %s，得到 %v", tt.code, err)
+			}
+			if perr.code != tt.code || perr.fatal != tt.fatal {
+				t.Errorf("期望错误码 %s（fatal=%v），得到 %s（fatal=%v）", tt.code, tt.fatal, perr.code, perr.fatal)
+			}
+		})
+	}
+}
+
+// realtimeTestClient 启动测试服务器并连接 /ws/realtime
+func realtimeTestClient(t *testing.T, transcriber transcribe.Transcriber) *websocket.Conn {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	srv := NewServer(transcriber)
+	server := httptest.NewServer(srv.router)
+	t.Cleanup(server.Close)
+	t.Cleanup(srv.Close)
+
+	return dialRealtime(t, server.URL+"/ws/realtime")
+}
+
+func dialRealtime(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatalf("连接 WebSocket 失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readEvent 读取一个服务端事件
+func readEvent(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event map[string]interface{}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("读取事件失败: %v", err)
+	}
+	return event
+}
+
+// pcm16 生成值全为 v 的 16 位 PCM 数据
+func pcm16(samples int, v int16) []byte {
+	data := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(v))
+	}
+	return data
+}
+
+func sendMessage(t *testing.T, conn *websocket.Conn, msg ClientMessage) {
+	t.Helper()
+
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+}
+
+func TestRealtimeProtocol(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := realtimeTestClient(t, fake)
+
+	// start 之前发送音频
+	sendMessage(t, conn, ClientMessage{Type: MessageAudio, Audio: pcm16(160, 1000)})
+	if event := readEvent(t, conn); event["type"] != EventError || event["code"] != ErrorNotStarted {
+		t.Fatalf("期望 not_started 错误，得到 %v", event)
+	}
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Version: ProtocolVersion, Config: &StreamConfig{SampleRate: 8000}})
+	event := readEvent(t, conn)
+	if event["type"] != EventReady || event["sample_rate"] != float64(8000) || event["model_sample_rate"] != float64(16000) {
+		t.Fatalf("期望 ready 事件，得到 %v", event)
+	}
+
+	// 语音产生部分结果，随后的静音触发端点
+	sendMessage(t, conn, ClientMessage{Type: MessageAudio, Audio: pcm16(800, 1000)})
+	if event := readEvent(t, conn); event["type"] != EventPartial || event["text"] != "你好" || event["utterance"] != float64(0) {
+		t.Fatalf("期望 partial 事件，得到 %v", event)
+	}
+	sendMessage(t, conn, ClientMessage{Type: MessageAudio, Audio: pcm16(800, 0)})
+	// 8kHz 的 800 个采样各 0.1 秒，final 包含触发端点的静音，重采样会保留少量尾部采样
+	event = readEvent(t, conn)
+	if event["type"] != EventFinal || event["utterance"] != float64(0) || event["start"] != float64(0) {
+		t.Fatalf("期望 final 事件，得到 %v", event)
+	}
+	if end := event["end"].(float64); end < 0.19 || end > 0.2 {
+		t.Errorf("期望句子在 0.2 秒左右结束，得到 %v", end)
+	}
+
+	// flush 立即结束第二句
+	sendMessage(t, conn, ClientMessage{Type: MessageAudio, Audio: pcm16(800, 1000)})
+	readEvent(t, conn)
+	sendMessage(t, conn, ClientMessage{Type: MessageFlush})
+	if event := readEvent(t, conn); event["type"] != EventFinal || event["utterance"] != float64(1) {
+		t.Fatalf("期望第二句的 final 事件，得到 %v", event)
+	}
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStop})
+	if event := readEvent(t, conn); event["type"] != EventClosed || event["reason"] != "stop" {
+		t.Fatalf("期望 closed 事件，得到 %v", event)
+	}
+
+	sessions := fake.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("期望 1 个会话，实际 %d 个", len(sessions))
+	}
+	// 8kHz 音频重采样到 16kHz
+	if n := len(sessions[0].Samples()); n < 4500 || n > 4900 {
+		t.Errorf("期望约 4800 个采样，实际 %d 个", n)
+	}
+}
+
+func TestRealtimeProtocolErrors(t *testing.T) {
+	conn := realtimeTestClient(t, transcribe.NewFakeTranscriber("你好"))
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Config: &StreamConfig{Options: &StreamOptions{PartialResults: new(bool)}}})
+	if event := readEvent(t, conn); event["type"] != EventReady || event["format"] != "pcm" {
+		t.Fatalf("期望 ready 事件，得到 %v", event)
+	}
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart})
+	if event := readEvent(t, conn); event["code"] != ErrorAlreadyStarted {
+		t.Fatalf("期望 already_started 错误，得到 %v", event)
+	}
+
+	// 关闭部分结果后只收到 final
+	sendMessage(t, conn, ClientMessage{Type: MessageAudio, Audio: pcm16(800, 1000)})
+	sendMessage(t, conn, ClientMessage{Type: MessageFlush})
+	if event := readEvent(t, conn); event["type"] != EventFinal {
+		t.Fatalf("关闭部分结果后期望 final 事件，得到 %v", event)
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"start","version":9}`)); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	if event := readEvent(t, conn); event["code"] != ErrorUnsupportedVersion {
+		t.Fatalf("期望 unsupported_version 错误，得到 %v", event)
+	}
+	if event := readEvent(t, conn); event["type"] != EventClosed {
+		t.Fatalf("期望 closed 事件，得到 %v", event)
+	}
+
+	// 服务端随后关闭连接
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("期望连接已关闭")
+	}
+}
+
+func TestRealtimeBinaryFrames(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := realtimeTestClient(t, fake)
+
+	// start 之前的二进制帧
+	if err := conn.WriteMessage(websocket.BinaryMessage, pcm16(160, 1000)); err != nil {
+		t.Fatalf("发送音频帧失败: %v", err)
+	}
+	if event := readEvent(t, conn); event["code"] != ErrorNotStarted {
+		t.Fatalf("期望 not_started 错误，得到 %v", event)
+	}
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Config: &StreamConfig{Format: "f32le"}})
+	if event := readEvent(t, conn); event["type"] != EventReady || event["format"] != "f32" {
+		t.Fatalf("期望 f32 格式的 ready 事件，得到 %v", event)
+	}
+
+	samples := make([]byte, 1600*4)
+	for i := 0; i < 1600; i++ {
+		binary.LittleEndian.PutUint32(samples[i*4:], math.Float32bits(0.25))
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, samples); err != nil {
+		t.Fatalf("发送音频帧失败: %v", err)
+	}
+	if event := readEvent(t, conn); event["type"] != EventPartial {
+		t.Fatalf("期望 partial 事件，得到 %v", event)
+	}
+
+	// 长度不是 4 的整数倍
+	if err := conn.WriteMessage(websocket.BinaryMessage, samples[:7]); err != nil {
+		t.Fatalf("发送音频帧失败: %v", err)
+	}
+	if event := readEvent(t, conn); event["code"] != ErrorInvalidAudio {
+		t.Fatalf("期望 invalid_audio 错误，得到 %v", event)
+	}
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStop})
+	if event := readEvent(t, conn); event["type"] != EventFinal {
+		t.Fatalf("期望 final 事件，得到 %v", event)
+	}
+
+	got := fake.Sessions()[0].Samples()
+	if len(got) != 1600 || got[0] != 0.25 {
+		t.Errorf("期望 1600 个值为 0.25 的采样，实际 %d 个", len(got))
+	}
+}
+
+func TestClientMessageJSON(t *testing.T) {
+	data, _ := json.Marshal(ClientMessage{Type: MessageAudio, Audio: []byte{1, 2, 3}})
+	if string(data) != `{"type":"audio","audio":"AQID"}` {
+		t.Errorf("audio 消息应该使用 base64 编码，得到 %s", data)
+	}
+}
+
+func TestRealtimeHotwords(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := realtimeTestClient(t, fake)
+
+	// 无效的热词返回错误，会话保持可用
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Config: &StreamConfig{Options: &StreamOptions{HotwordsScore: -1}}})
+	if event := readEvent(t, conn); event["code"] != ErrorInvalidMessage {
+		t.Fatalf("期望 invalid_message 错误，得到 %v", event)
+	}
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Config: &StreamConfig{Options: &StreamOptions{Hotwords: []string{"语音识别"}}}})
+	if event := readEvent(t, conn); event["type"] != EventReady {
+		t.Fatalf("期望 ready 事件，得到 %v", event)
+	}
+	sendMessage(t, conn, ClientMessage{Type: MessageAudio, Audio: pcm16(800, 1000)})
+	if event := readEvent(t, conn); event["type"] != EventPartial {
+		t.Fatalf("期望 partial 事件，得到 %v", event)
+	}
+
+	// 连接时创建的会话被替换
+	sessions := fake.Sessions()
+	if len(sessions) != 2 || !sessions[0].Closed() || sessions[1].Closed() {
+		t.Fatalf("期望替换为新的会话，得到 %d 个会话", len(sessions))
+	}
+	if opts := sessions[1].Options(); len(opts.Hotwords) != 1 || opts.Hotwords[0] != "语音识别" {
+		t.Errorf("新会话的热词错误: %+v", opts)
+	}
+	if len(sessions[1].Samples()) == 0 {
+		t.Error("音频应该送入新的会话")
+	}
+}
+
+func TestRealtimeDecodingOptions(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := realtimeTestClient(t, fake)
+
+	enable := false
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Config: &StreamConfig{Options: &StreamOptions{
+		DecodingMethod:          transcribe.DecodingModifiedBeamSearch,
+		MaxActivePaths:          8,
+		EnableEndpoint:          &enable,
+		Rule2MinTrailingSilence: 0.5,
+	}}})
+	if event := readEvent(t, conn); event["type"] != EventReady {
+		t.Fatalf("期望 ready 事件，得到 %v", event)
+	}
+
+	sessions := fake.Sessions()
+	if len(sessions) != 2 || !sessions[0].Closed() {
+		t.Fatalf("期望替换为新的会话，得到 %d 个会话", len(sessions))
+	}
+	opts := sessions[1].Options()
+	if opts.DecodingMethod != transcribe.DecodingModifiedBeamSearch || opts.MaxActivePaths != 8 {
+		t.Errorf("新会话的解码参数错误: %+v", opts)
+	}
+	if opts.EnableEndpoint == nil || *opts.EnableEndpoint || opts.Endpoint.Rule2MinTrailingSilence != 0.5 {
+		t.Errorf("新会话的端点检测选项错误: %+v", opts)
+	}
+}
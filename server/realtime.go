@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/layzdonw/transerver/transcribe"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// writeWait 单次写入的超时时间
+	writeWait = 10 * time.Second
+	// pongWait 等待客户端消息或 pong 的最长时间，超时后认为连接已断开
+	pongWait = 60 * time.Second
+	// pingPeriod 发送 ping 的间隔，必须小于 pongWait
+	pingPeriod = pongWait * 9 / 10
+	// sendQueueSize 等待写入连接的事件数量，队列满时读取端等待，不再接收新的音频
+	sendQueueSize = 64
+)
+
+// RealtimeSession 一个实时转录 WebSocket 连接
+// 连接只有一个读取 goroutine（调用 run 的 goroutine）和一个写入 goroutine：
+// 读取 goroutine 处理客户端消息并把事件放入 outgoing，写入 goroutine 负责所有写操作和 ping
+type RealtimeSession struct {
+	conn *websocket.Conn
+	// driver 驱动识别会话并把结果转换为事件
+	driver streamDriver
+	// newSession 创建使用指定解码选项的会话，start 设置了解码选项时替换连接时创建的会话；
+	// 为空时不支持按会话设置解码选项
+	newSession func(transcribe.DecodingOptions) (transcribe.Session, error)
+	// sampleRate 模型采样率
+	sampleRate int
+	logger     *logrus.Logger
+
+	pingPeriod time.Duration
+	pongWait   time.Duration
+
+	// outgoing 等待写入的事件，只由读取 goroutine 发送和关闭
+	outgoing chan interface{}
+
+	// 以下字段只在读取 goroutine 中访问
+	// started 是否已经收到 start 消息
+	started bool
+	config  StreamConfig
+}
+
+func newRealtimeSession(conn *websocket.Conn, session transcribe.Session, sampleRate int, logger *logrus.Logger) *RealtimeSession {
+	return &RealtimeSession{
+		conn:       conn,
+		driver:     streamDriver{session: session, partials: true},
+		sampleRate: sampleRate,
+		logger:     logger,
+		pingPeriod: pingPeriod,
+		pongWait:   pongWait,
+		outgoing:   make(chan interface{}, sendQueueSize),
+	}
+}
+
+// run 读取并处理客户端消息，直到会话结束、连接断开或 ctx 取消，返回前关闭连接和识别会话
+func (rs *RealtimeSession) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rs.driver.emit = func(event ResultEvent) error {
+		rs.send(ctx, event)
+		return nil
+	}
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		rs.writeLoop(ctx, cancel)
+	}()
+
+	rs.readLoop(ctx)
+
+	// 写入 goroutine 发送完剩余的事件后返回
+	close(rs.outgoing)
+	<-written
+
+	rs.conn.Close()
+	rs.driver.session.Close()
+	rs.logger.Info("实时转录会话已清理")
+}
+
+func (rs *RealtimeSession) readLoop(ctx context.Context) {
+	// 收到任何消息或 pong 都说明连接仍然有效
+	rs.conn.SetReadDeadline(time.Now().Add(rs.pongWait))
+	rs.conn.SetPongHandler(func(string) error {
+		return rs.conn.SetReadDeadline(time.Now().Add(rs.pongWait))
+	})
+
+	for {
+		messageType, message, err := rs.conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil && websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				rs.logger.Errorf("读取 WebSocket 消息失败: %v", err)
+			}
+			return
+		}
+		rs.conn.SetReadDeadline(time.Now().Add(rs.pongWait))
+
+		// 文本帧是控制消息，二进制帧直接是音频数据
+		var stop bool
+		if messageType == websocket.BinaryMessage {
+			stop = rs.handleAudioFrame(ctx, message)
+		} else {
+			stop = rs.handleMessage(ctx, message)
+		}
+		if stop {
+			return
+		}
+	}
+}
+
+// writeLoop 写入 outgoing 中的事件并定时发送 ping
+// outgoing 关闭后发送关闭帧；ctx 取消时发送 closed 事件并关闭连接，使读取 goroutine 退出
+func (rs *RealtimeSession) writeLoop(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(rs.pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-rs.outgoing:
+			if !ok {
+				rs.writeClose()
+				return
+			}
+			rs.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := rs.conn.WriteJSON(event); err != nil {
+				rs.logger.Errorf("发送 WebSocket 消息失败: %v", err)
+				cancel()
+				rs.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := rs.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				rs.logger.Errorf("发送 ping 失败: %v", err)
+				cancel()
+				rs.conn.Close()
+				return
+			}
+		case <-ctx.Done():
+			rs.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			rs.conn.WriteJSON(ClosedEvent{Type: EventClosed, Reason: "shutdown"})
+			rs.writeClose()
+			rs.conn.Close()
+			return
+		}
+	}
+}
+
+func (rs *RealtimeSession) writeClose() {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	rs.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+}
+
+// handleMessage 处理一条客户端消息，返回 true 表示会话结束
+func (rs *RealtimeSession) handleMessage(ctx context.Context, data []byte) bool {
+	msg, err := parseClientMessage(data)
+	if err != nil {
+		return rs.sendError(ctx, err)
+	}
+
+	if msg.Type != MessageStart && !rs.started {
+		return rs.sendError(ctx, newProtocolError(ErrorNotStarted, "请先发送 start 消息"))
+	}
+
+	switch msg.Type {
+	case MessageStart:
+		if rs.started {
+			return rs.sendError(ctx, newProtocolError(ErrorAlreadyStarted, "会话已经开始"))
+		}
+		if err := rs.start(ctx, msg.Config); err != nil {
+			return rs.sendError(ctx, err)
+		}
+	case MessageAudio:
+		if err := rs.driver.acceptChunk(&rs.config, msg.Audio); err != nil {
+			return rs.sendError(ctx, err)
+		}
+	case MessageFlush:
+		if err := rs.driver.flush(); err != nil {
+			return rs.sendError(ctx, err)
+		}
+	case MessageStop:
+		rs.driver.flush()
+		rs.send(ctx, ClosedEvent{Type: EventClosed, Reason: "stop"})
+		return true
+	}
+	return false
+}
+
+// handleAudioFrame 处理一个二进制音频帧，内容和 audio 消息解码 base64 后相同
+func (rs *RealtimeSession) handleAudioFrame(ctx context.Context, data []byte) bool {
+	if !rs.started {
+		return rs.sendError(ctx, newProtocolError(ErrorNotStarted, "请先发送 start 消息"))
+	}
+	if err := rs.driver.acceptChunk(&rs.config, data); err != nil {
+		return rs.sendError(ctx, err)
+	}
+	return false
+}
+
+// start 保存音频配置并回复 ready
+func (rs *RealtimeSession) start(ctx context.Context, config *StreamConfig) error {
+	if opts, ok := config.decodingOptions(); ok {
+		if err := rs.reopen(opts); err != nil {
+			return err
+		}
+	}
+
+	rs.started = true
+	rs.config = *config
+	if rs.config.SampleRate == 0 {
+		rs.config.SampleRate = rs.sampleRate
+	}
+	rs.driver.partials = config.partialResults()
+	rs.driver.timestamps = config.timestamps()
+
+	rs.send(ctx, ReadyEvent{
+		Type:            EventReady,
+		Version:         ProtocolVersion,
+		SampleRate:      rs.config.SampleRate,
+		ModelSampleRate: rs.sampleRate,
+		Format:          rs.config.Format,
+		Language:        rs.config.Language,
+	})
+	return nil
+}
+
+// reopen 换成使用指定解码选项的会话
+// 先关闭连接时创建的会话归还名额；新会话创建失败时换回默认会话，客户端可以修改选项后重新发送 start
+func (rs *RealtimeSession) reopen(opts transcribe.DecodingOptions) error {
+	if rs.newSession == nil {
+		return newProtocolError(ErrorInvalidMessage, "不支持按会话设置解码选项")
+	}
+
+	rs.driver.session.Close()
+	session, err := rs.newSession(opts)
+	if err == nil {
+		rs.driver.session = session
+		return nil
+	}
+	code := ErrorInternal
+	if errors.Is(err, transcribe.ErrInvalidOptions) {
+		code = ErrorInvalidMessage
+	}
+	perr := newProtocolError(code, "无法创建识别会话: %v", err)
+
+	session, rerr := rs.newSession(transcribe.DecodingOptions{})
+	if rerr != nil {
+		perr.fatal = true
+		return perr
+	}
+	rs.driver.session = session
+	return perr
+}
+
+// sendError 发送 error 事件，返回 true 表示错误无法恢复，会话需要结束
+func (rs *RealtimeSession) sendError(ctx context.Context, err error) bool {
+	perr, ok := err.(*protocolError)
+	if !ok {
+		perr = newProtocolError(ErrorInternal, "%v", err)
+	}
+
+	rs.send(ctx, ErrorEvent{
+		Type:    EventError,
+		Code:    perr.code,
+		Message: perr.message,
+	})
+	if perr.fatal {
+		rs.send(ctx, ClosedEvent{Type: EventClosed, Reason: perr.code})
+	}
+	return perr.fatal
+}
+
+// send 把事件放入发送队列，队列满时等待写入 goroutine，会话结束后丢弃
+func (rs *RealtimeSession) send(ctx context.Context, event interface{}) {
+	select {
+	case rs.outgoing <- event:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/layzdonw/transerver/transcribe"
+	"github.com/sirupsen/logrus"
+)
+
+// keepaliveTestClient 连接一个使用较短 ping 间隔的会话
+func keepaliveTestClient(t *testing.T, fake *transcribe.FakeTranscriber, ping, pong time.Duration) *websocket.Conn {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := fake.NewSession(transcribe.DecodingOptions{})
+		if err != nil {
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			session.Close()
+			return
+		}
+		rs := newRealtimeSession(conn, session, fake.GetSampleRate(), logrus.New())
+		rs.pingPeriod = ping
+		rs.pongWait = pong
+		rs.run(context.Background())
+	}))
+	t.Cleanup(server.Close)
+
+	return dialRealtime(t, server.URL)
+}
+
+// waitSessionClosed 等待服务端关闭识别会话
+func waitSessionClosed(t *testing.T, fake *transcribe.FakeTranscriber) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if sessions := fake.Sessions(); len(sessions) == 1 && sessions[0].Closed() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("识别会话没有被关闭")
+}
+
+func TestRealtimeNoLostMessages(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := realtimeTestClient(t, fake)
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart, Config: &StreamConfig{Options: &StreamOptions{PartialResults: new(bool)}}})
+	if event := readEvent(t, conn); event["type"] != EventReady {
+		t.Fatalf("期望 ready 事件，得到 %v", event)
+	}
+
+	// 所有音频帧都必须被同一个读取者处理，不能被丢弃
+	const frames = 100
+	for i := 0; i < frames; i++ {
+		if err := conn.WriteMessage(websocket.BinaryMessage, pcm16(160, 1000)); err != nil {
+			t.Fatalf("发送音频帧失败: %v", err)
+		}
+	}
+	sendMessage(t, conn, ClientMessage{Type: MessageStop})
+
+	if event := readEvent(t, conn); event["type"] != EventFinal {
+		t.Fatalf("期望 final 事件，得到 %v", event)
+	}
+	if event := readEvent(t, conn); event["type"] != EventClosed {
+		t.Fatalf("期望 closed 事件，得到 %v", event)
+	}
+	waitSessionClosed(t, fake)
+
+	if n := len(fake.Sessions()[0].Samples()); n != frames*160 {
+		t.Errorf("期望收到 %d 个采样，实际 %d 个", frames*160, n)
+	}
+}
+
+func TestRealtimeConcurrentSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := transcribe.NewFakeTranscriber("你好")
+	srv := NewServer(fake)
+	server := httptest.NewServer(srv.router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/realtime"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			if err != nil {
+				t.Errorf("连接 WebSocket 失败: %v", err)
+				return
+			}
+			defer conn.Close()
+
+			conn.WriteJSON(ClientMessage{Type: MessageStart})
+			for j := 0; j < 10; j++ {
+				conn.WriteMessage(websocket.BinaryMessage, pcm16(160, 1000))
+			}
+			conn.WriteJSON(ClientMessage{Type: MessageStop})
+
+			// 读到 closed 为止，中间的事件顺序不重要
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			for {
+				var event map[string]interface{}
+				if err := conn.ReadJSON(&event); err != nil {
+					t.Errorf("没有收到 closed 事件: %v", err)
+					return
+				}
+				if event["type"] == EventClosed {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, session := range fake.Sessions() {
+		if n := len(session.Samples()); n != 1600 {
+			t.Errorf("期望每个会话收到 1600 个采样，实际 %d 个", n)
+		}
+	}
+}
+
+func TestRealtimeKeepalive(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := keepaliveTestClient(t, fake, 20*time.Millisecond, time.Second)
+
+	var pings atomic.Int32
+	conn.SetPingHandler(func(data string) error {
+		pings.Add(1)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart})
+	readEvent(t, conn)
+
+	// 读取期间处理 ping，没有其他事件时读取超时
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("期望读取超时")
+	}
+	if pings.Load() == 0 {
+		t.Error("期望收到服务端的 ping")
+	}
+	if fake.Sessions()[0].Closed() {
+		t.Error("回复 pong 时会话不应该被关闭")
+	}
+}
+
+func TestRealtimePongTimeout(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	conn := keepaliveTestClient(t, fake, 20*time.Millisecond, 100*time.Millisecond)
+
+	// 不回复 pong
+	conn.SetPingHandler(func(string) error { return nil })
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	waitSessionClosed(t, fake)
+}
+
+func TestRealtimeServerClose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := transcribe.NewFakeTranscriber("你好")
+	srv := NewServer(fake)
+	server := httptest.NewServer(srv.router)
+	defer server.Close()
+	conn := dialRealtime(t, server.URL+"/ws/realtime")
+
+	sendMessage(t, conn, ClientMessage{Type: MessageStart})
+	readEvent(t, conn)
+
+	srv.Close()
+	if event := readEvent(t, conn); event["type"] != EventClosed || event["reason"] != "shutdown" {
+		t.Fatalf("期望 shutdown 的 closed 事件，得到 %v", event)
+	}
+	waitSessionClosed(t, fake)
+}
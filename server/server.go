@@ -1,32 +1,68 @@
 package server
 
 import (
-	"bytes"
-	"encoding/json"
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 	"github.com/layzdonw/transerver/config"
+	"github.com/layzdonw/transerver/jobs"
 	"github.com/layzdonw/transerver/transcribe"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
 type Server struct {
-	transcriber *transcribe.SherpaTranscriber
+	transcriber transcribe.Transcriber
 	router      *gin.Engine
 	upgrader    websocket.Upgrader
 	logger      *logrus.Logger
+	// ctx 在 Close 时取消，结束所有实时转录会话
+	ctx    context.Context
+	cancel context.CancelFunc
+	// jobs 异步任务管理器，为 nil 时任务接口返回 404
+	jobs *jobs.Manager
+	// maxUploadSize 上传请求体的大小上限（字节），为 0 时不限制
+	maxUploadSize int64
+	// http 和 grpc 是 Start 和 StartGRPC 启动的服务器，Shutdown 和 Close 时停止
+	mu   sync.Mutex
+	http *http.Server
+	grpc *grpc.Server
 }
 
 type TranscribeRequest struct {
 	AudioData []byte `json:"audio_data"`
 	Format    string `json:"format"`
+	// 输入音频的采样率，WAV 以文件头为准，PCM 不填时使用模型采样率
+	SampleRate int `json:"sample_rate,omitempty"`
+	// 识别模式：online 或 offline，不填时使用配置的默认模式
+	Mode string `json:"mode,omitempty"`
+	// 是否返回词和 token 的时间戳
+	Timestamps bool `json:"timestamps,omitempty"`
+	// 输出格式：json（默认）、text、srt、vtt、tsv
+	OutputFormat string `json:"output_format,omitempty"`
+	// 字幕切分限制，不填时使用默认值
+	MaxLineLength  int     `json:"max_line_length,omitempty"`
+	MaxLines       int     `json:"max_lines,omitempty"`
+	MaxCueDuration float64 `json:"max_cue_duration,omitempty"`
+	// 额外的热词和热词加分，需要 transducer 模型；表单和查询字符串中 hotwords 用逗号分隔
+	Hotwords      []string `json:"hotwords,omitempty"`
+	HotwordsScore float32  `json:"hotwords_score,omitempty"`
+	// 解码方法和 modified_beam_search 保留的路径数，不填时使用配置的值
+	DecodingMethod string `json:"decoding_method,omitempty"`
+	MaxActivePaths int    `json:"max_active_paths,omitempty"`
 }
 
 type TranscribeResponse struct {
@@ -35,18 +71,8 @@ type TranscribeResponse struct {
 	Error   string                          `json:"error,omitempty"`
 }
 
-// 实时转录会话
-type RealtimeSession struct {
-	conn       *websocket.Conn
-	recognizer *sherpa_onnx.OnlineRecognizer
-	stream     *sherpa_onnx.OnlineStream
-	sampleRate int
-	logger     *logrus.Logger
-	mu         sync.Mutex
-	isActive   bool
-}
-
-func NewServer(transcriber *transcribe.SherpaTranscriber) *Server {
+func NewServer(transcriber transcribe.Transcriber) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
 	server := &Server{
 		transcriber: transcriber,
 		router:      gin.Default(),
@@ -56,6 +82,8 @@ func NewServer(transcriber *transcribe.SherpaTranscriber) *Server {
 			},
 		},
 		logger: logrus.New(),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 
 	server.setupRoutes()
@@ -67,265 +95,501 @@ func (s *Server) setupRoutes() {
 	s.router.GET("/health", s.healthCheck)
 
 	// 转录端点
-	s.router.POST("/transcribe", s.transcribeHandler)
+	s.router.POST("/transcribe", s.limitUpload, s.transcribeHandler)
+
+	// 分块上传音频，以 Server-Sent Events 返回实时转录结果
+	s.router.POST("/transcribe/stream", s.streamTranscribeHandler)
 
 	// WebSocket 端点用于实时转录
 	s.router.GET("/ws/realtime", s.realtimeTranscribeHandler)
 
+	// 异步任务端点，用于长录音
+	s.router.POST("/jobs", s.limitUpload, s.createJobHandler)
+	s.router.GET("/jobs/:id", s.getJobHandler)
+	s.router.DELETE("/jobs/:id", s.deleteJobHandler)
+
 	// 静态文件服务（可选）
 	s.router.Static("/static", "./static")
 }
 
+// queueReporter 可以报告排队情况的转录引擎，例如 transcribe.Pool
+type queueReporter interface {
+	Stats() transcribe.PoolStats
+	RetryAfter() time.Duration
+}
+
 func (s *Server) healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"status":  "ok",
 		"message": "转录服务器运行正常",
-	})
+	}
+	if queue, ok := s.transcriber.(queueReporter); ok {
+		response["queue"] = queue.Stats()
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (s *Server) transcribeHandler(c *gin.Context) {
 	var req TranscribeRequest
+	// audio 上传的音频文件，JSON 请求时为 nil，使用 req.AudioData
+	var audio io.Reader
+	var upload *multipartUpload
 
 	// 处理 multipart/form-data
 	if c.ContentType() == "multipart/form-data" {
-		file, err := c.FormFile("audio")
+		var err error
+		upload, err = readMultipartUpload(c.Request)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, TranscribeResponse{
+			c.JSON(uploadErrorStatus(err), TranscribeResponse{
 				Success: false,
 				Error:   "无法获取音频文件: " + err.Error(),
 			})
 			return
 		}
-
-		f, err := file.Open()
+		field := upload.field
+
+		// 只读取文件头来确定格式，音频直接从请求体边读边解码，不缓存在内存或临时文件中
+		reader := bufio.NewReaderSize(upload.audio, sniffSize)
+		header, _ := reader.Peek(sniffSize)
+		audio = reader
+
+		// 根据文件头、文件名、Content-Type 和 format 字段确定格式
+		format, err := resolveFormat(header, formatHints{
+			Filename:    upload.audio.FileName(),
+			ContentType: upload.audio.Header.Get("Content-Type"),
+			Format:      field("format"),
+		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, TranscribeResponse{
+			c.JSON(http.StatusUnsupportedMediaType, TranscribeResponse{
 				Success: false,
-				Error:   "无法打开音频文件: " + err.Error(),
+				Error:   err.Error(),
 			})
 			return
 		}
-		defer f.Close()
+		req.Format = format
+
+		if v := field("sample_rate"); v != "" {
+			sampleRate, err := strconv.Atoi(v)
+			if err != nil || sampleRate <= 0 {
+				c.JSON(http.StatusBadRequest, TranscribeResponse{
+					Success: false,
+					Error:   "无效的采样率: " + v,
+				})
+				return
+			}
+			req.SampleRate = sampleRate
+		}
+		req.Mode = field("mode")
+
+		if v := field("timestamps"); v != "" {
+			timestamps, err := strconv.ParseBool(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, TranscribeResponse{
+					Success: false,
+					Error:   "无效的 timestamps 参数: " + v,
+				})
+				return
+			}
+			req.Timestamps = timestamps
+		}
 
-		var buf bytes.Buffer
-		_, err = buf.ReadFrom(f)
+		decoding, err := parseDecodingFields(field)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, TranscribeResponse{
+			c.JSON(http.StatusBadRequest, TranscribeResponse{
 				Success: false,
-				Error:   "无法读取音频文件: " + err.Error(),
+				Error:   err.Error(),
 			})
 			return
 		}
+		req.Hotwords = decoding.Hotwords
+		req.HotwordsScore = decoding.HotwordsScore
+		req.DecodingMethod = decoding.DecodingMethod
+		req.MaxActivePaths = decoding.MaxActivePaths
 
-		req.AudioData = buf.Bytes()
-		req.Format = "wav" // 默认格式
+		req.OutputFormat = field("output_format")
+		if err := parseSubtitleFields(field, &req); err != nil {
+			c.JSON(http.StatusBadRequest, TranscribeResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
 	} else {
 		// 处理 JSON 请求
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, TranscribeResponse{
+			c.JSON(uploadErrorStatus(err), TranscribeResponse{
 				Success: false,
 				Error:   "无效的请求格式: " + err.Error(),
 			})
 			return
 		}
+
+		format, err := resolveFormat(req.AudioData, formatHints{Format: req.Format})
+		if err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, TranscribeResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		req.Format = format
 	}
 
-	// 执行转录
-	result, err := s.transcriber.TranscribeAudio(req.AudioData, req.Format)
-	if err != nil {
-		s.logger.Errorf("转录失败: %v", err)
-		c.JSON(http.StatusInternalServerError, TranscribeResponse{
+	switch req.Mode {
+	case "", transcribe.ModeOnline, transcribe.ModeOffline:
+	default:
+		c.JSON(http.StatusBadRequest, TranscribeResponse{
 			Success: false,
-			Error:   "转录失败: " + err.Error(),
+			Error:   "无效的识别模式: " + req.Mode,
 		})
 		return
 	}
-
-	c.JSON(http.StatusOK, TranscribeResponse{
-		Success: true,
-		Result:  result,
-	})
-}
-
-func (s *Server) realtimeTranscribeHandler(c *gin.Context) {
-	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		s.logger.Errorf("WebSocket 升级失败: %v", err)
+	decoding := transcribe.DecodingOptions{
+		Hotwords:       req.Hotwords,
+		HotwordsScore:  req.HotwordsScore,
+		DecodingMethod: req.DecodingMethod,
+		MaxActivePaths: req.MaxActivePaths,
+	}
+	if err := decoding.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, TranscribeResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
 		return
 	}
 
-	s.logger.Info("实时转录 WebSocket 连接已建立")
+	if req.OutputFormat == "" {
+		req.OutputFormat = transcribe.OutputJSON
+	}
+	if !transcribe.IsOutputFormat(req.OutputFormat) {
+		c.JSON(http.StatusBadRequest, TranscribeResponse{
+			Success: false,
+			Error:   "无效的输出格式: " + req.OutputFormat,
+		})
+		return
+	}
+	if req.MaxLineLength < 0 || req.MaxLines < 0 || req.MaxCueDuration < 0 {
+		c.JSON(http.StatusBadRequest, TranscribeResponse{
+			Success: false,
+			Error:   "字幕切分限制不能为负数",
+		})
+		return
+	}
 
-	// 创建实时转录会话
-	session := &RealtimeSession{
-		conn:       conn,
-		recognizer: s.transcriber.GetRecognizer(),
-		sampleRate: s.transcriber.GetSampleRate(),
-		logger:     s.logger,
-		isActive:   true,
+	// 字幕需要词的时间戳来切分
+	timestamps := req.Timestamps
+	switch req.OutputFormat {
+	case transcribe.OutputSRT, transcribe.OutputVTT, transcribe.OutputTSV:
+		timestamps = true
 	}
 
-	// 创建新的音频流
-	stream := sherpa_onnx.NewOnlineStream(session.recognizer)
-	if stream == nil {
-		session.logger.Error("无法创建音频流")
-		conn.WriteJSON(TranscribeResponse{
+	// 执行转录，客户端断开后停止排队和识别
+	opts := transcribe.TranscribeOptions{
+		Format:     req.Format,
+		SampleRate: req.SampleRate,
+		Mode:       req.Mode,
+		Timestamps: timestamps,
+		Context:    c.Request.Context(),
+
+		DecodingOptions: decoding,
+	}
+	var result *transcribe.TranscriptionResult
+	var err error
+	if audio != nil {
+		result, err = s.transcriber.TranscribeReader(audio, opts)
+	} else {
+		result, err = s.transcriber.TranscribeAudio(req.AudioData, opts)
+	}
+	if err != nil {
+		if c.Request.Context().Err() != nil {
+			s.logger.Info("转录客户端已断开")
+			return
+		}
+		s.logger.Errorf("转录失败: %v", err)
+		c.JSON(s.errorStatus(c, err), TranscribeResponse{
 			Success: false,
-			Error:   "无法创建音频流",
+			Error:   "转录失败: " + err.Error(),
 		})
-		conn.Close()
 		return
 	}
-	session.stream = stream
+	// 音频之后的表单字段没有生效，不能返回按错误参数得到的结果
+	if upload != nil {
+		if err := upload.finish(); err != nil {
+			c.JSON(uploadErrorStatus(err), TranscribeResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
 
-	// 发送连接成功消息
-	conn.WriteJSON(TranscribeResponse{
+	if req.OutputFormat != transcribe.OutputJSON {
+		data, err := transcribe.RenderTranscript(result, req.OutputFormat, transcribe.SubtitleOptions{
+			MaxLineLength: req.MaxLineLength,
+			MaxLines:      req.MaxLines,
+			MaxDuration:   req.MaxCueDuration,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, TranscribeResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.Data(http.StatusOK, transcribe.OutputContentType(req.OutputFormat), data)
+		return
+	}
+
+	c.JSON(http.StatusOK, TranscribeResponse{
 		Success: true,
-		Result: &transcribe.TranscriptionResult{
-			Text: "连接已建立，开始实时转录...",
-		},
+		Result:  result,
 	})
+}
 
-	// 启动实时转录处理
-	go session.handleRealtimeTranscription()
-
-	// 等待连接关闭
-	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
+// parseSubtitleFields 读取表单或查询字符串中的字幕切分限制
+func parseSubtitleFields(field func(string) string, req *TranscribeRequest) error {
+	if v := field("max_line_length"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("无效的 max_line_length 参数: %s", v)
 		}
+		req.MaxLineLength = n
 	}
-	session.cleanup()
-}
-
-func (rs *RealtimeSession) handleRealtimeTranscription() {
-	defer rs.cleanup()
-
-	for rs.isActive {
-		// 读取音频数据
-		_, message, err := rs.conn.ReadMessage()
+	if v := field("max_lines"); v != "" {
+		n, err := strconv.Atoi(v)
 		if err != nil {
-			rs.logger.Errorf("读取 WebSocket 消息失败: %v", err)
-			break
+			return fmt.Errorf("无效的 max_lines 参数: %s", v)
 		}
-
-		// 解析消息
-		var req TranscribeRequest
-		if err := json.Unmarshal(message, &req); err != nil {
-			rs.sendError("无效的消息格式: " + err.Error())
-			continue
+		req.MaxLines = n
+	}
+	if v := field("max_cue_duration"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("无效的 max_cue_duration 参数: %s", v)
 		}
+		req.MaxCueDuration = d
+	}
+	return nil
+}
 
-		// 处理音频数据
-		if err := rs.processAudioChunk(req.AudioData, req.Format); err != nil {
-			rs.sendError("处理音频数据失败: " + err.Error())
-			continue
+// parseDecodingFields 读取表单或查询字符串中的热词和解码参数，hotwords 是逗号分隔的列表
+func parseDecodingFields(field func(string) string) (transcribe.DecodingOptions, error) {
+	var opts transcribe.DecodingOptions
+	if v := field("hotwords"); v != "" {
+		for _, word := range strings.Split(v, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				opts.Hotwords = append(opts.Hotwords, word)
+			}
+		}
+	}
+	if v := field("hotwords_score"); v != "" {
+		score, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return opts, fmt.Errorf("无效的 hotwords_score 参数: %s", v)
+		}
+		opts.HotwordsScore = float32(score)
+	}
+	opts.DecodingMethod = field("decoding_method")
+	if v := field("max_active_paths"); v != "" {
+		paths, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("无效的 max_active_paths 参数: %s", v)
 		}
+		opts.MaxActivePaths = paths
 	}
+	return opts, opts.Validate()
 }
 
-func (rs *RealtimeSession) processAudioChunk(audioData []byte, format string) error {
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
-
-	// 处理音频数据
-	audioSamples, err := rs.processAudioData(audioData, format)
-	if err != nil {
-		return fmt.Errorf("处理音频数据失败: %v", err)
+// errorStatus 把转录错误映射为 HTTP 状态码，排队相关的错误同时设置 Retry-After
+func (s *Server) errorStatus(c *gin.Context, err error) int {
+	var unsupported *transcribe.UnsupportedFormatError
+	switch {
+	case errors.As(err, &unsupported):
+		return http.StatusUnsupportedMediaType
+	case isTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, transcribe.ErrModeUnavailable), errors.Is(err, transcribe.ErrInvalidOptions), errors.Is(err, transcribe.ErrTimestampsUnavailable):
+		return http.StatusBadRequest
+	case errors.Is(err, transcribe.ErrQueueFull):
+		s.setRetryAfter(c)
+		return http.StatusTooManyRequests
+	case errors.Is(err, transcribe.ErrQueueTimeout), errors.Is(err, transcribe.ErrPoolClosed), errors.Is(err, transcribe.ErrTooManyRecognizers):
+		s.setRetryAfter(c)
+		return http.StatusServiceUnavailable
 	}
+	return http.StatusInternalServerError
+}
 
-	// 将音频数据输入到流中
-	rs.stream.AcceptWaveform(rs.sampleRate, audioSamples)
+// SetMaxUploadSize 设置上传音频的请求体大小上限（字节），为 0 时不限制
+func (s *Server) SetMaxUploadSize(size int64) {
+	s.maxUploadSize = size
+}
 
-	// 获取识别结果
-	result := rs.recognizer.GetResult(rs.stream)
-	if result.Text != "" {
-		// 检查是否是最终结果（这里简化处理）
-		// 在实际应用中，您可能需要更复杂的逻辑来判断是否是最终结果
-		isFinal := len(audioSamples) < rs.sampleRate // 如果音频块小于1秒，可能是最终结果
-		rs.sendResult(result.Text, isFinal)
+// limitUpload 在读取请求体之前检查大小限制
+// Content-Length 超过上限时直接返回 413，没有声明长度时读到上限后报错
+func (s *Server) limitUpload(c *gin.Context) {
+	if s.maxUploadSize <= 0 {
+		return
 	}
+	if c.Request.ContentLength > s.maxUploadSize {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, TranscribeResponse{
+			Success: false,
+			Error:   fmt.Sprintf("上传的数据超过 %d 字节的限制", s.maxUploadSize),
+		})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.maxUploadSize)
+}
 
-	return nil
+// isTooLarge 判断错误是否由请求体超过大小限制引起
+func isTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
 }
 
-func (rs *RealtimeSession) processAudioData(audioData []byte, format string) ([]float32, error) {
-	// 这里复用 transcribe 包中的音频处理逻辑
-	// 简化处理，假设是原始 PCM 数据
-	if format == "pcm" {
-		audioSamples := make([]float32, 0, len(audioData)/2)
-		for i := 0; i < len(audioData)-1; i += 2 {
-			sample := int16(audioData[i]) | (int16(audioData[i+1]) << 8)
-			audioSamples = append(audioSamples, float32(sample)/32768.0)
-		}
-		return audioSamples, nil
+// uploadErrorStatus 读取请求体失败时的状态码，超过大小限制时为 413
+func uploadErrorStatus(err error) int {
+	if isTooLarge(err) {
+		return http.StatusRequestEntityTooLarge
 	}
+	return http.StatusBadRequest
+}
 
-	return nil, fmt.Errorf("不支持的音频格式: %s", format)
+// setRetryAfter 根据转录池的排队情况设置 Retry-After（秒）
+func (s *Server) setRetryAfter(c *gin.Context) {
+	retryAfter := time.Second
+	if queue, ok := s.transcriber.(queueReporter); ok {
+		retryAfter = queue.RetryAfter()
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
 }
 
-func (rs *RealtimeSession) sendResult(text string, isFinal bool) {
-	response := TranscribeResponse{
-		Success: true,
-		Result: &transcribe.TranscriptionResult{
-			Text: text,
-		},
+func (s *Server) realtimeTranscribeHandler(c *gin.Context) {
+	// 先用默认的解码选项创建流式识别会话，会话数量达到上限时直接返回 HTTP 错误
+	stream, err := s.transcriber.NewSession(transcribe.DecodingOptions{})
+	if err != nil {
+		s.logger.Errorf("无法创建识别会话: %v", err)
+		c.JSON(s.errorStatus(c, err), TranscribeResponse{
+			Success: false,
+			Error:   "无法创建识别会话: " + err.Error(),
+		})
+		return
 	}
 
-	// 添加最终结果标识
-	if isFinal {
-		response.Result.Text += " [FINAL]"
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Errorf("WebSocket 升级失败: %v", err)
+		stream.Close()
+		return
 	}
 
-	rs.conn.WriteJSON(response)
+	s.logger.Info("实时转录 WebSocket 连接已建立")
+
+	// 在当前 goroutine 中读取连接，直到会话结束
+	rs := newRealtimeSession(conn, stream, s.transcriber.GetSampleRate(), s.logger)
+	rs.newSession = s.transcriber.NewSession
+	rs.run(s.ctx)
 }
 
-func (rs *RealtimeSession) sendError(message string) {
-	response := TranscribeResponse{
-		Success: false,
-		Error:   message,
+// Start 按配置监听端口或 Unix socket 并提供 HTTP 服务，Shutdown 之后返回 http.ErrServerClosed
+func (s *Server) Start() error {
+	cfg := config.AppConfig.Server
+
+	listener, err := listen(cfg.UseUnixSocket, cfg.UnixSocket, cfg.Host, cfg.Port)
+	if err != nil {
+		return err
 	}
-	rs.conn.WriteJSON(response)
+	return s.serve(listener)
 }
 
-func (rs *RealtimeSession) cleanup() {
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
-
-	rs.isActive = false
+// serve 在 listener 上提供 HTTP 服务，直到 Shutdown 或 Close
+func (s *Server) serve(listener net.Listener) error {
+	defer listener.Close()
 
-	if rs.stream != nil {
-		sherpa_onnx.DeleteOnlineStream(rs.stream)
-		rs.stream = nil
+	// Shutdown 先取消 ctx 再取服务器，已经关闭时不再开始服务
+	server := &http.Server{Handler: s.router}
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return http.ErrServerClosed
 	}
+	s.http = server
+	s.mu.Unlock()
 
-	if rs.conn != nil {
-		rs.conn.Close()
+	s.logger.Infof("服务器启动在: %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+// listen 监听 Unix socket 或 TCP 端口，HTTP 和 gRPC 服务共用
+func listen(useUnixSocket bool, socket, host string, port int) (net.Listener, error) {
+	if useUnixSocket {
+		// 删除已存在的 socket 文件
+		os.Remove(socket)
+
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("无法创建 Unix socket: %v", err)
+		}
+		return listener, nil
 	}
 
-	rs.logger.Info("实时转录会话已清理")
+	addr := fmt.Sprintf("%s:%d", host, port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("无法监听 %s: %v", addr, err)
+	}
+	return listener, nil
 }
 
-func (s *Server) Start() error {
-	cfg := config.AppConfig.Server
+// Shutdown 优雅地停止服务，之后 Start 返回 http.ErrServerClosed
+// 先结束实时转录会话和流式识别（它们不会自己结束），再停止接受新连接并等待进行中的请求和 gRPC 调用；
+// ctx 到期时强制关闭剩余的连接并返回 ctx 的错误
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
 
-	if cfg.UseUnixSocket {
-		// 删除已存在的 socket 文件
-		os.Remove(cfg.UnixSocket)
+	s.mu.Lock()
+	httpServer, grpcServer := s.http, s.grpc
+	s.mu.Unlock()
 
-		// 创建 Unix socket
-		listener, err := net.Listen("unix", cfg.UnixSocket)
-		if err != nil {
-			return fmt.Errorf("无法创建 Unix socket: %v", err)
+	var err error
+	if httpServer != nil {
+		if err = httpServer.Shutdown(ctx); err != nil {
+			httpServer.Close()
 		}
-		defer listener.Close()
+	}
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+			<-stopped
+			if err == nil {
+				err = ctx.Err()
+			}
+		}
+	}
+	return err
+}
 
-		s.logger.Infof("服务器启动在 Unix socket: %s", cfg.UnixSocket)
-		return http.Serve(listener, s.router)
-	} else {
-		// 使用 TCP 端口
-		addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-		s.logger.Infof("服务器启动在: %s", addr)
-		return s.router.Run(addr)
+// Close 结束所有实时转录会话，并立即关闭 HTTP 服务、停止 gRPC 服务
+func (s *Server) Close() {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.http != nil {
+		s.http.Close()
+	}
+	if s.grpc != nil {
+		s.grpc.GracefulStop()
 	}
 }
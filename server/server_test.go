@@ -2,10 +2,20 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/layzdonw/transerver/transcribe"
@@ -16,8 +26,7 @@ func TestHealthCheck(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// 创建测试服务器
-	transcriber := transcribe.NewSherpaTranscriber("", "", 16000, 1, "greedy_search")
-	srv := NewServer(transcriber)
+	srv := NewServer(transcribe.NewFakeTranscriber("测试文本"))
 
 	// 创建测试请求
 	w := httptest.NewRecorder()
@@ -47,8 +56,7 @@ func TestTranscribeHandlerInvalidJSON(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// 创建测试服务器
-	transcriber := transcribe.NewSherpaTranscriber("", "", 16000, 1, "greedy_search")
-	srv := NewServer(transcriber)
+	srv := NewServer(transcribe.NewFakeTranscriber("测试文本"))
 
 	// 创建无效的 JSON 请求
 	w := httptest.NewRecorder()
@@ -74,3 +82,657 @@ func TestTranscribeHandlerInvalidJSON(t *testing.T) {
 		t.Error("期望请求失败，但得到了成功响应")
 	}
 }
+
+func TestTranscribeHandlerJSON(t *testing.T) {
+	// 设置测试模式
+	gin.SetMode(gin.TestMode)
+
+	// 使用假转录引擎创建测试服务器
+	transcriber := transcribe.NewFakeTranscriber("你好世界")
+	srv := NewServer(transcriber)
+
+	// 创建 JSON 请求
+	body, _ := json.Marshal(TranscribeRequest{
+		AudioData: make([]byte, 32000),
+		Format:    "pcm",
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	// 执行请求
+	srv.router.ServeHTTP(w, req)
+
+	// 检查响应
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response TranscribeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+
+	if !response.Success || response.Result == nil {
+		t.Fatalf("期望成功响应，得到 %+v", response)
+	}
+	if response.Result.Text != "你好世界" {
+		t.Errorf("期望文本 '你好世界'，得到 '%s'", response.Result.Text)
+	}
+	if transcriber.Requests() != 1 {
+		t.Errorf("期望调用转录引擎 1 次，实际 %d 次", transcriber.Requests())
+	}
+}
+
+func TestTranscribeHandlerSampleRate(t *testing.T) {
+	// 设置测试模式
+	gin.SetMode(gin.TestMode)
+
+	srv := NewServer(transcribe.NewFakeTranscriber("测试文本"))
+
+	// 8kHz PCM 音频，16000 个采样对应 2 秒
+	body, _ := json.Marshal(TranscribeRequest{
+		AudioData:  make([]byte, 32000),
+		Format:     "pcm",
+		SampleRate: 8000,
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	srv.router.ServeHTTP(w, req)
+
+	var response TranscribeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !response.Success || response.Result == nil {
+		t.Fatalf("期望成功响应，得到 %+v", response)
+	}
+	if response.Result.Duration != 2 {
+		t.Errorf("期望时长 2 秒，得到 %f", response.Result.Duration)
+	}
+}
+
+// multipartRequest 构造上传音频文件的 multipart 请求，表单字段放在音频之前
+func multipartRequest(t *testing.T, filename, contentType string, data []byte, fields map[string]string) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		writer.WriteField(k, v)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="audio"; filename="%s"`, filename))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatalf("创建表单文件失败: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/transcribe", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestTranscribeHandlerMultipartFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		filename    string
+		contentType string
+		data        []byte
+		fields      map[string]string
+		expected    int
+	}{
+		{"WAV 文件", "audio.wav", "audio/wav", testWAV(16000, 1600), nil, http.StatusOK},
+		{"文件名未知时按魔数识别", "upload.bin", "application/octet-stream", testWAV(8000, 800), nil, http.StatusOK},
+		{"原始 PCM 使用 format 字段", "upload.bin", "", make([]byte, 3200), map[string]string{"format": "pcm"}, http.StatusOK},
+		{"扩展名与内容不符", "audio.flac", "", testWAV(16000, 1600), nil, http.StatusUnsupportedMediaType},
+		{"无法识别的内容", "upload.bin", "", make([]byte, 3200), nil, http.StatusUnsupportedMediaType},
+		{"MPEG Layer II", "upload.bin", "", testMP2(), nil, http.StatusUnsupportedMediaType},
+		{"MPEG Layer II 声明为 MP3", "audio.mp3", "audio/mpeg", testMP2(), nil, http.StatusUnsupportedMediaType},
+		{"Ogg Opus 语音", "note.opus", "audio/ogg", testOpus(t), nil, http.StatusOK},
+		{"不支持的 Opus 多声道映射", "note.opus", "audio/ogg", oggOpusHead(6, 1), nil, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(transcribe.NewFakeTranscriber("测试文本"))
+
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, multipartRequest(t, tt.filename, tt.contentType, tt.data, tt.fields))
+
+			if w.Code != tt.expected {
+				t.Errorf("期望状态码 %d，得到 %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTranscribeHandlerJSONFormatMismatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	srv := NewServer(transcribe.NewFakeTranscriber("测试文本"))
+
+	body, _ := json.Marshal(TranscribeRequest{
+		AudioData: testWAV(16000, 160),
+		Format:    "mp3",
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	srv.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("期望状态码 %d，得到 %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
+
+func TestTranscribeHandlerMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("表单字段传递识别模式", func(t *testing.T) {
+		transcriber := transcribe.NewFakeTranscriber("测试文本")
+		srv := NewServer(transcriber)
+
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), map[string]string{"mode": "offline"}))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if mode := transcriber.LastOptions().Mode; mode != transcribe.ModeOffline {
+			t.Errorf("期望识别模式 offline，得到 %q", mode)
+		}
+	})
+
+	t.Run("无效的识别模式", func(t *testing.T) {
+		transcriber := transcribe.NewFakeTranscriber("测试文本")
+		srv := NewServer(transcriber)
+
+		body, _ := json.Marshal(TranscribeRequest{
+			AudioData: testWAV(16000, 160),
+			Mode:      "batch",
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		srv.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("期望状态码 %d，得到 %d", http.StatusBadRequest, w.Code)
+		}
+		if transcriber.Requests() != 0 {
+			t.Errorf("无效模式不应该调用转录引擎")
+		}
+	})
+
+	t.Run("识别模式不可用", func(t *testing.T) {
+		transcriber := transcribe.NewFakeTranscriber("测试文本")
+		transcriber.Err = fmt.Errorf("%w: 离线识别器未启用", transcribe.ErrModeUnavailable)
+		srv := NewServer(transcriber)
+
+		body, _ := json.Marshal(TranscribeRequest{
+			AudioData: testWAV(16000, 160),
+			Mode:      transcribe.ModeOffline,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		srv.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("期望状态码 %d，得到 %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestTranscribeHandlerTimestamps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	transcriber := transcribe.NewFakeTranscriber("你好")
+	transcriber.Tokens = []transcribe.Token{{Text: "你", Start: 0, End: 0.2}, {Text: "好", Start: 0.2, End: 0.4}}
+	srv := NewServer(transcriber)
+
+	// 不请求时间戳时不返回词
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), nil))
+	if bytes.Contains(w.Body.Bytes(), []byte(`"words"`)) {
+		t.Errorf("未请求时间戳时不应该返回词: %s", w.Body.String())
+	}
+	// 识别器没有提供概率时不返回置信度
+	if bytes.Contains(w.Body.Bytes(), []byte(`"confidence"`)) {
+		t.Errorf("没有 token 概率时不应该返回置信度: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), map[string]string{"timestamps": "true"}))
+	var response TranscribeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !response.Success || len(response.Result.Words) != 2 || len(response.Result.Tokens) != 2 {
+		t.Fatalf("期望返回 2 个词和 2 个 token: %s", w.Body.String())
+	}
+	if word := response.Result.Words[1]; word.Text != "好" || word.Start != 0.2 || word.End != 0.4 {
+		t.Errorf("词的时间戳错误: %+v", word)
+	}
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), map[string]string{"timestamps": "maybe"}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("无效的 timestamps 参数期望状态码 %d，得到 %d", http.StatusBadRequest, w.Code)
+	}
+
+	// 模型不提供时间戳时明确返回错误，而不是返回没有时间戳的结果
+	srv = NewServer(transcribe.NewFakeTranscriber("你好"))
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), map[string]string{"timestamps": "true"}))
+	if w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte("当前模型不提供时间戳")) {
+		t.Errorf("模型不提供时间戳时期望状态码 %d，得到 %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestTranscribeHandlerOutputFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	transcriber := transcribe.NewFakeTranscriber("你好")
+	transcriber.Tokens = []transcribe.Token{{Text: "你", Start: 0, End: 0.2}, {Text: "好", Start: 0.2, End: 0.4}}
+	srv := NewServer(transcriber)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), map[string]string{"output_format": "srt"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-subrip; charset=utf-8" {
+		t.Errorf("Content-Type 错误: %s", ct)
+	}
+	if body := w.Body.String(); body != "1\n00:00:00,000 --> 00:00:00,400\n你好\n\n" {
+		t.Errorf("SRT 内容错误: %q", body)
+	}
+	// 字幕格式自动请求时间戳
+	if !transcriber.LastOptions().Timestamps {
+		t.Error("字幕格式应该请求时间戳")
+	}
+
+	body, _ := json.Marshal(TranscribeRequest{AudioData: testWAV(16000, 160), OutputFormat: "docx"})
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	srv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("无效的输出格式期望状态码 %d，得到 %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), map[string]string{"output_format": "vtt", "max_lines": "x"}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("无效的切分限制期望状态码 %d，得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTranscribeHandlerClientGone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		request func(t *testing.T) *http.Request
+	}{
+		{"JSON 请求", func(t *testing.T) *http.Request {
+			body, _ := json.Marshal(TranscribeRequest{AudioData: make([]byte, 3200), Format: "pcm"})
+			req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		}},
+		{"表单上传", func(t *testing.T) *http.Request {
+			return multipartRequest(t, "audio.wav", "audio/wav", testWAV(16000, 1600), nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 识别一直阻塞，只有请求的 context 取消后才返回
+			fake := transcribe.NewFakeTranscriber("测试文本")
+			fake.Gate = make(chan struct{})
+			defer close(fake.Gate)
+			srv := NewServer(fake)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				srv.router.ServeHTTP(httptest.NewRecorder(), tt.request(t).WithContext(ctx))
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("客户端断开后转录没有停止")
+			}
+			if fake.LastOptions().Context != ctx {
+				t.Error("转录选项没有使用请求的 context")
+			}
+		})
+	}
+}
+
+func TestTranscribeHandlerQueueFull(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := transcribe.NewFakeTranscriber("测试文本")
+	fake.Gate = make(chan struct{})
+	pool := transcribe.NewPool(fake, transcribe.PoolConfig{Workers: 1})
+	srv := NewServer(pool)
+
+	newRequest := func() *http.Request {
+		body, _ := json.Marshal(TranscribeRequest{AudioData: testWAV(16000, 160)})
+		req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	// 第一个请求占用唯一的解码槽位
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, newRequest())
+		done <- w.Code
+	}()
+	for deadline := time.Now().Add(2 * time.Second); pool.Stats().Busy == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("等待请求开始解码超时")
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, newRequest())
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("期望状态码 %d，得到 %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 响应缺少 Retry-After")
+	}
+
+	// 健康检查返回排队情况
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/health", nil)
+	srv.router.ServeHTTP(w, req)
+	var health struct {
+		Queue transcribe.PoolStats `json:"queue"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if health.Queue.Workers != 1 || health.Queue.Busy != 1 {
+		t.Errorf("健康检查中的排队情况错误: %+v", health.Queue)
+	}
+
+	close(fake.Gate)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("第一个请求期望状态码 %d，得到 %d", http.StatusOK, code)
+	}
+}
+
+func TestRealtimeHandlerMaxSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	pool := transcribe.NewPool(transcribe.NewFakeTranscriber("测试文本"), transcribe.PoolConfig{MaxSessions: 1})
+	session, err := pool.NewSession(transcribe.DecodingOptions{})
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	defer session.Close()
+	srv := NewServer(pool)
+
+	// 会话数量已达上限，升级之前直接返回 429
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ws/realtime", nil)
+	srv.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("期望状态码 %d，得到 %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 响应缺少 Retry-After")
+	}
+}
+
+// testOpus 读取 transcribe 包中由 libopus 编码的 Ogg Opus 测试文件
+func testOpus(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("../transcribe/testdata/opus.ogg")
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+	return data
+}
+
+// oggOpusHead 生成只包含 OpusHead 页的 Ogg 文件
+func oggOpusHead(channels, mappingFamily byte) []byte {
+	head := append([]byte("OpusHead\x01"), channels, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0, mappingFamily)
+	page := []byte("OggS\x00\x02")
+	page = append(page, make([]byte, 20)...)
+	page = append(page, 1, byte(len(head)))
+	page = append(page, head...)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	return page
+}
+
+// oggCRC 计算 Ogg 页的 CRC32（多项式 0x04c11db7，非反射）
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func TestTranscribeHandlerMaxUploadSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	transcriber := transcribe.NewFakeTranscriber("测试文本")
+	srv := NewServer(transcriber)
+	srv.SetMaxUploadSize(8000)
+
+	// 未超过上限时正常转录
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 1600), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Content-Length 超过上限时不读取请求体
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, multipartRequest(t, "audio.wav", "", testWAV(16000, 16000), nil))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("期望状态码 %d，得到 %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	// 没有 Content-Length 时读到上限后返回 413
+	req := multipartRequest(t, "audio.wav", "", testWAV(16000, 16000), nil)
+	req.Body = io.NopCloser(io.MultiReader(req.Body))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("期望状态码 %d，得到 %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	body, _ := json.Marshal(TranscribeRequest{AudioData: testWAV(16000, 16000)})
+	req, _ = http.NewRequest("POST", "/transcribe", io.MultiReader(bytes.NewReader(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("JSON 请求期望状态码 %d，得到 %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	if n := transcriber.Requests(); n != 1 {
+		t.Errorf("超过上限的请求不应该转录，实际转录 %d 次", n)
+	}
+}
+
+func TestTranscribeHandlerDecodingOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("JSON 请求", func(t *testing.T) {
+		transcriber := transcribe.NewFakeTranscriber("你好")
+		srv := NewServer(transcriber)
+
+		body, _ := json.Marshal(TranscribeRequest{
+			AudioData:      make([]byte, 3200),
+			Format:         "pcm",
+			Hotwords:       []string{"语音识别", "转录"},
+			HotwordsScore:  2,
+			MaxActivePaths: 6,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/transcribe", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		srv.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if opts := transcriber.LastOptions(); len(opts.Hotwords) != 2 || opts.HotwordsScore != 2 || opts.MaxActivePaths != 6 {
+			t.Errorf("热词没有传给转录引擎: %+v", opts.DecodingOptions)
+		}
+	})
+
+	t.Run("multipart 请求", func(t *testing.T) {
+		transcriber := transcribe.NewFakeTranscriber("你好")
+		srv := NewServer(transcriber)
+
+		w := httptest.NewRecorder()
+		fields := map[string]string{
+			"format":           "pcm",
+			"hotwords":         "语音识别, 转录",
+			"hotwords_score":   "1.5",
+			"decoding_method":  "modified_beam_search",
+			"max_active_paths": "8",
+		}
+		srv.router.ServeHTTP(w, multipartRequest(t, "audio.pcm", "", make([]byte, 3200), fields))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d，得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		opts := transcriber.LastOptions()
+		if len(opts.Hotwords) != 2 || opts.Hotwords[1] != "转录" || opts.HotwordsScore != 1.5 {
+			t.Errorf("热词没有传给转录引擎: %+v", opts.DecodingOptions)
+		}
+		if opts.DecodingMethod != transcribe.DecodingModifiedBeamSearch || opts.MaxActivePaths != 8 {
+			t.Errorf("解码参数没有传给转录引擎: %+v", opts.DecodingOptions)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{"无效的加分", map[string]string{"format": "pcm", "hotwords": "转录", "hotwords_score": "abc"}},
+		{"加分超出范围", map[string]string{"format": "pcm", "hotwords": "转录", "hotwords_score": "100"}},
+		{"不支持的解码方法", map[string]string{"format": "pcm", "decoding_method": "beam"}},
+		{"无效的路径数", map[string]string{"format": "pcm", "max_active_paths": "abc"}},
+		{"路径数超出范围", map[string]string{"format": "pcm", "max_active_paths": "100"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transcriber := transcribe.NewFakeTranscriber("你好")
+			srv := NewServer(transcriber)
+
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, multipartRequest(t, "audio.pcm", "", make([]byte, 3200), tt.fields))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("期望状态码 %d，得到 %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			if transcriber.Requests() != 0 {
+				t.Errorf("无效的热词不应该调用转录引擎")
+			}
+		})
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Gate = make(chan struct{})
+	srv := NewServer(fake)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("无法监听: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.serve(listener)
+	}()
+
+	// 一个请求正在转录
+	responses := make(chan int, 1)
+	go func() {
+		body, _ := json.Marshal(TranscribeRequest{AudioData: testWAV(16000, 160)})
+		resp, err := http.Post("http://"+listener.Addr().String()+"/transcribe", "application/json", bytes.NewReader(body))
+		if err != nil {
+			responses <- 0
+			return
+		}
+		resp.Body.Close()
+		responses <- resp.StatusCode
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for fake.Requests() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("请求没有开始转录")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Shutdown 等正在处理的请求结束后才返回
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- srv.Shutdown(ctx)
+	}()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("请求结束之前 Shutdown 不应该返回: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(fake.Gate)
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown 失败: %v", err)
+	}
+	if code := <-responses; code != http.StatusOK {
+		t.Errorf("正在处理的请求应该正常完成，得到状态码 %d", code)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("期望 http.ErrServerClosed，得到 %v", err)
+	}
+
+	// 关闭之后不再开始服务
+	listener, _ = net.Listen("tcp", "127.0.0.1:0")
+	if err := srv.serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("关闭后期望 http.ErrServerClosed，得到 %v", err)
+	}
+}
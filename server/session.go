@@ -0,0 +1,68 @@
+package server
+
+import (
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// streamDriver 驱动一个流式识别会话，实时转录 WebSocket、分块上传（SSE）和 gRPC 共用
+// 负责把音频送入会话、结束句子，并把识别结果转换为 partial 和 final 事件；
+// 各接口只负责读取音频和发送事件
+type streamDriver struct {
+	session transcribe.Session
+	// partials 是否发送 partial 事件
+	partials bool
+	// timestamps 是否在结果中返回词和 token 的时间戳
+	timestamps bool
+	// emit 发送一个结果事件，返回错误时停止发送并把错误交给调用方
+	emit func(ResultEvent) error
+}
+
+// acceptChunk 按音频配置解码客户端发送的一段音频并送入会话
+func (d *streamDriver) acceptChunk(config *StreamConfig, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	audio, err := decodeStreamChunk(config, data)
+	if err != nil {
+		return err
+	}
+	return d.accept(audio.SampleRate, audio.Samples)
+}
+
+// accept 把音频送入会话并发送产生的结果，会话内部负责重采样和端点检测
+func (d *streamDriver) accept(sampleRate int, samples []float32) error {
+	results, err := d.session.AcceptWaveform(sampleRate, samples)
+	if err != nil {
+		return newProtocolError(ErrorInternal, "识别失败: %v", err)
+	}
+	return d.send(results)
+}
+
+// flush 结束当前句子并发送它的 final
+func (d *streamDriver) flush() error {
+	results, err := d.session.Flush()
+	if err != nil {
+		return newProtocolError(ErrorInternal, "结束句子失败: %v", err)
+	}
+	return d.send(results)
+}
+
+// send 发送识别结果，关闭部分结果时只发送 final
+// 请求了时间戳而结果有文本却没有 token 时，说明模型不提供时间戳，返回无法恢复的错误
+func (d *streamDriver) send(results []transcribe.StreamResult) error {
+	for _, result := range results {
+		if d.timestamps && result.Text != "" && len(result.Tokens) == 0 {
+			err := newProtocolError(ErrorTimestampsUnavailable, "%v", transcribe.ErrTimestampsUnavailable)
+			err.fatal = true
+			return err
+		}
+		if !result.Final && !d.partials {
+			continue
+		}
+		if err := d.emit(newResultEvent(result, d.timestamps)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
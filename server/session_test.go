@@ -0,0 +1,121 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+func TestStreamDriver(t *testing.T) {
+	session, _ := transcribe.NewFakeTranscriber("你好").NewSession(transcribe.DecodingOptions{})
+	defer session.Close()
+
+	var events []ResultEvent
+	driver := streamDriver{
+		session:  session,
+		partials: true,
+		emit: func(event ResultEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	}
+
+	speech := make([]float32, 1600)
+	for i := range speech {
+		speech[i] = 0.1
+	}
+	if err := driver.accept(16000, speech); err != nil {
+		t.Fatalf("送入音频失败: %v", err)
+	}
+	if err := driver.flush(); err != nil {
+		t.Fatalf("结束句子失败: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != EventPartial || events[1].Type != EventFinal || events[1].Text != "你好" {
+		t.Fatalf("事件错误: %+v", events)
+	}
+
+	// 关闭部分结果时只发送 final
+	events = nil
+	driver.partials = false
+	driver.accept(16000, speech)
+	driver.flush()
+	if len(events) != 1 || events[0].Type != EventFinal || events[0].Utterance != 1 {
+		t.Errorf("关闭部分结果时事件错误: %+v", events)
+	}
+
+	// 无法解码的音频返回 invalid_audio
+	err := driver.acceptChunk(&StreamConfig{Format: "pcm"}, []byte{1, 2, 3})
+	if perr, ok := err.(*protocolError); !ok || perr.code != ErrorInvalidAudio {
+		t.Errorf("期望 invalid_audio 错误，得到 %v", err)
+	}
+
+	// 发送失败的错误原样返回
+	sendErr := errors.New("连接已断开")
+	driver.emit = func(ResultEvent) error { return sendErr }
+	driver.accept(16000, speech)
+	if err := driver.flush(); !errors.Is(err, sendErr) {
+		t.Errorf("期望发送错误，得到 %v", err)
+	}
+
+	// 会话出错时返回 internal
+	session.Close()
+	if err := driver.flush(); err == nil {
+		t.Error("会话关闭后期望返回错误")
+	} else if perr, ok := err.(*protocolError); !ok || perr.code != ErrorInternal {
+		t.Errorf("期望 internal 错误，得到 %v", err)
+	}
+}
+
+func TestStreamDriverTimestamps(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Tokens = []transcribe.Token{{Text: "你", Start: 0, End: 0.05}, {Text: "好", Start: 0.05, End: 0.1}}
+	session, _ := fake.NewSession(transcribe.DecodingOptions{})
+	defer session.Close()
+
+	var events []ResultEvent
+	driver := streamDriver{
+		session:  session,
+		partials: true,
+		emit: func(event ResultEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	}
+
+	speech := make([]float32, 1600)
+	for i := range speech {
+		speech[i] = 0.1
+	}
+	// 不请求时间戳时不返回词和 token
+	driver.accept(16000, speech)
+	driver.flush()
+	if len(events) != 2 || events[1].Words != nil || events[1].Tokens != nil {
+		t.Fatalf("未请求时间戳时不应该返回词和 token: %+v", events)
+	}
+
+	// 第二句的 token 时间加上句子在会话中的开始时间
+	events = nil
+	driver.timestamps = true
+	driver.accept(16000, speech)
+	driver.flush()
+	if len(events) != 2 || len(events[1].Words) != 2 || len(events[1].Tokens) != 2 {
+		t.Fatalf("期望返回 2 个词和 2 个 token: %+v", events)
+	}
+	if token := events[1].Tokens[1]; token.Start != 0.15 || token.End != 0.2 {
+		t.Errorf("token 的时间应该相对于会话开始: %+v", token)
+	}
+
+	// 模型不提供时间戳时返回无法恢复的错误
+	session, _ = transcribe.NewFakeTranscriber("你好").NewSession(transcribe.DecodingOptions{})
+	defer session.Close()
+	driver.session = session
+	events = nil
+	err := driver.accept(16000, speech)
+	if perr, ok := err.(*protocolError); !ok || perr.code != ErrorTimestampsUnavailable || !perr.fatal {
+		t.Errorf("期望无法恢复的 timestamps_unavailable 错误，得到 %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("出错时不应该发送结果: %+v", events)
+	}
+}
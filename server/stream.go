@@ -0,0 +1,254 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+const (
+	// streamSniffSize 识别分块上传的音频格式时读取的文件头长度
+	streamSniffSize = 12
+	// streamChunkSamples 每次送入识别会话的最大采样数
+	streamChunkSamples = 4096
+)
+
+// streamTranscribeHandler 边上传边识别，以 Server-Sent Events 返回识别结果
+// 请求体是分块上传的原始 PCM 或 WAV 音频，参数放在查询字符串中；
+// 事件与实时转录 WebSocket 协议相同，event 为事件类型，data 为事件的 JSON。
+// 请求体结束相当于发送 stop：返回剩余的 final 后发送 closed
+func (s *Server) streamTranscribeHandler(c *gin.Context) {
+	config, err := parseStreamQuery(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TranscribeResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 没有指定格式时根据文件头识别，无法可靠识别时按原始 PCM 处理
+	body := bufio.NewReaderSize(c.Request.Body, sniffSize)
+	if config.Format == "" {
+		header, _ := body.Peek(streamSniffSize)
+		if n := transcribe.MPEGFrameSize(header); n > 0 {
+			// 像 MPEG 帧头时再等到第二个帧头，以 0xFFFF 开始的 PCM 也可能通过一个帧头的检查
+			header, _ = body.Peek(n + 4)
+		}
+		config.Format = transcribe.SniffFormat(header)
+		if config.Format == "" {
+			config.Format = "pcm"
+		}
+	}
+	if err := validateStreamConfig(&config); err != nil {
+		status := http.StatusBadRequest
+		if perr, ok := err.(*protocolError); ok && perr.code == ErrorUnsupportedFormat {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, TranscribeResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	decoding, _ := config.decodingOptions()
+	session, err := s.transcriber.NewSession(decoding)
+	if err != nil {
+		s.logger.Errorf("无法创建识别会话: %v", err)
+		c.JSON(s.errorStatus(c, err), TranscribeResponse{
+			Success: false,
+			Error:   "无法创建识别会话: " + err.Error(),
+		})
+		return
+	}
+	defer session.Close()
+
+	// WAV 在这里读取文件头，之后的数据边到达边解码
+	sampleRate := config.SampleRate
+	if sampleRate == 0 {
+		sampleRate = s.transcriber.GetSampleRate()
+	}
+	reader, err := transcribe.NewAudioReader(body, config.Format, sampleRate)
+	if err != nil {
+		status := http.StatusBadRequest
+		var unsupported *transcribe.UnsupportedFormatError
+		if errors.As(err, &unsupported) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, TranscribeResponse{
+			Success: false,
+			Error:   "处理音频数据失败: " + err.Error(),
+		})
+		return
+	}
+
+	// HTTP/1.1 默认在写响应之前读完请求体，这里需要一边读取音频一边返回结果
+	if err := http.NewResponseController(c.Writer).EnableFullDuplex(); err != nil {
+		s.logger.Debugf("无法启用全双工: %v", err)
+	}
+	c.Header("X-Accel-Buffering", "no")
+
+	s.sendEvent(c, EventReady, ReadyEvent{
+		Type:            EventReady,
+		Version:         ProtocolVersion,
+		SampleRate:      reader.SampleRate(),
+		ModelSampleRate: s.transcriber.GetSampleRate(),
+		Format:          config.Format,
+		Language:        config.Language,
+	})
+
+	driver := streamDriver{
+		session:    session,
+		partials:   config.partialResults(),
+		timestamps: config.timestamps(),
+		emit: func(event ResultEvent) error {
+			s.sendEvent(c, event.Type, event)
+			return nil
+		},
+	}
+	buf := make([]float32, streamChunkSamples)
+	for {
+		n, readErr := reader.ReadSamples(buf)
+		if n > 0 {
+			if err := driver.accept(reader.SampleRate(), buf[:n]); err != nil {
+				s.sendStreamError(c, err)
+				return
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			if c.Request.Context().Err() != nil {
+				s.logger.Info("流式转录客户端已断开")
+				return
+			}
+			s.sendStreamError(c, newProtocolError(ErrorInvalidAudio, "处理音频数据失败: %v", readErr))
+			return
+		}
+
+		select {
+		case <-s.ctx.Done():
+			s.sendEvent(c, EventClosed, ClosedEvent{Type: EventClosed, Reason: "shutdown"})
+			return
+		default:
+		}
+	}
+
+	if err := driver.flush(); err != nil {
+		s.sendStreamError(c, err)
+		return
+	}
+	s.sendEvent(c, EventClosed, ClosedEvent{Type: EventClosed, Reason: "stop"})
+}
+
+// parseStreamQuery 从查询字符串读取音频配置
+func parseStreamQuery(field func(string) string) (StreamConfig, error) {
+	config := StreamConfig{
+		Format:   field("format"),
+		Language: field("language"),
+	}
+
+	if v := field("sample_rate"); v != "" {
+		sampleRate, err := strconv.Atoi(v)
+		if err != nil || sampleRate <= 0 {
+			return config, newProtocolError(ErrorInvalidMessage, "无效的采样率: %s", v)
+		}
+		config.SampleRate = sampleRate
+	}
+
+	if v := field("partial_results"); v != "" {
+		partials, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, newProtocolError(ErrorInvalidMessage, "无效的 partial_results 参数: %s", v)
+		}
+		config.Options = &StreamOptions{PartialResults: &partials}
+	}
+
+	if v := field("timestamps"); v != "" {
+		timestamps, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, newProtocolError(ErrorInvalidMessage, "无效的 timestamps 参数: %s", v)
+		}
+		if config.Options == nil {
+			config.Options = &StreamOptions{}
+		}
+		config.Options.Timestamps = timestamps
+	}
+
+	decoding, err := parseDecodingFields(field)
+	if err == nil {
+		err = parseEndpointFields(field, &decoding)
+	}
+	if err != nil {
+		return config, newProtocolError(ErrorInvalidMessage, "%v", err)
+	}
+	if !decoding.IsZero() {
+		if config.Options == nil {
+			config.Options = &StreamOptions{}
+		}
+		config.Options.setDecoding(decoding)
+	}
+	return config, nil
+}
+
+// parseEndpointFields 读取查询字符串中的端点检测选项
+func parseEndpointFields(field func(string) string, opts *transcribe.DecodingOptions) error {
+	if v := field("enable_endpoint"); v != "" {
+		enable, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("无效的 enable_endpoint 参数: %s", v)
+		}
+		opts.EnableEndpoint = &enable
+	}
+
+	rules := []struct {
+		name  string
+		value *float32
+	}{
+		{"rule1_min_trailing_silence", &opts.Endpoint.Rule1MinTrailingSilence},
+		{"rule2_min_trailing_silence", &opts.Endpoint.Rule2MinTrailingSilence},
+		{"rule3_min_utterance_length", &opts.Endpoint.Rule3MinUtteranceLength},
+	}
+	for _, rule := range rules {
+		if v := field(rule.name); v != "" {
+			seconds, err := strconv.ParseFloat(v, 32)
+			if err != nil {
+				return fmt.Errorf("无效的 %s 参数: %s", rule.name, v)
+			}
+			*rule.value = float32(seconds)
+		}
+	}
+	return opts.Validate()
+}
+
+// sendEvent 发送一个事件并立即刷新，使客户端在上传过程中就能收到结果
+func (s *Server) sendEvent(c *gin.Context, name string, event interface{}) {
+	c.SSEvent(name, event)
+	c.Writer.Flush()
+}
+
+// sendStreamError 发送 error 事件，之后发送 closed 结束响应
+// 与 WebSocket 不同，请求体出错后无法继续读取，所有错误都会结束会话
+func (s *Server) sendStreamError(c *gin.Context, err error) {
+	perr, ok := err.(*protocolError)
+	if !ok {
+		perr = newProtocolError(ErrorInternal, "%v", err)
+	}
+
+	s.logger.Errorf("流式转录失败: %v", perr)
+	s.sendEvent(c, EventError, ErrorEvent{
+		Type:    EventError,
+		Code:    perr.code,
+		Message: perr.message,
+	})
+	s.sendEvent(c, EventClosed, ClosedEvent{Type: EventClosed, Reason: perr.code})
+}
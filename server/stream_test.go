@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// sseEvent 一个 Server-Sent Events 事件
+type sseEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// readSSE 读取下一个事件
+func readSSE(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取事件失败: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event.name != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "event:"):
+			event.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event.data); err != nil {
+				t.Fatalf("无法解析事件数据 %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func streamTestServer(t *testing.T, fake *transcribe.FakeTranscriber) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	srv := NewServer(fake)
+	server := httptest.NewServer(srv.router)
+	t.Cleanup(func() {
+		server.Close()
+		srv.Close()
+	})
+	return server
+}
+
+func TestStreamTranscribe(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	server := streamTestServer(t, fake)
+
+	body, upload := io.Pipe()
+	resp, err := http.Post(server.URL+"/transcribe/stream?format=pcm&sample_rate=8000", "application/octet-stream", body)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("期望事件流，得到 %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := bufio.NewReader(resp.Body)
+	if event := readSSE(t, events); event.name != EventReady || event.data["sample_rate"] != float64(8000) {
+		t.Fatalf("期望 ready 事件，得到 %+v", event)
+	}
+
+	// 上传还没有结束时就能收到识别结果
+	upload.Write(pcm16(800, 1000))
+	if event := readSSE(t, events); event.name != EventPartial || event.data["text"] != "你好" {
+		t.Fatalf("期望 partial 事件，得到 %+v", event)
+	}
+	upload.Write(pcm16(800, 0))
+	if event := readSSE(t, events); event.name != EventFinal || event.data["utterance"] != float64(0) {
+		t.Fatalf("期望 final 事件，得到 %+v", event)
+	}
+
+	// 请求体结束后返回剩余的结果并结束响应
+	upload.Write(pcm16(800, 1000))
+	upload.Close()
+	if event := readSSE(t, events); event.name != EventPartial {
+		t.Fatalf("期望 partial 事件，得到 %+v", event)
+	}
+	if event := readSSE(t, events); event.name != EventFinal || event.data["utterance"] != float64(1) {
+		t.Fatalf("期望 final 事件，得到 %+v", event)
+	}
+	if event := readSSE(t, events); event.name != EventClosed || event.data["reason"] != "stop" {
+		t.Fatalf("期望 closed 事件，得到 %+v", event)
+	}
+	if rest, _ := io.ReadAll(events); len(rest) != 0 {
+		t.Errorf("closed 之后不应该有数据: %q", rest)
+	}
+
+	sessions := fake.Sessions()
+	if len(sessions) != 1 || !sessions[0].Closed() {
+		t.Fatalf("识别会话应该被关闭")
+	}
+	// 8kHz 的 2400 个采样重采样到 16kHz
+	if n := len(sessions[0].Samples()); n < 4700 || n > 4800 {
+		t.Errorf("期望收到约 4800 个采样，实际 %d 个", n)
+	}
+}
+
+func TestStreamTranscribeTimestamps(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	fake.Tokens = []transcribe.Token{{Text: "你", Start: 0, End: 0.05}, {Text: "好", Start: 0.05, End: 0.1}}
+	server := streamTestServer(t, fake)
+
+	resp, err := http.Post(server.URL+"/transcribe/stream?format=pcm&partial_results=false&timestamps=true", "application/octet-stream", bytes.NewReader(pcm16(1600, 1000)))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewReader(resp.Body)
+	readSSE(t, events)
+	event := readSSE(t, events)
+	words, _ := event.data["words"].([]interface{})
+	tokens, _ := event.data["tokens"].([]interface{})
+	if event.name != EventFinal || len(words) != 2 || len(tokens) != 2 {
+		t.Fatalf("期望带时间戳的 final 事件，得到 %+v", event)
+	}
+
+	// 模型不提供时间戳时返回 error 事件并结束
+	server = streamTestServer(t, transcribe.NewFakeTranscriber("你好"))
+	resp, err = http.Post(server.URL+"/transcribe/stream?format=pcm&timestamps=true", "application/octet-stream", bytes.NewReader(pcm16(1600, 1000)))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	events = bufio.NewReader(resp.Body)
+	readSSE(t, events)
+	if event := readSSE(t, events); event.name != EventError || event.data["code"] != ErrorTimestampsUnavailable {
+		t.Fatalf("期望 timestamps_unavailable 错误，得到 %+v", event)
+	}
+	if event := readSSE(t, events); event.name != EventClosed {
+		t.Fatalf("期望 closed 事件，得到 %+v", event)
+	}
+}
+
+func TestStreamTranscribeWAV(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	server := streamTestServer(t, fake)
+
+	// 不指定格式时根据文件头识别，只发送 final
+	resp, err := http.Post(server.URL+"/transcribe/stream?partial_results=false", "audio/wav", bytes.NewReader(testWAV(16000, 1600)))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewReader(resp.Body)
+	if event := readSSE(t, events); event.name != EventReady || event.data["format"] != "wav" {
+		t.Fatalf("期望 ready 事件，得到 %+v", event)
+	}
+	if event := readSSE(t, events); event.name != EventClosed {
+		t.Fatalf("静音音频期望直接 closed，得到 %+v", event)
+	}
+	if n := len(fake.Sessions()[0].Samples()); n != 1600 {
+		t.Errorf("期望收到 1600 个采样，实际 %d 个", n)
+	}
+}
+
+func TestStreamTranscribeSniffPCM(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"采样 -1 开始", []byte{0xff, 0xff, 0x00, 0x00}},
+		{"像 MP3 帧头", []byte{0xff, 0xfb, 0x90, 0xc0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := transcribe.NewFakeTranscriber("你好")
+			server := streamTestServer(t, fake)
+
+			// 没有指定格式的原始 PCM 不能被误判为 mp3
+			body := append(append([]byte{}, tt.header...), pcm16(1598, 0)...)
+			resp, err := http.Post(server.URL+"/transcribe/stream", "application/octet-stream", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			defer resp.Body.Close()
+
+			events := bufio.NewReader(resp.Body)
+			if event := readSSE(t, events); event.name != EventReady || event.data["format"] != "pcm" {
+				t.Fatalf("期望按 pcm 处理，得到 %+v", event)
+			}
+			io.Copy(io.Discard, events)
+			if n := len(fake.Sessions()[0].Samples()); n != 1600 {
+				t.Errorf("期望收到 1600 个采样，实际 %d 个", n)
+			}
+		})
+	}
+}
+
+func TestStreamTranscribeErrors(t *testing.T) {
+	server := streamTestServer(t, transcribe.NewFakeTranscriber("你好"))
+
+	tests := []struct {
+		name     string
+		query    string
+		body     []byte
+		expected int
+	}{
+		{"无效的采样率", "?sample_rate=abc", pcm16(160, 0), http.StatusBadRequest},
+		{"无效的 partial_results", "?partial_results=maybe", pcm16(160, 0), http.StatusBadRequest},
+		{"不支持的格式", "?format=aac", pcm16(160, 0), http.StatusUnsupportedMediaType},
+		{"MPEG Layer II", "", testMP2(), http.StatusUnsupportedMediaType},
+		{"MPEG Layer II 声明为 MP3", "?format=mp3", testMP2(), http.StatusUnsupportedMediaType},
+		{"无效的 WAV 文件头", "?format=wav", []byte("RIFF\x00\x00\x00\x00WAVE"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL+"/transcribe/stream"+tt.query, "application/octet-stream", bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expected {
+				t.Errorf("期望状态码 %d，得到 %d", tt.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestStreamTranscribeDecodingOptions(t *testing.T) {
+	fake := transcribe.NewFakeTranscriber("你好")
+	server := streamTestServer(t, fake)
+
+	resp, err := http.Post(server.URL+"/transcribe/stream?format=pcm&hotwords=语音识别,转录&hotwords_score=2&enable_endpoint=false&rule1_min_trailing_silence=1.5", "application/octet-stream", bytes.NewReader(pcm16(1600, 1000)))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	sessions := fake.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("期望 1 个会话，实际 %d 个", len(sessions))
+	}
+	opts := sessions[0].Options()
+	if len(opts.Hotwords) != 2 || opts.HotwordsScore != 2 {
+		t.Errorf("会话的热词错误: %+v", opts)
+	}
+	if opts.EnableEndpoint == nil || *opts.EnableEndpoint || opts.Endpoint.Rule1MinTrailingSilence != 1.5 {
+		t.Errorf("会话的端点检测选项错误: %+v", opts)
+	}
+
+	for _, query := range []string{"hotwords_score=-1", "enable_endpoint=maybe", "rule3_min_utterance_length=100"} {
+		resp, err := http.Post(server.URL+"/transcribe/stream?format=pcm&"+query, "application/octet-stream", bytes.NewReader(pcm16(160, 0)))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: 期望状态码 %d，得到 %d", query, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+}
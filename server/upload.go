@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// maxFormFieldSize multipart 表单中单个字段的大小上限
+const maxFormFieldSize = 1 << 20
+
+var (
+	// errMissingAudio 表单中没有 audio 文件
+	errMissingAudio = errors.New("缺少 audio 文件")
+	// errFieldAfterAudio 音频之后还有表单字段，这时音频已经开始处理，字段无法生效
+	errFieldAfterAudio = errors.New("表单字段必须放在 audio 文件之前")
+)
+
+// multipartUpload 边读边处理的 multipart/form-data 上传
+// audio 之前的字段读入内存，audio 文件直接交给解码器或任务存储，不会整个缓存在内存或临时文件中
+type multipartUpload struct {
+	reader *multipart.Reader
+	fields map[string]string
+	// audio 音频文件，读取时直接从请求体中读
+	audio *multipart.Part
+
+	finished bool
+	err      error
+}
+
+// readMultipartUpload 读取 audio 之前的表单字段，返回时请求体停在音频数据的开头
+func readMultipartUpload(r *http.Request) (*multipartUpload, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &multipartUpload{reader: reader, fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errMissingAudio
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "audio" {
+			upload.audio = part
+			return upload, nil
+		}
+
+		name := part.FormName()
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(value) > maxFormFieldSize {
+			return nil, fmt.Errorf("表单字段 %s 超过 %d 字节", name, maxFormFieldSize)
+		}
+		// 与 PostForm 相同，同名字段取第一个
+		if _, ok := upload.fields[name]; !ok && name != "" {
+			upload.fields[name] = string(value)
+		}
+	}
+}
+
+// field 返回 audio 之前的表单字段，不存在时返回空字符串
+func (u *multipartUpload) field(name string) string {
+	return u.fields[name]
+}
+
+// finish 在音频读取完后检查剩余的部分，audio 之后还有表单字段时返回 errFieldAfterAudio
+// 可以重复调用，之后的调用返回第一次的结果
+func (u *multipartUpload) finish() error {
+	if u.finished {
+		return u.err
+	}
+	u.finished = true
+
+	for {
+		part, err := u.reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			u.err = err
+			return err
+		}
+		if part.FormName() != "" {
+			u.err = errFieldAfterAudio
+			return u.err
+		}
+	}
+}
+
+// audioReader 返回音频文件的读取器，读到文件末尾时检查之后的部分
+// 检查失败时返回检查的错误而不是 io.EOF，保存音频的一方不会把请求当作成功
+func (u *multipartUpload) audioReader() io.Reader {
+	return &uploadAudioReader{upload: u}
+}
+
+type uploadAudioReader struct {
+	upload *multipartUpload
+}
+
+func (r *uploadAudioReader) Read(p []byte) (int, error) {
+	n, err := r.upload.audio.Read(p)
+	if err == io.EOF {
+		if finishErr := r.upload.finish(); finishErr != nil {
+			return n, finishErr
+		}
+	}
+	return n, err
+}
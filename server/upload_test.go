@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/layzdonw/transerver/transcribe"
+)
+
+// trailingFieldRequest 构造 mode 字段放在音频之后的 multipart 请求
+func trailingFieldRequest(t *testing.T, path string) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("format", "pcm")
+	part, err := writer.CreateFormFile("audio", "audio.pcm")
+	if err != nil {
+		t.Fatalf("创建表单文件失败: %v", err)
+	}
+	part.Write(make([]byte, 3200))
+	writer.WriteField("mode", "offline")
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", path, &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestReadMultipartUpload(t *testing.T) {
+	// 请求体还没有传完时就可以开始读取音频
+	body, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	req, _ := http.NewRequest("POST", "/transcribe", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	// more 读到音频开头之后才发送剩余的音频
+	more := make(chan struct{}, 1)
+	go func() {
+		writer.WriteField("format", "pcm")
+		writer.WriteField("format", "wav")
+		part, _ := writer.CreateFormFile("audio", "audio.pcm")
+		part.Write([]byte("音频的开头"))
+		<-more
+		part.Write([]byte("和结尾"))
+		writer.Close()
+		pipe.Close()
+	}()
+
+	upload, err := readMultipartUpload(req)
+	if err != nil {
+		t.Fatalf("读取上传失败: %v", err)
+	}
+	if upload.field("format") != "pcm" || upload.field("mode") != "" {
+		t.Errorf("表单字段错误: %v", upload.fields)
+	}
+	if upload.audio.FileName() != "audio.pcm" {
+		t.Errorf("文件名错误: %s", upload.audio.FileName())
+	}
+
+	more <- struct{}{}
+	data, err := io.ReadAll(upload.audioReader())
+	if err != nil || string(data) != "音频的开头和结尾" {
+		t.Errorf("音频内容错误: %q, %v", data, err)
+	}
+}
+
+func TestReadMultipartUploadErrors(t *testing.T) {
+	// 没有音频文件
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("format", "pcm")
+	writer.Close()
+	req, _ := http.NewRequest("POST", "/transcribe", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if _, err := readMultipartUpload(req); !errors.Is(err, errMissingAudio) {
+		t.Errorf("没有音频时期望 errMissingAudio，得到 %v", err)
+	}
+
+	// 过大的表单字段
+	buf.Reset()
+	writer = multipart.NewWriter(&buf)
+	writer.WriteField("hotwords", strings.Repeat("词", maxFormFieldSize))
+	writer.Close()
+	req, _ = http.NewRequest("POST", "/transcribe", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if _, err := readMultipartUpload(req); err == nil {
+		t.Error("过大的表单字段应该返回错误")
+	}
+
+	// 音频之后的字段在读完音频时报告
+	upload, err := readMultipartUpload(trailingFieldRequest(t, "/transcribe"))
+	if err != nil {
+		t.Fatalf("读取上传失败: %v", err)
+	}
+	if _, err := io.ReadAll(upload.audioReader()); !errors.Is(err, errFieldAfterAudio) {
+		t.Errorf("音频之后有字段时期望 errFieldAfterAudio，得到 %v", err)
+	}
+	if err := upload.finish(); !errors.Is(err, errFieldAfterAudio) {
+		t.Errorf("重复检查应该返回同样的错误，得到 %v", err)
+	}
+}
+
+func TestUploadFieldAfterAudio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	NewServer(transcribe.NewFakeTranscriber("你好")).router.ServeHTTP(w, trailingFieldRequest(t, "/transcribe"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("/transcribe 期望状态码 %d，得到 %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	srv := jobTestServer(t, transcribe.NewFakeTranscriber("你好"))
+	w, _ = doJobRequest(t, srv, trailingFieldRequest(t, "/jobs"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("/jobs 期望状态码 %d，得到 %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package transcribe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DecodeAudio 按格式把音频数据解码为单声道 float32
+// 压缩格式和 WAV 的采样率以文件头为准，PCM 使用 sampleRate 指定的采样率；
+// format 为空时根据文件头自动识别
+func DecodeAudio(audioData []byte, format string, sampleRate int) (*Audio, error) {
+	format = NormalizeFormat(format)
+	if format == "" {
+		format = SniffFormat(audioData)
+		if format == "" {
+			return nil, fmt.Errorf("无法识别音频格式")
+		}
+	}
+
+	if IsRawFormat(format) {
+		if sampleRate <= 0 {
+			return nil, fmt.Errorf("PCM 音频需要指定采样率")
+		}
+		samples := DecodePCM16(audioData)
+		if format == "f32" {
+			samples = DecodeFloat32(audioData)
+		}
+		return &Audio{
+			Samples:    samples,
+			SampleRate: sampleRate,
+		}, nil
+	}
+
+	decoder, ok := lookupDecoder(format)
+	if !ok {
+		return nil, &UnsupportedFormatError{Format: format, Reason: "没有可用的解码器"}
+	}
+	return decoder(audioData)
+}
+
+// DecodePCM16 解码 16 位小端单声道 PCM 数据
+func DecodePCM16(data []byte) []float32 {
+	samples := make([]float32, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		sample := int16(binary.LittleEndian.Uint16(data[i : i+2]))
+		samples = append(samples, float32(sample)/32768.0)
+	}
+	return samples
+}
+
+// DecodeFloat32 解码 32 位小端浮点单声道 PCM 数据
+func DecodeFloat32(data []byte) []float32 {
+	samples := make([]float32, 0, len(data)/4)
+	for i := 0; i+3 < len(data); i += 4 {
+		samples = append(samples, math.Float32frombits(binary.LittleEndian.Uint32(data[i:i+4])))
+	}
+	return samples
+}
+
+// Resample 返回转换到指定采样率的音频，采样率相同时直接返回自身
+func (a *Audio) Resample(sampleRate int) (*Audio, error) {
+	if a.SampleRate == sampleRate {
+		return a, nil
+	}
+
+	samples, err := Resample(a.Samples, a.SampleRate, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	return &Audio{
+		Samples:    samples,
+		SampleRate: sampleRate,
+	}, nil
+}
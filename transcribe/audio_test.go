@@ -0,0 +1,62 @@
+package transcribe
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestDecodeAudioPCM(t *testing.T) {
+	audio, err := DecodeAudio([]byte{0x00, 0x40, 0x00, 0xc0}, "PCM", 8000)
+	if err != nil {
+		t.Fatalf("解码 PCM 失败: %v", err)
+	}
+	if audio.SampleRate != 8000 {
+		t.Errorf("采样率错误，期望: 8000, 实际: %d", audio.SampleRate)
+	}
+	assertSamples(t, audio.Samples, 0.5, -0.5)
+
+	if _, err := DecodeAudio([]byte{0, 0}, "pcm", 0); err == nil {
+		t.Error("PCM 未指定采样率时期望返回错误")
+	}
+	if _, err := DecodeAudio([]byte{0, 0}, "aac", 16000); err == nil {
+		t.Error("不支持的格式期望返回错误")
+	}
+}
+
+func TestDecodeAudioFloat32(t *testing.T) {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data, math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(data[4:], math.Float32bits(-0.25))
+
+	audio, err := DecodeAudio(data, "f32le", 16000)
+	if err != nil {
+		t.Fatalf("解码 f32 失败: %v", err)
+	}
+	assertSamples(t, audio.Samples, 0.5, -0.25)
+
+	// 不完整的采样被丢弃
+	if samples := DecodeFloat32(data[:6]); len(samples) != 1 {
+		t.Errorf("期望 1 个采样，实际 %d 个", len(samples))
+	}
+}
+
+func TestAudioResample(t *testing.T) {
+	audio := &Audio{Samples: make([]float32, 8000), SampleRate: 8000}
+
+	same, err := audio.Resample(8000)
+	if err != nil || same != audio {
+		t.Errorf("采样率相同时应返回自身")
+	}
+
+	resampled, err := audio.Resample(16000)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+	if resampled.SampleRate != 16000 || len(resampled.Samples) != 16000 {
+		t.Errorf("重采样结果错误，采样率: %d, 采样数: %d", resampled.SampleRate, len(resampled.Samples))
+	}
+	if resampled.Duration() != audio.Duration() {
+		t.Errorf("重采样后时长应保持不变，期望: %f, 实际: %f", audio.Duration(), resampled.Duration())
+	}
+}
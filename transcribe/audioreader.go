@@ -0,0 +1,369 @@
+package transcribe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/mewkiz/flac"
+)
+
+// readBlockSize 增量解码时每次从输入读取的字节数
+const readBlockSize = 64 << 10
+
+// AudioReader 增量解码的单声道音频，采样值范围 [-1, 1]
+type AudioReader interface {
+	// SampleRate 返回音频的采样率
+	SampleRate() int
+	// ReadSamples 读取最多 len(buf) 个采样，音频结束时返回 io.EOF
+	ReadSamples(buf []float32) (int, error)
+}
+
+// NewAudioReader 创建从 r 增量解码音频的读取器，参数含义与 DecodeAudio 相同
+// wav、flac、mp3 和原始 PCM 边读边解码；ogg 和通过 RegisterDecoder 注册的格式需要先读入整个文件
+func NewAudioReader(r io.Reader, format string, sampleRate int) (AudioReader, error) {
+	br := bufio.NewReaderSize(r, readBlockSize)
+
+	format = NormalizeFormat(format)
+	if format == "" {
+		header, _ := br.Peek(SniffSize)
+		format = SniffFormat(header)
+		if format == "" {
+			return nil, fmt.Errorf("无法识别音频格式")
+		}
+	}
+
+	switch {
+	case IsRawFormat(format):
+		if sampleRate <= 0 {
+			return nil, fmt.Errorf("PCM 音频需要指定采样率")
+		}
+		return newRawReader(br, format, sampleRate), nil
+	case format == "wav":
+		return newWAVReader(br)
+	case format == "flac":
+		return newFLACReader(br)
+	case format == "mp3":
+		header, _ := br.Peek(SniffSize)
+		if err := checkMPEGLayer(header); err != nil {
+			return nil, err
+		}
+		return newMP3Reader(br)
+	}
+
+	decoder, ok := lookupDecoder(format)
+	if !ok {
+		return nil, &UnsupportedFormatError{Format: format, Reason: "没有可用的解码器"}
+	}
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	audio, err := decoder(data)
+	if err != nil {
+		return nil, err
+	}
+	return newSliceReader(audio), nil
+}
+
+// blockReader 把逐块产生采样的解码器包装为 AudioReader
+type blockReader struct {
+	sampleRate int
+	// next 返回下一块采样，最后一块可以和 io.EOF 一起返回
+	next    func() ([]float32, error)
+	pending []float32
+	err     error
+}
+
+func (b *blockReader) SampleRate() int {
+	return b.sampleRate
+}
+
+func (b *blockReader) ReadSamples(buf []float32) (int, error) {
+	for len(b.pending) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		b.pending, b.err = b.next()
+	}
+	n := copy(buf, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func newSliceReader(audio *Audio) *blockReader {
+	return &blockReader{
+		sampleRate: audio.SampleRate,
+		pending:    audio.Samples,
+		err:        io.EOF,
+	}
+}
+
+// readBlock 读取最多 len(buf) 字节，返回的长度是 size 的整数倍，输入结束时返回 io.EOF
+func readBlock(r io.Reader, buf []byte, size int) ([]byte, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return buf[:n-n%size], err
+}
+
+// alignedReader 按采样对齐读取数据，有多少返回多少，不等缓冲区填满，
+// 适合边上传边识别的输入；不足一个采样的字节留到下一次读取
+type alignedReader struct {
+	r    io.Reader
+	buf  []byte
+	size int
+	// aligned 和 filled 上一次读取后 buf 中对齐部分的长度和全部数据的长度
+	aligned int
+	filled  int
+}
+
+func newAlignedReader(r io.Reader, size int) *alignedReader {
+	return &alignedReader{r: r, buf: make([]byte, readBlockSize/size*size), size: size}
+}
+
+// next 读取至少一个采样，输入结束时返回 io.EOF，结尾不完整的采样被丢弃
+func (a *alignedReader) next() ([]byte, error) {
+	tail := copy(a.buf, a.buf[a.aligned:a.filled])
+	n, err := io.ReadAtLeast(a.r, a.buf[tail:], a.size-tail)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	a.filled = tail + n
+	a.aligned = a.filled - a.filled%a.size
+	return a.buf[:a.aligned], err
+}
+
+func newRawReader(r io.Reader, format string, sampleRate int) *blockReader {
+	decode := DecodePCM16
+	if format == "f32" {
+		decode = DecodeFloat32
+	}
+
+	aligned := newAlignedReader(r, SampleSize(format))
+	return &blockReader{
+		sampleRate: sampleRate,
+		next: func() ([]float32, error) {
+			block, err := aligned.next()
+			return decode(block), err
+		},
+	}
+}
+
+// newWAVReader 读取 WAV 头直到 data 块，之后按块解码
+// data 块之后的块会被忽略；data 块大小为 0 或超出文件时读到输入结束
+func newWAVReader(r io.Reader) (*blockReader, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("音频数据太短，不是有效的 WAV 文件")
+	}
+	if string(header[0:4]) != "RIFF" {
+		if string(header[0:4]) == "RIFX" {
+			return nil, &UnsupportedFormatError{Format: "wav", Reason: "不支持大端序 RIFX 文件"}
+		}
+		return nil, fmt.Errorf("缺少 RIFF 头，不是有效的 WAV 文件")
+	}
+	if string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("RIFF 类型不是 WAVE")
+	}
+
+	var format *wavFormat
+	var dataSize int64
+	for {
+		chunk := make([]byte, 8)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			if format == nil {
+				return nil, fmt.Errorf("WAV 文件缺少 fmt 块")
+			}
+			return nil, fmt.Errorf("WAV 文件缺少 data 块")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		if id == "data" {
+			dataSize = size
+			break
+		}
+		if id == "fmt " {
+			if size > 1024 {
+				return nil, fmt.Errorf("WAV fmt 块长度无效: %d", size)
+			}
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("WAV fmt 块不完整: %v", err)
+			}
+			f, err := parseWavFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			format = f
+			size = 0
+		}
+		// 跳过块内容，块大小为奇数时后面有一个填充字节
+		if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+			return nil, fmt.Errorf("WAV 文件不完整: %v", err)
+		}
+	}
+	if format == nil {
+		return nil, fmt.Errorf("WAV 文件缺少 fmt 块")
+	}
+
+	data := r
+	if dataSize > 0 {
+		data = io.LimitReader(r, dataSize)
+	}
+	aligned := newAlignedReader(data, format.blockAlign)
+	return &blockReader{
+		sampleRate: format.sampleRate,
+		next: func() ([]float32, error) {
+			block, err := aligned.next()
+			samples, decodeErr := decodeWavSamples(format, block)
+			if decodeErr != nil {
+				return nil, decodeErr
+			}
+			return samples, err
+		},
+	}, nil
+}
+
+// newFLACReader 逐帧解码 FLAC，多声道会被平均混合为单声道
+func newFLACReader(r io.Reader) (*blockReader, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return nil, fmt.Errorf("解析 FLAC 文件失败: %v", err)
+	}
+
+	info := stream.Info
+	if info.NChannels == 0 || info.SampleRate == 0 {
+		return nil, fmt.Errorf("FLAC 文件的 STREAMINFO 无效")
+	}
+	if info.BitsPerSample == 0 || info.BitsPerSample > 32 {
+		return nil, &UnsupportedFormatError{Format: "flac", Reason: fmt.Sprintf("不支持 %d 位采样", info.BitsPerSample)}
+	}
+
+	scale := 1 / float64(uint64(1)<<(info.BitsPerSample-1))
+	return &blockReader{
+		sampleRate: int(info.SampleRate),
+		next: func() ([]float32, error) {
+			frame, err := stream.ParseNext()
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, fmt.Errorf("解码 FLAC 帧失败: %v", err)
+			}
+
+			channels := len(frame.Subframes)
+			if channels == 0 {
+				return nil, nil
+			}
+			samples := make([]float32, frame.BlockSize)
+			for i := range samples {
+				var sum float64
+				for _, subframe := range frame.Subframes {
+					sum += float64(subframe.Samples[i])
+				}
+				samples[i] = float32(sum * scale / float64(channels))
+			}
+			return samples, nil
+		},
+	}, nil
+}
+
+// newMP3Reader 增量解码 MP3
+// go-mp3 固定输出 16 位双声道数据，这里混合为单声道
+func newMP3Reader(r io.Reader) (*blockReader, error) {
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("解析 MP3 文件失败: %v", err)
+	}
+
+	buf := make([]byte, readBlockSize)
+	return &blockReader{
+		sampleRate: decoder.SampleRate(),
+		next: func() ([]float32, error) {
+			// 每帧 4 字节：左右声道各一个 16 位采样
+			block, err := readBlock(decoder, buf, 4)
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("解码 MP3 数据失败: %v", err)
+			}
+			samples := make([]float32, len(block)/4)
+			for i := range samples {
+				left := int16(binary.LittleEndian.Uint16(block[i*4:]))
+				right := int16(binary.LittleEndian.Uint16(block[i*4+2:]))
+				samples[i] = (float32(left) + float32(right)) / 65536
+			}
+			return samples, err
+		},
+	}, nil
+}
+
+// ReadAllSamples 读取全部采样
+func ReadAllSamples(r AudioReader) (*Audio, error) {
+	audio := &Audio{SampleRate: r.SampleRate()}
+	buf := make([]float32, readBlockSize)
+	for {
+		n, err := r.ReadSamples(buf)
+		audio.Samples = append(audio.Samples, buf[:n]...)
+		if errors.Is(err, io.EOF) {
+			return audio, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// resampledReader 把 AudioReader 的输出转换到目标采样率
+type resampledReader struct {
+	reader    AudioReader
+	resampler *Resampler
+	rate      int
+	buf       []float32
+	pending   []float32
+	done      bool
+}
+
+// NewResampledReader 返回输出 sampleRate 采样率的 AudioReader，采样率相同时直接返回 r
+func NewResampledReader(r AudioReader, sampleRate int) (AudioReader, error) {
+	if r.SampleRate() == sampleRate {
+		return r, nil
+	}
+	resampler, err := NewResampler(r.SampleRate(), sampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("重采样失败: %v", err)
+	}
+	return &resampledReader{
+		reader:    r,
+		resampler: resampler,
+		rate:      sampleRate,
+		buf:       make([]float32, readBlockSize),
+	}, nil
+}
+
+func (r *resampledReader) SampleRate() int {
+	return r.rate
+}
+
+func (r *resampledReader) ReadSamples(buf []float32) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := r.reader.ReadSamples(r.buf)
+		r.pending = r.resampler.Process(r.buf[:n])
+		if errors.Is(err, io.EOF) {
+			r.pending = append(r.pending, r.resampler.Flush()...)
+			r.done = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(buf, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
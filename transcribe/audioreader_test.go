@@ -0,0 +1,124 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+// readInChunks 每次读取 size 个采样，检查读取器能被任意切分地读取
+func readInChunks(t *testing.T, r AudioReader, size int) []float32 {
+	t.Helper()
+
+	var samples []float32
+	buf := make([]float32, size)
+	for {
+		n, err := r.ReadSamples(buf)
+		samples = append(samples, buf[:n]...)
+		if errors.Is(err, io.EOF) {
+			return samples
+		}
+		if err != nil {
+			t.Fatalf("读取采样失败: %v", err)
+		}
+	}
+}
+
+func TestAudioReaderMatchesDecodeAudio(t *testing.T) {
+	values := make([]int16, 20000)
+	for i := range values {
+		values[i] = int16(10000 * math.Sin(float64(i)/7))
+	}
+	var pcm bytes.Buffer
+	binary.Write(&pcm, binary.LittleEndian, values)
+	var f32 bytes.Buffer
+	binary.Write(&f32, binary.LittleEndian, DecodePCM16(pcm.Bytes()))
+	flacSamples := make([]int32, 4000)
+	for i := range flacSamples {
+		flacSamples[i] = int32(values[i])
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{"wav", buildRIFF(fmtChunk(wavFormatPCM, 2, 8000, 16), pcm16Chunk(values...), wavChunk{id: "LIST", body: []byte("INFO")}), ""},
+		{"pcm", pcm.Bytes(), "pcm"},
+		{"f32", f32.Bytes(), "f32"},
+		{"flac", encodeFLAC(t, 16000, 16, flacSamples), ""},
+		{"mp3", silentMP3(20), "mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := DecodeAudio(tt.data, tt.format, 16000)
+			if err != nil {
+				t.Fatalf("解码音频失败: %v", err)
+			}
+
+			// 每次只读一个字节，模拟网络上传时零碎到达的数据
+			reader, err := NewAudioReader(iotest.OneByteReader(bytes.NewReader(tt.data)), tt.format, 16000)
+			if err != nil {
+				t.Fatalf("创建读取器失败: %v", err)
+			}
+			if reader.SampleRate() != expected.SampleRate {
+				t.Errorf("采样率错误，期望: %d, 实际: %d", expected.SampleRate, reader.SampleRate())
+			}
+			assertSamples(t, readInChunks(t, reader, 333), expected.Samples...)
+		})
+	}
+}
+
+func TestAudioReaderWAVUnknownDataSize(t *testing.T) {
+	// 边录边写的 WAV 文件 data 块大小未知，读到输入结束
+	data := buildRIFF(fmtChunk(wavFormatPCM, 1, 8000, 16), pcm16Chunk(0, 16384, -32768))
+	binary.LittleEndian.PutUint32(data[len(data)-10:], 0xFFFFFFFF)
+
+	reader, err := NewAudioReader(bytes.NewReader(data), "wav", 0)
+	if err != nil {
+		t.Fatalf("创建读取器失败: %v", err)
+	}
+	assertSamples(t, readInChunks(t, reader, 2), 0, 0.5, -1)
+}
+
+func TestAudioReaderInvalid(t *testing.T) {
+	if _, err := NewAudioReader(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVE")), "wav", 0); err == nil {
+		t.Error("缺少 fmt 块的 WAV 期望返回错误")
+	}
+	if _, err := NewAudioReader(bytes.NewReader([]byte("hello world")), "", 0); err == nil {
+		t.Error("无法识别的格式期望返回错误")
+	}
+	if _, err := NewAudioReader(bytes.NewReader(nil), "pcm", 0); err == nil {
+		t.Error("PCM 没有采样率期望返回错误")
+	}
+}
+
+func TestResampledReader(t *testing.T) {
+	input := sineWave(700, 44100, 20000)
+	expected, err := Resample(input, 44100, 16000)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+
+	reader, err := NewResampledReader(newSliceReader(&Audio{Samples: input, SampleRate: 44100}), 16000)
+	if err != nil {
+		t.Fatalf("创建重采样读取器失败: %v", err)
+	}
+	if reader.SampleRate() != 16000 {
+		t.Errorf("采样率错误，期望: 16000, 实际: %d", reader.SampleRate())
+	}
+	got := readInChunks(t, reader, 1000)
+	if len(got) != len(expected) {
+		t.Fatalf("输出长度错误，期望: %d, 实际: %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if math.Abs(float64(got[i]-expected[i])) > 1e-6 {
+			t.Fatalf("采样 %d 不一致，期望: %f, 实际: %f", i, expected[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package transcribe
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+const (
+	// maxDecodeBatch 一次 DecodeStreams 最多解码的流数量
+	maxDecodeBatch = 32
+	// decodeQueueSize 等待送入解码循环的请求数量
+	decodeQueueSize = 256
+	// flushPadding Flush 时补充的静音时长（秒），保证最后几帧也能被解码
+	flushPadding = 0.3
+)
+
+// errDecodeLoopClosed 解码循环已停止
+var errDecodeLoopClosed = errors.New("识别器已关闭")
+
+// onlineStream 解码循环中的一个流
+type onlineStream interface {
+	AcceptWaveform(sampleRate int, samples []float32)
+}
+
+// streamRecognizer 解码循环使用的流式识别器，便于在测试中替换
+type streamRecognizer interface {
+	IsReady(stream onlineStream) bool
+	DecodeStreams(streams []onlineStream)
+	IsEndpoint(stream onlineStream) bool
+	Reset(stream onlineStream)
+	// Result 返回流当前句子的识别结果，duration 为句子已经收到的音频时长（秒），
+	// token 的时间相对于句子的开始
+	Result(stream onlineStream, duration float64) recognition
+}
+
+// sherpaStreamRecognizer 把 sherpa-onnx 的 OnlineRecognizer 适配为 streamRecognizer
+type sherpaStreamRecognizer struct {
+	recognizer *sherpa_onnx.OnlineRecognizer
+}
+
+func (r sherpaStreamRecognizer) IsReady(stream onlineStream) bool {
+	return r.recognizer.IsReady(stream.(*sherpa_onnx.OnlineStream))
+}
+
+func (r sherpaStreamRecognizer) DecodeStreams(streams []onlineStream) {
+	batch := make([]*sherpa_onnx.OnlineStream, len(streams))
+	for i, stream := range streams {
+		batch[i] = stream.(*sherpa_onnx.OnlineStream)
+	}
+	r.recognizer.DecodeStreams(batch)
+}
+
+func (r sherpaStreamRecognizer) IsEndpoint(stream onlineStream) bool {
+	return r.recognizer.IsEndpoint(stream.(*sherpa_onnx.OnlineStream))
+}
+
+func (r sherpaStreamRecognizer) Reset(stream onlineStream) {
+	r.recognizer.Reset(stream.(*sherpa_onnx.OnlineStream))
+}
+
+func (r sherpaStreamRecognizer) Result(stream onlineStream, duration float64) recognition {
+	s := stream.(*sherpa_onnx.OnlineStream)
+	result, err := getOnlineResult(r.recognizer, s)
+	if err != nil {
+		// 取不到 JSON 结果时退回只有文本的结果
+		return recognition{text: strings.TrimSpace(r.recognizer.GetResult(s).Text)}
+	}
+	return result.recognition(duration)
+}
+
+// streamState 一个会话在解码循环中的状态，只由解码循环读写
+type streamState struct {
+	stream onlineStream
+	// utterance 当前句子的序号
+	utterance int
+	// partial 上一次返回的部分结果
+	partial string
+	// sampleRate 送入流的采样率
+	sampleRate int
+	// received 已经收到的音频采样数，不包括 Flush 补充的静音
+	received int
+	// utteranceStart 当前句子开始时的 received
+	utteranceStart int
+}
+
+// span 返回当前句子的起止时间（秒）
+func (s *streamState) span() (start, end float64) {
+	if s.sampleRate <= 0 {
+		return 0, 0
+	}
+	rate := float64(s.sampleRate)
+	return float64(s.utteranceStart) / rate, float64(s.received) / rate
+}
+
+func newStreamState(stream onlineStream) *streamState {
+	return &streamState{stream: stream}
+}
+
+// decodeRequest 会话提交给解码循环的一段音频
+type decodeRequest struct {
+	state      *streamState
+	sampleRate int
+	samples    []float32
+	// flush 为 true 时补一段静音并结束当前句子
+	flush bool
+	// results 解码完成后返回这段音频产生的结果
+	results chan []StreamResult
+}
+
+// decodeLoop 在一个 goroutine 中为所有流式会话解码
+// 同时到达的音频先全部送入各自的流，再把就绪的流合并成一批调用 DecodeStreams，
+// 会话越多每批解码的流越多，而不是每个会话各自解码
+type decodeLoop struct {
+	recognizer streamRecognizer
+	maxBatch   int
+	requests   chan decodeRequest
+	done       chan struct{}
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+}
+
+func newDecodeLoop(recognizer streamRecognizer, maxBatch int) *decodeLoop {
+	if maxBatch <= 0 {
+		maxBatch = maxDecodeBatch
+	}
+
+	loop := &decodeLoop{
+		recognizer: recognizer,
+		maxBatch:   maxBatch,
+		requests:   make(chan decodeRequest, decodeQueueSize),
+		done:       make(chan struct{}),
+	}
+	loop.wg.Add(1)
+	go loop.run()
+	return loop
+}
+
+// submit 提交音频并等待解码完成，返回这段音频产生的结果
+func (l *decodeLoop) submit(state *streamState, sampleRate int, samples []float32, flush bool) ([]StreamResult, error) {
+	req := decodeRequest{
+		state:      state,
+		sampleRate: sampleRate,
+		samples:    samples,
+		flush:      flush,
+		results:    make(chan []StreamResult, 1),
+	}
+
+	select {
+	case l.requests <- req:
+	case <-l.done:
+		return nil, errDecodeLoopClosed
+	}
+
+	select {
+	case results := <-req.results:
+		return results, nil
+	case <-l.done:
+		return nil, errDecodeLoopClosed
+	}
+}
+
+func (l *decodeLoop) run() {
+	defer l.wg.Done()
+
+	for {
+		select {
+		case req := <-l.requests:
+			l.process(l.collect(req))
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// collect 取出已经在排队的请求，和第一个请求一起处理
+func (l *decodeLoop) collect(first decodeRequest) []decodeRequest {
+	batch := []decodeRequest{first}
+	for {
+		select {
+		case req := <-l.requests:
+			batch = append(batch, req)
+		default:
+			return batch
+		}
+	}
+}
+
+// process 把音频送入各自的流，批量解码所有就绪的流，然后返回结果
+// 每轮解码后检查端点，检测到端点的句子作为最终结果返回并重置流
+func (l *decodeLoop) process(batch []decodeRequest) {
+	var states []*streamState
+	seen := make(map[*streamState]bool, len(batch))
+	for _, req := range batch {
+		req.state.sampleRate = req.sampleRate
+		if len(req.samples) > 0 {
+			req.state.stream.AcceptWaveform(req.sampleRate, req.samples)
+			req.state.received += len(req.samples)
+		}
+		if req.flush {
+			req.state.stream.AcceptWaveform(req.sampleRate, make([]float32, int(float64(req.sampleRate)*flushPadding)))
+		}
+		if !seen[req.state] {
+			seen[req.state] = true
+			states = append(states, req.state)
+		}
+	}
+
+	results := make(map[*streamState][]StreamResult, len(states))
+	for {
+		var ready []*streamState
+		for _, state := range states {
+			if l.recognizer.IsReady(state.stream) {
+				ready = append(ready, state)
+			}
+		}
+		if len(ready) == 0 {
+			break
+		}
+
+		for start := 0; start < len(ready); start += l.maxBatch {
+			end := start + l.maxBatch
+			if end > len(ready) {
+				end = len(ready)
+			}
+			streams := make([]onlineStream, 0, end-start)
+			for _, state := range ready[start:end] {
+				streams = append(streams, state.stream)
+			}
+			l.recognizer.DecodeStreams(streams)
+		}
+
+		for _, state := range ready {
+			if l.recognizer.IsEndpoint(state.stream) {
+				results[state] = append(results[state], l.endUtterance(state)...)
+			}
+		}
+	}
+
+	for _, req := range batch {
+		state := req.state
+		if req.flush {
+			results[state] = append(results[state], l.endUtterance(state)...)
+		} else if result := l.result(state); result.Text != state.partial {
+			state.partial = result.Text
+			if result.Text != "" {
+				results[state] = append(results[state], result)
+			}
+		}
+		req.results <- results[state]
+		delete(results, state)
+	}
+}
+
+// endUtterance 结束当前句子并重置流，句子为空时不产生结果
+func (l *decodeLoop) endUtterance(state *streamState) []StreamResult {
+	result := l.result(state)
+	result.Final = true
+	l.recognizer.Reset(state.stream)
+	state.partial = ""
+	state.utteranceStart = state.received
+	if result.Text == "" {
+		return nil
+	}
+
+	state.utterance++
+	return []StreamResult{result}
+}
+
+// result 返回流当前句子的结果，token 的时间换算为会话音频中的时间
+func (l *decodeLoop) result(state *streamState) StreamResult {
+	start, end := state.span()
+	rec := l.recognizer.Result(state.stream, end-start)
+	result := StreamResult{Utterance: state.utterance, Text: strings.TrimSpace(rec.text), Start: start, End: end}
+	if len(rec.tokens) > 0 {
+		result.setTokens(offsetTokens(rec.tokens, start))
+	}
+	return result
+}
+
+// close 停止解码循环，等待正在处理的一批结束
+func (l *decodeLoop) close() {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	l.wg.Wait()
+}
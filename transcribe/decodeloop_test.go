@@ -0,0 +1,247 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeStream 每 160 个采样构成一帧，帧内有非零采样时视为语音
+type fakeStream struct {
+	frames []bool
+	// silence 句子末尾连续的静音帧数
+	silence int
+	text    strings.Builder
+	// decoded 当前句子已经解码的帧数，timestamps 为每个 a 所在帧的开始时间
+	decoded    int
+	timestamps []float32
+}
+
+func (s *fakeStream) AcceptWaveform(sampleRate int, samples []float32) {
+	for i := 0; i+160 <= len(samples); i += 160 {
+		voiced := false
+		for _, v := range samples[i : i+160] {
+			if v != 0 {
+				voiced = true
+			}
+		}
+		s.frames = append(s.frames, voiced)
+	}
+}
+
+// fakeStreamRecognizer 每次解码消耗一帧，语音帧输出一个 a，连续 2 个静音帧视为端点
+// 每个 a 是一个 token，时间戳为所在帧相对于句子开始的时间
+type fakeStreamRecognizer struct {
+	mu      sync.Mutex
+	batches []int
+	// hold 不为空时第一次解码会阻塞，直到 hold 被关闭
+	hold chan struct{}
+	// started 第一次解码开始时关闭
+	started chan struct{}
+}
+
+func (r *fakeStreamRecognizer) IsReady(stream onlineStream) bool {
+	return len(stream.(*fakeStream).frames) > 0
+}
+
+func (r *fakeStreamRecognizer) DecodeStreams(streams []onlineStream) {
+	r.mu.Lock()
+	first := len(r.batches) == 0
+	r.batches = append(r.batches, len(streams))
+	r.mu.Unlock()
+
+	if first && r.hold != nil {
+		close(r.started)
+		<-r.hold
+	}
+	for _, stream := range streams {
+		s := stream.(*fakeStream)
+		voiced := s.frames[0]
+		s.frames = s.frames[1:]
+		if voiced {
+			s.text.WriteString("a")
+			s.timestamps = append(s.timestamps, float32(s.decoded)*0.01)
+			s.silence = 0
+		} else {
+			s.silence++
+		}
+		s.decoded++
+	}
+}
+
+func (r *fakeStreamRecognizer) IsEndpoint(stream onlineStream) bool {
+	return stream.(*fakeStream).silence >= 2
+}
+
+func (r *fakeStreamRecognizer) Reset(stream onlineStream) {
+	s := stream.(*fakeStream)
+	s.text.Reset()
+	s.silence = 0
+	s.decoded = 0
+	s.timestamps = nil
+}
+
+func (r *fakeStreamRecognizer) Result(stream onlineStream, duration float64) recognition {
+	s := stream.(*fakeStream)
+	texts := make([]string, len(s.timestamps))
+	for i := range texts {
+		texts[i] = "a"
+	}
+	return recognition{text: s.text.String(), tokens: newTokens(texts, s.timestamps, nil, duration)}
+}
+
+// frames 生成指定的语音帧（1）和静音帧（0）
+func frames(pattern string) []float32 {
+	samples := make([]float32, 0, len(pattern)*160)
+	for _, c := range pattern {
+		v := float32(0)
+		if c == '1' {
+			v = 0.5
+		}
+		for i := 0; i < 160; i++ {
+			samples = append(samples, v)
+		}
+	}
+	return samples
+}
+
+// spans 把结果格式化为句子序号、文本、是否结束和起止时间，不比较 token
+func spans(results []StreamResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "{%d %s %v %g %g}", r.Utterance, r.Text, r.Final, r.Start, r.End)
+	}
+	return b.String()
+}
+
+func TestDecodeLoopEndpoint(t *testing.T) {
+	loop := newDecodeLoop(&fakeStreamRecognizer{}, 0)
+	defer loop.close()
+
+	state := newStreamState(&fakeStream{})
+
+	results, err := loop.submit(state, 16000, frames("111"), false)
+	if err != nil {
+		t.Fatalf("提交音频失败: %v", err)
+	}
+	if spans(results) != spans([]StreamResult{{Utterance: 0, Text: "aaa", Start: 0, End: 0.03}}) {
+		t.Errorf("期望部分结果 aaa，得到 %+v", results)
+	}
+
+	// 没有新的文本时不重复返回部分结果
+	results, _ = loop.submit(state, 16000, frames("0"), false)
+	if len(results) != 0 {
+		t.Errorf("文本没有变化时不应该返回结果，得到 %+v", results)
+	}
+
+	// 第二个静音帧触发端点，随后开始新的句子
+	// 端点在整段音频送入后才检测到，句子的结束时间是当时已经收到的音频长度
+	results, _ = loop.submit(state, 16000, frames("011"), false)
+	expected := []StreamResult{
+		{Utterance: 0, Text: "aaa", Final: true, Start: 0, End: 0.07},
+		{Utterance: 1, Text: "aa", Start: 0.07, End: 0.07},
+	}
+	if spans(results) != spans(expected) {
+		t.Errorf("期望 %+v，得到 %+v", expected, results)
+	}
+
+	// Flush 立即结束当前句子
+	results, _ = loop.submit(state, 16000, nil, true)
+	if spans(results) != spans([]StreamResult{{Utterance: 1, Text: "aa", Final: true, Start: 0.07, End: 0.07}}) {
+		t.Errorf("Flush 期望最终结果 aa，得到 %+v", results)
+	}
+	if state.utterance != 2 {
+		t.Errorf("期望句子序号 2，得到 %d", state.utterance)
+	}
+
+	// 只有静音的句子不产生结果，也不增加句子序号
+	results, _ = loop.submit(state, 16000, frames("000"), false)
+	if len(results) != 0 || state.utterance != 2 {
+		t.Errorf("静音不应该产生结果，得到 %+v，句子序号 %d", results, state.utterance)
+	}
+}
+
+func TestDecodeLoopTokens(t *testing.T) {
+	loop := newDecodeLoop(&fakeStreamRecognizer{}, 0)
+	defer loop.close()
+
+	state := newStreamState(&fakeStream{})
+	results, _ := loop.submit(state, 16000, frames("1100"), false)
+	if len(results) != 1 || !results[0].Final {
+		t.Fatalf("期望一个最终结果，得到 %+v", results)
+	}
+	if tokens := fmt.Sprint(results[0].Tokens); tokens != "[{a 0 0.01 0} {a 0.01 0.04 0}]" {
+		t.Errorf("第一句的 token 不正确: %s", tokens)
+	}
+
+	// 第二句的 token 从句子开始计时，需要加上句子在会话中的开始时间
+	results, _ = loop.submit(state, 16000, frames("01"), true)
+	if len(results) != 1 || results[0].Start != 0.04 {
+		t.Fatalf("期望第二句从 0.04 秒开始，得到 %+v", results)
+	}
+	if tokens := fmt.Sprint(results[0].Tokens); tokens != "[{a 0.05 0.06 0}]" {
+		t.Errorf("第二句的 token 应该加上句子的开始时间: %s", tokens)
+	}
+	if len(results[0].Words) != 1 || results[0].Words[0].Start != 0.05 {
+		t.Errorf("词不正确: %+v", results[0].Words)
+	}
+}
+
+func TestDecodeLoopBatchesStreams(t *testing.T) {
+	recognizer := &fakeStreamRecognizer{
+		hold:    make(chan struct{}),
+		started: make(chan struct{}),
+	}
+	loop := newDecodeLoop(recognizer, 4)
+	defer loop.close()
+
+	// 第一个会话的解码被阻塞，期间其余会话提交的音频会合并为一批
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		loop.submit(newStreamState(&fakeStream{}), 16000, frames("1"), false)
+	}()
+	<-recognizer.started
+
+	const sessions = 6
+	results := make([][]StreamResult, sessions)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			results[i], err = loop.submit(newStreamState(&fakeStream{}), 16000, frames("11"), false)
+			if err != nil {
+				t.Errorf("会话 %d 提交音频失败: %v", i, err)
+			}
+		}(i)
+	}
+	waitFor(t, func() bool { return len(loop.requests) == sessions })
+
+	close(recognizer.hold)
+	wg.Wait()
+
+	for i, r := range results {
+		if len(r) != 1 || r[0].Text != "aa" {
+			t.Errorf("会话 %d 期望部分结果 aa，得到 %+v", i, r)
+		}
+	}
+
+	// 6 个流各 2 帧，每批最多 4 个流：4、2、4、2
+	expected := []int{1, 4, 2, 4, 2}
+	if fmt.Sprint(recognizer.batches) != fmt.Sprint(expected) {
+		t.Errorf("期望批次 %v，得到 %v", expected, recognizer.batches)
+	}
+}
+
+func TestDecodeLoopClose(t *testing.T) {
+	loop := newDecodeLoop(&fakeStreamRecognizer{}, 0)
+	loop.close()
+	loop.close()
+
+	if _, err := loop.submit(newStreamState(&fakeStream{}), 16000, frames("1"), false); err != errDecodeLoopClosed {
+		t.Errorf("关闭后提交应该返回 errDecodeLoopClosed，实际: %v", err)
+	}
+}
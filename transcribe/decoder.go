@@ -0,0 +1,208 @@
+package transcribe
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Decoder 把一个完整的音频文件解码为单声道 float32
+type Decoder func(data []byte) (*Audio, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{}
+)
+
+// 常见的格式别名，统一映射到容器格式
+var formatAliases = map[string]string{
+	"wave":   "wav",
+	"x-wav":  "wav",
+	"x-flac": "flac",
+	"mpeg":   "mp3",
+	"mpga":   "mp3",
+	"oga":    "ogg",
+	"opus":   "ogg",
+	"vorbis": "ogg",
+	"raw":    "pcm",
+	"s16le":  "pcm",
+	"f32le":  "f32",
+	"float":  "f32",
+}
+
+func init() {
+	RegisterDecoder("wav", DecodeWAV)
+	RegisterDecoder("flac", DecodeFLAC)
+	RegisterDecoder("mp3", DecodeMP3)
+	RegisterDecoder("ogg", DecodeOgg)
+}
+
+// RegisterDecoder 注册某种容器格式的解码器，已存在时覆盖
+func RegisterDecoder(format string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	decoders[NormalizeFormat(format)] = decoder
+}
+
+func lookupDecoder(format string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	decoder, ok := decoders[format]
+	return decoder, ok
+}
+
+// HasDecoder 返回是否能解码该格式，原始 PCM 总是支持
+func HasDecoder(format string) bool {
+	format = NormalizeFormat(format)
+	if IsRawFormat(format) {
+		return true
+	}
+	_, ok := lookupDecoder(format)
+	return ok
+}
+
+// IsRawFormat 返回格式是否为没有文件头的原始采样：pcm（16 位整数）或 f32（32 位浮点）
+func IsRawFormat(format string) bool {
+	return format == "pcm" || format == "f32"
+}
+
+// SampleSize 返回原始格式每个采样的字节数，其他格式返回 0
+func SampleSize(format string) int {
+	switch format {
+	case "pcm":
+		return 2
+	case "f32":
+		return 4
+	}
+	return 0
+}
+
+// NormalizeFormat 把格式名转换为小写并展开别名
+func NormalizeFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if alias, ok := formatAliases[format]; ok {
+		return alias
+	}
+	return format
+}
+
+// SniffSize 识别格式时建议读取的文件头长度，足够容纳两个最长的 MPEG 音频帧
+const SniffSize = 4096
+
+// SniffFormat 根据文件头的魔数识别音频容器格式
+// 可以识别 wav、flac、ogg、mp3，无法识别时返回空字符串（可能是原始 PCM）；
+// 没有 ID3 标签的 mp3 需要看到两个连续的帧才能确认，data 应该至少有 SniffSize 字节。
+// MPEG Layer I/II 音频返回 mp2，没有对应的解码器，解码时返回 UnsupportedFormatError
+func SniffFormat(data []byte) string {
+	switch {
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WAVE":
+		return "wav"
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "flac"
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return "ogg"
+	case bytes.HasPrefix(data, []byte("ID3")):
+		// ID3v2 标签后面也可能是 FLAC，这里需要跳过标签再判断
+		if n := id3v2Size(data); n > 0 && n < len(data) {
+			if format := SniffFormat(data[n:]); format != "" {
+				return format
+			}
+		}
+		return "mp3"
+	case isMPEGAudioFrame(data):
+		if mpegLayer(data) != 3 {
+			return "mp2"
+		}
+		return "mp3"
+	}
+	return ""
+}
+
+// id3v2Size 返回 ID3v2 标签的总长度（包括 10 字节头部）
+func id3v2Size(data []byte) int {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return 0
+	}
+	// 标签长度为 4 个 7 位的 syncsafe 整数
+	size := int(data[6]&0x7f)<<21 | int(data[7]&0x7f)<<14 | int(data[8]&0x7f)<<7 | int(data[9]&0x7f)
+	size += 10
+	// 存在 footer 时额外有 10 字节
+	if data[5]&0x10 != 0 {
+		size += 10
+	}
+	return size
+}
+
+// isMPEGAudioFrame 判断数据是否以连续两个合法的 MPEG 音频帧开始
+// 只看一个 4 字节帧头太容易误判：以 0xFFFF 开始的原始 PCM（采样 -1）也能通过帧头检查
+func isMPEGAudioFrame(data []byte) bool {
+	n := MPEGFrameSize(data)
+	if n == 0 || len(data) < n+4 {
+		return false
+	}
+	// 同一个流中相邻帧的版本、层和采样率相同
+	next := data[n:]
+	return MPEGFrameSize(next) > 0 && next[1]&0xfe == data[1]&0xfe && next[2]&0x0c == data[2]&0x0c
+}
+
+// mpegLayer 返回 MPEG 音频帧头中的层（1 到 3），data 不以合法的帧头开始时返回 0
+func mpegLayer(data []byte) int {
+	if MPEGFrameSize(data) == 0 {
+		return 0
+	}
+	// 层字段 3 为 Layer I，1 为 Layer III
+	return 4 - int(data[1]>>1&0x03)
+}
+
+// MPEG 音频帧头中比特率索引对应的比特率（kbps），按版本和层区分
+var (
+	mpeg1Bitrates = [4][16]int{
+		3: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}, // Layer I
+		2: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},    // Layer II
+		1: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},     // Layer III
+	}
+	mpeg2Bitrates = [4][16]int{
+		3: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		2: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		1: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	// mpegSampleRates 按版本字段（0 为 MPEG 2.5，2 为 MPEG 2，3 为 MPEG 1）索引
+	mpegSampleRates = [4][3]int{
+		0: {11025, 12000, 8000},
+		2: {22050, 24000, 16000},
+		3: {44100, 48000, 32000},
+	}
+)
+
+// MPEGFrameSize 返回以 data 开始的 MPEG 音频帧的长度（字节），帧头无效或是自由比特率时返回 0
+func MPEGFrameSize(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1]&0xe0 != 0xe0 {
+		return 0
+	}
+	version := (data[1] >> 3) & 0x03
+	layer := (data[1] >> 1) & 0x03
+	bitrateIndex := data[2] >> 4
+	sampleRateIndex := (data[2] >> 2) & 0x03
+	padding := int(data[2]>>1) & 0x01
+	// layer 为 0 的是 AAC ADTS，不是 MPEG 音频；比特率索引 0 为自由比特率，无法计算帧长度
+	if version == 1 || layer == 0 || bitrateIndex == 0 || bitrateIndex == 0x0f || sampleRateIndex == 0x03 {
+		return 0
+	}
+
+	bitrate := mpeg2Bitrates[layer][bitrateIndex] * 1000
+	if version == 3 {
+		bitrate = mpeg1Bitrates[layer][bitrateIndex] * 1000
+	}
+	sampleRate := mpegSampleRates[version][sampleRateIndex]
+	switch {
+	case layer == 3:
+		return (12*bitrate/sampleRate + padding) * 4
+	case layer == 1 && version != 3:
+		// MPEG 2/2.5 的 Layer III 每帧只有 576 个采样
+		return 72*bitrate/sampleRate + padding
+	default:
+		return 144*bitrate/sampleRate + padding
+	}
+}
@@ -0,0 +1,136 @@
+package transcribe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniffFormat(t *testing.T) {
+	wav := buildRIFF(fmtChunk(wavFormatPCM, 1, 16000, 16), pcm16Chunk(0))
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x04"), 0, 0, 0, 0)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"WAV", wav, "wav"},
+		{"FLAC", []byte("fLaC\x00\x00\x00\x22"), "flac"},
+		{"Ogg", buildOpusFile(1), "ogg"},
+		{"MP3 帧", silentMP3(2), "mp3"},
+		{"MPEG Layer II 帧", mpegFrames([]byte{0xff, 0xfd, 0xe8, 0xc0}, 2), "mp2"},
+		{"MPEG Layer I 帧", mpegFrames([]byte{0xff, 0xff, 0x18, 0xc0}, 2), "mp2"},
+		{"ID3 + MPEG Layer II", append(id3, mpegFrames([]byte{0xff, 0xfd, 0xe8, 0xc0}, 2)...), "mp2"},
+		{"只有一个 MP3 帧头", silentMP3(1)[:4], ""},
+		{"以 FF FF 开始的 PCM", []byte{0xff, 0xff, 0, 0, 1, 0}, ""},
+		{"帧头后面不是下一帧", append(silentMP3(1), 0x01, 0x00, 0x02, 0x00), ""},
+		{"ID3 + MP3", append(id3, silentMP3(1)...), "mp3"},
+		{"ID3 + FLAC", append(id3, []byte("fLaC\x00\x00\x00\x22")...), "flac"},
+		{"AAC ADTS", []byte{0xff, 0xf1, 0x50, 0x80}, ""},
+		{"原始 PCM", []byte{0x01, 0x00, 0x02, 0x00}, ""},
+		{"RIFF 但不是 WAVE", []byte("RIFF\x00\x00\x00\x00AVI "), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffFormat(tt.data); got != tt.expected {
+				t.Errorf("期望: %q, 实际: %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMPEGFrameSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		expected int
+	}{
+		{"MPEG 1 Layer III 128kbps 44.1kHz", []byte{0xff, 0xfb, 0x90, 0xc0}, 417},
+		{"带填充字节", []byte{0xff, 0xfb, 0x92, 0xc0}, 418},
+		{"MPEG 2 Layer III 64kbps 16kHz", []byte{0xff, 0xf3, 0x88, 0xc0}, 288},
+		{"MPEG 1 Layer II 384kbps 32kHz", []byte{0xff, 0xfd, 0xe8, 0xc0}, 1728},
+		{"MPEG 1 Layer I 32kbps 32kHz", []byte{0xff, 0xff, 0x18, 0xc0}, 48},
+		{"自由比特率", []byte{0xff, 0xff, 0x00, 0x00}, 0},
+		{"AAC ADTS", []byte{0xff, 0xf1, 0x50, 0x80}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MPEGFrameSize(tt.header); got != tt.expected {
+				t.Errorf("期望: %d, 实际: %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	tests := map[string]string{
+		"WAV":    "wav",
+		" wave ": "wav",
+		"opus":   "ogg",
+		"mpeg":   "mp3",
+		"flac":   "flac",
+		"raw":    "pcm",
+	}
+	for input, expected := range tests {
+		if got := NormalizeFormat(input); got != expected {
+			t.Errorf("NormalizeFormat(%q) 期望: %q, 实际: %q", input, expected, got)
+		}
+	}
+}
+
+func TestHasDecoder(t *testing.T) {
+	tests := map[string]bool{
+		"pcm":  true,
+		"raw":  true,
+		"WAV":  true,
+		"flac": true,
+		"aac":  false,
+		"":     false,
+	}
+	for format, expected := range tests {
+		if got := HasDecoder(format); got != expected {
+			t.Errorf("HasDecoder(%q) 期望: %v, 实际: %v", format, expected, got)
+		}
+	}
+}
+
+func TestDecodeAudioAutoDetect(t *testing.T) {
+	wav := buildRIFF(fmtChunk(wavFormatPCM, 1, 8000, 16), pcm16Chunk(16384))
+
+	audio, err := DecodeAudio(wav, "", 0)
+	if err != nil {
+		t.Fatalf("自动识别 WAV 失败: %v", err)
+	}
+	if audio.SampleRate != 8000 {
+		t.Errorf("采样率错误，期望: 8000, 实际: %d", audio.SampleRate)
+	}
+
+	if _, err := DecodeAudio([]byte{1, 2, 3, 4}, "", 16000); err == nil {
+		t.Error("无法识别的数据期望返回错误")
+	}
+
+	_, err = DecodeAudio(wav, "aac", 16000)
+	var unsupported *UnsupportedFormatError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("没有解码器的格式期望返回 UnsupportedFormatError，实际: %v", err)
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	RegisterDecoder("test-format", func(data []byte) (*Audio, error) {
+		return &Audio{Samples: []float32{float32(len(data))}, SampleRate: 1000}, nil
+	})
+	defer func() {
+		decodersMu.Lock()
+		delete(decoders, "test-format")
+		decodersMu.Unlock()
+	}()
+
+	audio, err := DecodeAudio([]byte{1, 2, 3}, "TEST-FORMAT", 0)
+	if err != nil {
+		t.Fatalf("使用注册的解码器失败: %v", err)
+	}
+	assertSamples(t, audio.Samples, 3)
+}
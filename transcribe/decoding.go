@@ -0,0 +1,82 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 支持的解码方法
+const (
+	DecodingGreedySearch       = "greedy_search"
+	DecodingModifiedBeamSearch = "modified_beam_search"
+)
+
+// 解码参数的默认值和取值范围
+const (
+	defaultMaxActivePaths = 4
+	maxActivePaths        = 32
+
+	defaultRule1MinTrailingSilence = 2.4
+	defaultRule2MinTrailingSilence = 1.2
+	defaultRule3MinUtteranceLength = 20
+	maxTrailingSilence             = 30
+	maxUtteranceLength             = 60
+)
+
+// ErrInvalidOptions 请求的解码选项无效或当前模型不支持
+var ErrInvalidOptions = errors.New("无效的解码选项")
+
+// Validate 检查解码选项的取值范围，模型是否支持在选择识别器时检查
+func (o DecodingOptions) Validate() error {
+	if len(o.Hotwords) > maxHotwords {
+		return fmt.Errorf("%w: 热词不能超过 %d 个", ErrInvalidOptions, maxHotwords)
+	}
+	for _, word := range o.Hotwords {
+		if strings.ContainsAny(word, "\r\n") {
+			return fmt.Errorf("%w: 热词不能包含换行: %q", ErrInvalidOptions, word)
+		}
+		if utf8.RuneCountInString(word) > maxHotwordLength {
+			return fmt.Errorf("%w: 热词不能超过 %d 个字符: %s", ErrInvalidOptions, maxHotwordLength, word)
+		}
+	}
+	if err := checkRange("hotwords_score", o.HotwordsScore, maxHotwordsScore); err != nil {
+		return err
+	}
+
+	switch o.DecodingMethod {
+	case "", DecodingGreedySearch, DecodingModifiedBeamSearch:
+	default:
+		return fmt.Errorf("%w: 不支持的解码方法: %s", ErrInvalidOptions, o.DecodingMethod)
+	}
+	if o.MaxActivePaths < 0 || o.MaxActivePaths > maxActivePaths {
+		return fmt.Errorf("%w: max_active_paths 应在 1 到 %d 之间: %d", ErrInvalidOptions, maxActivePaths, o.MaxActivePaths)
+	}
+	return o.Endpoint.validate()
+}
+
+// validate 检查端点检测规则的取值范围，0 表示使用默认值
+func (r EndpointRules) validate() error {
+	if err := checkRange("rule1_min_trailing_silence", r.Rule1MinTrailingSilence, maxTrailingSilence); err != nil {
+		return err
+	}
+	if err := checkRange("rule2_min_trailing_silence", r.Rule2MinTrailingSilence, maxTrailingSilence); err != nil {
+		return err
+	}
+	return checkRange("rule3_min_utterance_length", r.Rule3MinUtteranceLength, maxUtteranceLength)
+}
+
+// checkRange 检查取值在 0 到 max 之间，NaN 同样无效
+func checkRange(name string, v, max float32) error {
+	if !(v >= 0 && v <= max) {
+		return fmt.Errorf("%w: %s 应在 0 到 %g 之间: %g", ErrInvalidOptions, name, max, v)
+	}
+	return nil
+}
+
+// IsZero 返回是否没有设置任何解码选项
+func (o DecodingOptions) IsZero() bool {
+	return len(o.Hotwords) == 0 && o.HotwordsScore == 0 && o.DecodingMethod == "" && o.MaxActivePaths == 0 &&
+		o.EnableEndpoint == nil && o.Endpoint == EndpointRules{}
+}
@@ -0,0 +1,45 @@
+package transcribe
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodingOptionsValidate(t *testing.T) {
+	enable := false
+	tests := []struct {
+		name  string
+		opts  DecodingOptions
+		valid bool
+	}{
+		{"默认选项", DecodingOptions{}, true},
+		{"热词", DecodingOptions{Hotwords: []string{"语音识别", "sherpa onnx"}, HotwordsScore: 2}, true},
+		{"解码参数", DecodingOptions{DecodingMethod: DecodingModifiedBeamSearch, MaxActivePaths: 8}, true},
+		{"端点检测", DecodingOptions{EnableEndpoint: &enable, Endpoint: EndpointRules{1, 0.5, 20}}, true},
+		{"包含换行", DecodingOptions{Hotwords: []string{"语音\n识别"}}, false},
+		{"热词过长", DecodingOptions{Hotwords: []string{strings.Repeat("词", maxHotwordLength+1)}}, false},
+		{"热词过多", DecodingOptions{Hotwords: make([]string, maxHotwords+1)}, false},
+		{"负的加分", DecodingOptions{HotwordsScore: -1}, false},
+		{"加分过大", DecodingOptions{HotwordsScore: maxHotwordsScore + 1}, false},
+		{"加分为 NaN", DecodingOptions{HotwordsScore: float32(math.NaN())}, false},
+		{"不支持的解码方法", DecodingOptions{DecodingMethod: "beam_search"}, false},
+		{"负的路径数", DecodingOptions{MaxActivePaths: -1}, false},
+		{"路径数过大", DecodingOptions{MaxActivePaths: maxActivePaths + 1}, false},
+		{"静音时长过大", DecodingOptions{Endpoint: EndpointRules{Rule2MinTrailingSilence: maxTrailingSilence + 1}}, false},
+		{"负的句子长度", DecodingOptions{Endpoint: EndpointRules{Rule3MinUtteranceLength: -1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("期望校验通过，实际: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("期望返回 ErrInvalidOptions，实际: %v", err)
+			}
+		})
+	}
+}
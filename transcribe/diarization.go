@@ -0,0 +1,174 @@
+package transcribe
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// 说话人分离模型目录下的默认文件名
+const (
+	diarizationSegmentationModel = "segmentation.onnx"
+	diarizationEmbeddingModel    = "embedding.onnx"
+)
+
+// DiarizationSegment 说话人分离后端输出的原始片段（单位：秒）
+type DiarizationSegment struct {
+	Start   float64
+	End     float64
+	Speaker int
+}
+
+// Diarizer 说话人分离后端接口
+// 实现需要完成分段、说话人嵌入提取和聚类，返回按开始时间排序的片段
+type Diarizer interface {
+	// SampleRate 返回后端期望的输入采样率
+	SampleRate() int
+	// Process 对整段音频执行说话人分离
+	Process(samples []float32) ([]DiarizationSegment, error)
+	// Close 释放后端持有的资源
+	Close() error
+}
+
+// SherpaDiarizer 基于 sherpa-onnx OfflineSpeakerDiarization 的说话人分离实现
+type SherpaDiarizer struct {
+	sd *sherpa_onnx.OfflineSpeakerDiarization
+	// sherpa-onnx 的分离器不保证并发安全，这里串行化调用
+	mu sync.Mutex
+}
+
+// NewSherpaDiarizer 从模型目录创建说话人分离器
+// 目录下需要包含 segmentation.onnx（pyannote 分段模型）和 embedding.onnx（说话人嵌入模型）
+// numSpeakers <= 0 时根据 threshold 自动确定说话人数量
+func NewSherpaDiarizer(modelPath string, numThreads, numSpeakers int, threshold float32) (*SherpaDiarizer, error) {
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+	if numSpeakers <= 0 {
+		numSpeakers = -1
+	}
+
+	config := &sherpa_onnx.OfflineSpeakerDiarizationConfig{}
+
+	// 分段模型
+	config.Segmentation.Pyannote.Model = filepath.Join(modelPath, diarizationSegmentationModel)
+	config.Segmentation.NumThreads = numThreads
+	config.Segmentation.Provider = "cpu"
+
+	// 说话人嵌入模型
+	config.Embedding.Model = filepath.Join(modelPath, diarizationEmbeddingModel)
+	config.Embedding.NumThreads = numThreads
+	config.Embedding.Provider = "cpu"
+
+	// 聚类配置
+	config.Clustering.NumClusters = numSpeakers
+	config.Clustering.Threshold = threshold
+
+	config.MinDurationOn = 0.3
+	config.MinDurationOff = 0.5
+
+	sd := sherpa_onnx.NewOfflineSpeakerDiarization(config)
+	if sd == nil {
+		return nil, fmt.Errorf("无法加载说话人分离模型: %s", modelPath)
+	}
+
+	return &SherpaDiarizer{sd: sd}, nil
+}
+
+func (d *SherpaDiarizer) SampleRate() int {
+	return d.sd.SampleRate()
+}
+
+func (d *SherpaDiarizer) Process(samples []float32) ([]DiarizationSegment, error) {
+	if len(samples) == 0 {
+		return nil, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	segments := d.sd.Process(samples)
+	result := make([]DiarizationSegment, 0, len(segments))
+	for _, s := range segments {
+		result = append(result, DiarizationSegment{
+			Start:   float64(s.Start),
+			End:     float64(s.End),
+			Speaker: s.Speaker,
+		})
+	}
+	return result, nil
+}
+
+func (d *SherpaDiarizer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sd != nil {
+		sherpa_onnx.DeleteOfflineSpeakerDiarization(d.sd)
+		d.sd = nil
+	}
+	return nil
+}
+
+// buildSpeakerSegments 执行说话人分离，并对每个片段单独识别出文本
+// recognize 只接收该片段对应的音频采样；返回的 token 时间已换算为整段音频中的时间；
+// progress 不为空时每识别完一个片段调用一次
+func buildSpeakerSegments(diarizer Diarizer, samples []float32, sampleRate int, recognize func([]float32) (recognition, error), progress func(float64)) ([]SpeakerSegment, []Token, error) {
+	if diarizer.SampleRate() != sampleRate {
+		return nil, nil, fmt.Errorf("说话人分离模型采样率 %d 与识别采样率 %d 不一致", diarizer.SampleRate(), sampleRate)
+	}
+
+	segments, err := diarizer.Process(samples)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+
+	duration := float64(len(samples)) / float64(sampleRate)
+	speakerSegments := make([]SpeakerSegment, 0, len(segments))
+	var tokens []Token
+	for i, seg := range segments {
+		if progress != nil && i > 0 {
+			progress(float64(i) / float64(len(segments)))
+		}
+
+		start := int(seg.Start * float64(sampleRate))
+		end := int(seg.End * float64(sampleRate))
+		if start < 0 {
+			start = 0
+		}
+		if end > len(samples) {
+			end = len(samples)
+		}
+		if start >= end {
+			continue
+		}
+
+		rec, err := recognize(samples[start:end])
+		if err != nil {
+			return nil, nil, fmt.Errorf("识别说话人片段 [%.2f, %.2f] 失败: %v", seg.Start, seg.End, err)
+		}
+
+		// 片段时间截断到音频范围内，字幕的时间轴不会超出音频
+		speakerSegments = append(speakerSegments, SpeakerSegment{
+			SpeakerID:  seg.Speaker,
+			Start:      math.Max(seg.Start, 0),
+			End:        math.Min(seg.End, duration),
+			Text:       rec.text,
+			Confidence: aggregateConfidence(rec.tokens),
+		})
+		tokens = append(tokens, offsetTokens(rec.tokens, float64(start)/float64(sampleRate))...)
+	}
+	if progress != nil && len(segments) > 0 {
+		progress(1)
+	}
+
+	return speakerSegments, tokens, nil
+}
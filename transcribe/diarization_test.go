@@ -0,0 +1,151 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// fakeDiarizer 用于测试的说话人分离后端，直接返回预设片段
+type fakeDiarizer struct {
+	sampleRate int
+	segments   []DiarizationSegment
+	err        error
+	closed     bool
+}
+
+func (d *fakeDiarizer) SampleRate() int { return d.sampleRate }
+
+func (d *fakeDiarizer) Process(samples []float32) ([]DiarizationSegment, error) {
+	return d.segments, d.err
+}
+
+func (d *fakeDiarizer) Close() error {
+	d.closed = true
+	return nil
+}
+
+// markedSamples 生成每秒采样值不同的音频，便于检查片段切分
+func markedSamples(sampleRate, seconds int) []float32 {
+	samples := make([]float32, sampleRate*seconds)
+	for i := range samples {
+		samples[i] = float32(i / sampleRate)
+	}
+	return samples
+}
+
+// markerRecognizer 将片段中出现的采样值拼接为文本，每个值是一个 token（按 100Hz 计算时间）
+func markerRecognizer(samples []float32) (recognition, error) {
+	var rec recognition
+	last := float32(-1)
+	for i, s := range samples {
+		if s != last {
+			marker := fmt.Sprintf("%d", int(s))
+			rec.text += marker
+			rec.tokens = append(rec.tokens, Token{Text: marker, Start: float64(i) / 100, End: float64(i)/100 + 1})
+			last = s
+		}
+	}
+	return rec, nil
+}
+
+func TestBuildSpeakerSegments(t *testing.T) {
+	diarizer := &fakeDiarizer{
+		sampleRate: 100,
+		segments: []DiarizationSegment{
+			{Start: 2, End: 4, Speaker: 1},
+			{Start: 0, End: 2, Speaker: 0},
+			{Start: 4, End: 10, Speaker: 0}, // 超出音频长度，应被截断
+		},
+	}
+
+	segments, tokens, err := buildSpeakerSegments(diarizer, markedSamples(100, 5), 100, markerRecognizer, nil)
+	if err != nil {
+		t.Fatalf("说话人分离失败: %v", err)
+	}
+
+	expected := []SpeakerSegment{
+		{SpeakerID: 0, Start: 0, End: 2, Text: "01"},
+		{SpeakerID: 1, Start: 2, End: 4, Text: "23"},
+		{SpeakerID: 0, Start: 4, End: 5, Text: "4"},
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("片段数量错误，期望: %d, 实际: %d", len(expected), len(segments))
+	}
+	for i, seg := range segments {
+		if seg != expected[i] {
+			t.Errorf("片段 %d 错误，期望: %+v, 实际: %+v", i, expected[i], seg)
+		}
+	}
+
+	// token 时间换算为整段音频中的时间
+	if len(tokens) != 5 {
+		t.Fatalf("期望 5 个 token，实际: %+v", tokens)
+	}
+	for i, token := range tokens {
+		if token.Text != fmt.Sprint(i) || token.Start != float64(i) {
+			t.Errorf("token %d 错误，实际: %+v", i, token)
+		}
+	}
+}
+
+func TestBuildSpeakerSegmentsSkipsEmpty(t *testing.T) {
+	diarizer := &fakeDiarizer{
+		sampleRate: 100,
+		segments: []DiarizationSegment{
+			{Start: 6, End: 8, Speaker: 0},
+		},
+	}
+
+	segments, _, err := buildSpeakerSegments(diarizer, markedSamples(100, 5), 100, markerRecognizer, nil)
+	if err != nil {
+		t.Fatalf("说话人分离失败: %v", err)
+	}
+	if len(segments) != 0 {
+		t.Errorf("期望没有片段，实际: %+v", segments)
+	}
+}
+
+func TestBuildSpeakerSegmentsErrors(t *testing.T) {
+	samples := markedSamples(100, 1)
+
+	// 采样率不一致
+	diarizer := &fakeDiarizer{sampleRate: 8000}
+	if _, _, err := buildSpeakerSegments(diarizer, samples, 100, markerRecognizer, nil); err == nil {
+		t.Error("采样率不一致时期望返回错误")
+	}
+
+	// 后端失败
+	diarizer = &fakeDiarizer{sampleRate: 100, err: errors.New("boom")}
+	if _, _, err := buildSpeakerSegments(diarizer, samples, 100, markerRecognizer, nil); err == nil {
+		t.Error("后端失败时期望返回错误")
+	}
+
+	// 片段识别失败
+	diarizer = &fakeDiarizer{sampleRate: 100, segments: []DiarizationSegment{{Start: 0, End: 1}}}
+	failing := func([]float32) (recognition, error) { return recognition{}, errors.New("boom") }
+	if _, _, err := buildSpeakerSegments(diarizer, samples, 100, failing, nil); err == nil {
+		t.Error("片段识别失败时期望返回错误")
+	}
+}
+
+func TestSetDiarizer(t *testing.T) {
+	transcriber := &SherpaTranscriber{}
+
+	first := &fakeDiarizer{sampleRate: 16000}
+	transcriber.SetDiarizer(first)
+	if !transcriber.diarizationEnabled {
+		t.Error("设置说话人分离后端后应启用说话人分离")
+	}
+
+	second := &fakeDiarizer{sampleRate: 16000}
+	transcriber.SetDiarizer(second)
+	if !first.closed {
+		t.Error("替换说话人分离后端时应关闭旧后端")
+	}
+
+	transcriber.SetDiarizer(nil)
+	if transcriber.diarizationEnabled {
+		t.Error("清除说话人分离后端后应禁用说话人分离")
+	}
+}
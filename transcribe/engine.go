@@ -0,0 +1,101 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+)
+
+// Transcriber 转录引擎接口
+// HTTP 层只依赖这个接口，sherpa-onnx 是其中一个后端，测试时可以使用 FakeTranscriber
+type Transcriber interface {
+	// TranscribeAudio 对一段完整音频进行转录
+	TranscribeAudio(audioData []byte, opts TranscribeOptions) (*TranscriptionResult, error)
+	// TranscribeReader 边读边解码，对 r 中的完整音频进行转录，不在内存中保留整个文件
+	TranscribeReader(r io.Reader, opts TranscribeOptions) (*TranscriptionResult, error)
+	// NewSession 创建一个流式识别会话
+	NewSession(opts DecodingOptions) (Session, error)
+	// GetSampleRate 返回模型期望的采样率
+	GetSampleRate() int
+	// Close 释放引擎持有的资源
+	Close() error
+}
+
+// Session 流式识别会话，每个实时连接对应一个会话
+type Session interface {
+	// AcceptWaveform 输入一段采样率为 sampleRate 的音频采样，采样值范围 [-1, 1]，
+	// 返回这段音频解码后产生的结果：检测到端点的句子作为最终结果，当前句子有变化时作为部分结果。
+	// 与模型采样率不一致时会自动重采样，sampleRate <= 0 表示与模型采样率相同
+	AcceptWaveform(sampleRate int, samples []float32) ([]StreamResult, error)
+	// Flush 不等端点检测，立即结束当前句子并返回其最终结果
+	Flush() ([]StreamResult, error)
+	// Close 释放会话资源，可以重复调用
+	Close() error
+}
+
+// StreamResult 流式识别的一条结果
+type StreamResult struct {
+	// Utterance 句子序号，从 0 开始，每个最终结果之后加一
+	Utterance int
+	Text      string
+	// Final 为 true 表示句子已经结束，之后不会再变化
+	Final bool
+	// Start 和 End 句子在会话音频中的起止时间（秒）；
+	// End 为产生该结果时已经收到的音频长度，最终结果包含端点检测所需的尾部静音
+	Start float64
+	End   float64
+	// Tokens 句子中的 token，时间相对于会话音频的开始；模型不提供 token 时间戳时为空
+	Tokens []Token
+	// Words 由 Tokens 合并得到的词
+	Words []Word
+	// Confidence 句子中各 token 概率的几何平均，只有 transducer 模型提供，其他模型为 0
+	Confidence float64
+}
+
+// TranscribeOptions 单次转录请求的选项
+type TranscribeOptions struct {
+	// Format 音频格式：wav、flac、mp3、ogg、pcm，为空时根据文件头识别
+	Format string
+	// SampleRate 输入音频的采样率
+	// WAV 以文件头为准；PCM 为 0 时使用模型采样率
+	SampleRate int
+	// Mode 识别模式：online 或 offline，为空时使用配置的默认模式
+	Mode string
+	// Timestamps 是否返回词和 token 的时间戳
+	Timestamps bool
+	// Progress 不为空时报告识别进度（0 到 1），只有分段识别时才会在中途调用；
+	// TranscribeReader 在 Size 大于 0 时按已读取的字节数报告
+	Progress func(float64)
+	// Size 音频文件的字节数，为 0 表示未知
+	Size int64
+	// Context 不为空时，取消后停止排队、读取音频和识别下一段，返回 Context 的错误；
+	// 正在进行的一次识别调用不能中断，不分段的离线识别要等这次调用结束
+	Context context.Context
+
+	DecodingOptions
+}
+
+// DecodingOptions 单次请求或会话的解码选项，零值表示使用配置的默认值
+type DecodingOptions struct {
+	// Hotwords 额外的热词，与配置的热词文件合并，每项是一个词或短语
+	Hotwords []string
+	// HotwordsScore 热词的加分，0 表示使用配置的默认值
+	HotwordsScore float32
+	// DecodingMethod 解码方法：greedy_search 或 modified_beam_search
+	DecodingMethod string
+	// MaxActivePaths modified_beam_search 保留的路径数（beam 大小）
+	MaxActivePaths int
+	// EnableEndpoint 是否启用端点检测，只对流式识别会话生效
+	EnableEndpoint *bool
+	// Endpoint 端点检测规则，只对流式识别会话生效
+	Endpoint EndpointRules
+}
+
+// EndpointRules 流式识别的端点检测规则，满足任意一条即结束当前句子，取值为 0 时使用默认值
+type EndpointRules struct {
+	// Rule1MinTrailingSilence 还没有识别出文字时，尾部静音达到该时长（秒）即结束
+	Rule1MinTrailingSilence float32
+	// Rule2MinTrailingSilence 已经识别出文字后，尾部静音达到该时长（秒）即结束
+	Rule2MinTrailingSilence float32
+	// Rule3MinUtteranceLength 句子达到该时长（秒）时强制结束
+	Rule3MinUtteranceLength float32
+}
@@ -0,0 +1,276 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+var _ Transcriber = (*FakeTranscriber)(nil)
+
+// FakeTranscriber 内存中的假转录引擎，不依赖模型文件，供测试使用
+type FakeTranscriber struct {
+	// Text 每次识别返回的文本
+	Text string
+	// Err 不为空时所有调用都返回该错误
+	Err error
+	// SampleRate 模型采样率，默认 16000
+	SampleRate int
+	// Gate 不为空时 TranscribeAudio 会等到 Gate 可读（或被关闭）后才返回，用于模拟耗时的解码；
+	// 选项中的 Context 被取消时提前返回 Context 的错误
+	Gate chan struct{}
+	// Tokens 请求时间戳时返回的 token；流式会话的每条结果也带有这些 token，时间相对于句子的开始
+	Tokens []Token
+
+	mu       sync.Mutex
+	requests int
+	lastOpts TranscribeOptions
+	sessions []*FakeSession
+	closed   bool
+}
+
+// NewFakeTranscriber 创建返回固定文本的假转录引擎
+func NewFakeTranscriber(text string) *FakeTranscriber {
+	return &FakeTranscriber{
+		Text:       text,
+		SampleRate: 16000,
+	}
+}
+
+func (f *FakeTranscriber) TranscribeAudio(audioData []byte, opts TranscribeOptions) (*TranscriptionResult, error) {
+	f.mu.Lock()
+	f.requests++
+	f.lastOpts = opts
+	f.mu.Unlock()
+
+	if f.Gate != nil {
+		var canceled <-chan struct{}
+		if opts.Context != nil {
+			canceled = opts.Context.Done()
+		}
+		select {
+		case <-f.Gate:
+		case <-canceled:
+			return nil, opts.Context.Err()
+		}
+	}
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if err := opts.DecodingOptions.Validate(); err != nil {
+		return nil, err
+	}
+
+	sampleRate := opts.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = f.GetSampleRate()
+	}
+	audio, err := DecodeAudio(audioData, opts.Format, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TranscriptionResult{
+		Text:       f.Text,
+		Confidence: aggregateConfidence(f.Tokens),
+		Duration:   audio.Duration(),
+	}
+	if opts.Timestamps {
+		if err := result.setTokens(f.Tokens); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// TranscribeReader 读入全部数据后按 TranscribeAudio 处理
+func (f *FakeTranscriber) TranscribeReader(r io.Reader, opts TranscribeOptions) (*TranscriptionResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return f.TranscribeAudio(data, opts)
+}
+
+func (f *FakeTranscriber) NewSession(opts DecodingOptions) (Session, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session := &FakeSession{
+		options:   opts,
+		text:      f.Text,
+		tokens:    f.Tokens,
+		resampler: streamResampler{targetRate: f.GetSampleRate()},
+	}
+	f.sessions = append(f.sessions, session)
+	return session, nil
+}
+
+func (f *FakeTranscriber) GetSampleRate() int {
+	if f.SampleRate <= 0 {
+		return 16000
+	}
+	return f.SampleRate
+}
+
+func (f *FakeTranscriber) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	return nil
+}
+
+// Requests 返回 TranscribeAudio 被调用的次数
+func (f *FakeTranscriber) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests
+}
+
+// LastOptions 返回最近一次 TranscribeAudio 调用的选项
+func (f *FakeTranscriber) LastOptions() TranscribeOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.lastOpts
+}
+
+// Sessions 返回已创建的所有会话
+func (f *FakeTranscriber) Sessions() []*FakeSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]*FakeSession(nil), f.sessions...)
+}
+
+// FakeSession 假流式会话
+// 收到非静音音频后返回固定文本作为部分结果；
+// 之后收到一整段静音（全为 0）时视为检测到端点，返回最终结果
+type FakeSession struct {
+	options   DecodingOptions
+	text      string
+	tokens    []Token
+	resampler streamResampler
+
+	mu        sync.Mutex
+	samples   []float32
+	utterance int
+	// utteranceStart 当前句子开始时已经收到的采样数
+	utteranceStart int
+	// speaking 当前句子是否已经有语音
+	speaking bool
+	closed   bool
+}
+
+func (s *FakeSession) AcceptWaveform(sampleRate int, samples []float32) ([]StreamResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, fmt.Errorf("会话已关闭")
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+
+	// 按重采样之前的输入判断静音，重采样滤波器会把上一段的尾音带进来
+	silent := true
+	for _, v := range samples {
+		if v != 0 {
+			silent = false
+			break
+		}
+	}
+
+	samples, err := s.resampler.process(sampleRate, samples)
+	if err != nil {
+		return nil, err
+	}
+	s.samples = append(s.samples, samples...)
+
+	if silent {
+		return s.endUtterance(), nil
+	}
+	if s.speaking {
+		return nil, nil
+	}
+	s.speaking = true
+	return []StreamResult{s.result()}, nil
+}
+
+func (s *FakeSession) Flush() ([]StreamResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, fmt.Errorf("会话已关闭")
+	}
+	return s.endUtterance(), nil
+}
+
+func (s *FakeSession) endUtterance() []StreamResult {
+	result := s.result()
+	result.Final = true
+	s.utteranceStart = len(s.samples)
+	if !s.speaking {
+		return nil
+	}
+	s.speaking = false
+	s.utterance++
+	return []StreamResult{result}
+}
+
+// result 返回当前句子的结果
+func (s *FakeSession) result() StreamResult {
+	start, end := s.span()
+	result := StreamResult{Utterance: s.utterance, Text: s.text, Start: start, End: end}
+	if len(s.tokens) > 0 {
+		result.setTokens(offsetTokens(s.tokens, start))
+	}
+	return result
+}
+
+// span 返回当前句子的起止时间（秒）
+func (s *FakeSession) span() (start, end float64) {
+	rate := float64(s.resampler.targetRate)
+	return float64(s.utteranceStart) / rate, float64(len(s.samples)) / rate
+}
+
+func (s *FakeSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+// Samples 返回会话收到的全部音频采样（已重采样到模型采样率）
+func (s *FakeSession) Samples() []float32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]float32(nil), s.samples...)
+}
+
+// Options 返回创建会话时的解码选项
+func (s *FakeSession) Options() DecodingOptions {
+	return s.options
+}
+
+// Closed 返回会话是否已关闭
+func (s *FakeSession) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
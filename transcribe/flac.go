@@ -0,0 +1,14 @@
+package transcribe
+
+import (
+	"bytes"
+)
+
+// DecodeFLAC 解码 FLAC 文件，多声道会被平均混合为单声道
+func DecodeFLAC(data []byte) (*Audio, error) {
+	r, err := newFLACReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ReadAllSamples(r)
+}
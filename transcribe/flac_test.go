@@ -0,0 +1,93 @@
+package transcribe
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// encodeFLAC 使用 verbatim 子帧把各声道的采样编码为 FLAC
+func encodeFLAC(t *testing.T, sampleRate int, bits uint8, channels ...[]int32) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	n := len(channels[0])
+	// STREAMINFO 要求块大小不小于 16，最后一帧可以更短
+	info := &meta.StreamInfo{
+		BlockSizeMin:  4096,
+		BlockSizeMax:  4096,
+		SampleRate:    uint32(sampleRate),
+		NChannels:     uint8(len(channels)),
+		BitsPerSample: bits,
+		NSamples:      uint64(n),
+	}
+	enc, err := flac.NewEncoder(&buf, info)
+	if err != nil {
+		t.Fatalf("创建 FLAC 编码器失败: %v", err)
+	}
+
+	layout := frame.ChannelsMono
+	if len(channels) == 2 {
+		layout = frame.ChannelsLR
+	}
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(n),
+			SampleRate:        uint32(sampleRate),
+			Channels:          layout,
+			BitsPerSample:     bits,
+		},
+	}
+	for _, samples := range channels {
+		f.Subframes = append(f.Subframes, &frame.Subframe{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   samples,
+			NSamples:  n,
+		})
+	}
+	if err := enc.WriteFrame(f); err != nil {
+		t.Fatalf("写入 FLAC 帧失败: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("关闭 FLAC 编码器失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeFLACMono(t *testing.T) {
+	data := encodeFLAC(t, 16000, 16, []int32{0, 16384, -32768, 8192})
+
+	audio, err := DecodeFLAC(data)
+	if err != nil {
+		t.Fatalf("解码 FLAC 失败: %v", err)
+	}
+	if audio.SampleRate != 16000 {
+		t.Errorf("采样率错误，期望: 16000, 实际: %d", audio.SampleRate)
+	}
+	assertSamples(t, audio.Samples, 0, 0.5, -1, 0.25)
+}
+
+func TestDecodeFLACStereo24Bit(t *testing.T) {
+	left := []int32{1 << 22, -(1 << 22)}
+	right := []int32{0, -(1 << 22)}
+	data := encodeFLAC(t, 44100, 24, left, right)
+
+	audio, err := DecodeAudio(data, "", 0)
+	if err != nil {
+		t.Fatalf("解码 FLAC 失败: %v", err)
+	}
+	if audio.SampleRate != 44100 {
+		t.Errorf("采样率错误，期望: 44100, 实际: %d", audio.SampleRate)
+	}
+	assertSamples(t, audio.Samples, 0.25, -0.5)
+}
+
+func TestDecodeFLACInvalid(t *testing.T) {
+	if _, err := DecodeFLAC([]byte("fLaC\x00\x00")); err == nil {
+		t.Error("无效的 FLAC 数据期望返回错误")
+	}
+}
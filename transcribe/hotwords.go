@@ -0,0 +1,62 @@
+package transcribe
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 单次请求热词的限制
+const (
+	maxHotwords      = 1000
+	maxHotwordLength = 64
+	maxHotwordsScore = 10
+)
+
+// hotwords 返回去掉空白、去重并排序后的热词，相同的热词集合共用一个识别器
+func (o DecodingOptions) hotwords() []string {
+	seen := make(map[string]bool, len(o.Hotwords))
+	words := make([]string, 0, len(o.Hotwords))
+	for _, word := range o.Hotwords {
+		word = strings.Join(strings.Fields(word), " ")
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// writeHotwordsFile 把热词文件的内容和额外的热词合并写入临时文件，返回文件路径
+// sherpa-onnx-go 不能按流指定热词，只能用合并后的文件另外创建识别器；识别器加载完成后即可删除该文件
+func writeHotwordsFile(base string, words []string) (string, error) {
+	var content []byte
+	if base != "" {
+		data, err := os.ReadFile(base)
+		if err != nil {
+			return "", fmt.Errorf("读取热词文件失败: %v", err)
+		}
+		content = append(content, data...)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			content = append(content, '\n')
+		}
+	}
+	for _, word := range words {
+		content = append(content, word...)
+		content = append(content, '\n')
+	}
+
+	f, err := os.CreateTemp("", "hotwords-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("创建热词文件失败: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("写入热词文件失败: %v", err)
+	}
+	return f.Name(), nil
+}
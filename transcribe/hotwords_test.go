@@ -0,0 +1,41 @@
+package transcribe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDecodingOptionsHotwords(t *testing.T) {
+	opts := DecodingOptions{Hotwords: []string{" 语音识别 ", "", "sherpa   onnx", "语音识别", "转录"}}
+	expected := []string{"sherpa onnx", "语音识别", "转录"}
+	if got := opts.hotwords(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("热词规范化结果 %q，期望 %q", got, expected)
+	}
+}
+
+func TestWriteHotwordsFile(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "hotwords.txt")
+	if err := os.WriteFile(base, []byte("语音识别"), 0o644); err != nil {
+		t.Fatalf("创建热词文件失败: %v", err)
+	}
+
+	file, err := writeHotwordsFile(base, []string{"转录"})
+	if err != nil {
+		t.Fatalf("写入热词文件失败: %v", err)
+	}
+	defer os.Remove(file)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("读取热词文件失败: %v", err)
+	}
+	if string(data) != "语音识别\n转录\n" {
+		t.Errorf("热词文件内容错误: %q", data)
+	}
+
+	if _, err := writeHotwordsFile(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("热词文件不存在时应该返回错误")
+	}
+}
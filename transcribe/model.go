@@ -0,0 +1,383 @@
+package transcribe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// 支持的模型类型
+const (
+	ModelTransducer    = "transducer"
+	ModelParaformer    = "paraformer"
+	ModelZipformer2CTC = "zipformer2-ctc"
+	ModelWhisper       = "whisper"
+	ModelSenseVoice    = "sense-voice"
+	ModelNemoCTC       = "nemo-ctc"
+	ModelMoonshine     = "moonshine"
+	ModelFireRedASR    = "fire-red-asr"
+	ModelDolphin       = "dolphin"
+	ModelTDNN          = "tdnn"
+)
+
+// ModelConfig 识别模型配置
+// 文件字段为空时使用 Dir 目录下的默认文件名，不同模型类型需要的文件见 modelFamilies
+type ModelConfig struct {
+	// Type 模型类型，默认 transducer
+	Type string
+	// Dir 模型目录
+	Dir    string
+	Tokens string
+
+	Encoder         string
+	Decoder         string
+	Joiner          string
+	Model           string
+	Preprocessor    string
+	UncachedDecoder string
+	CachedDecoder   string
+
+	// Language whisper 和 sense-voice 的识别语言，为空时自动检测
+	Language   string
+	NumThreads int
+	// DecodingMethod 解码方法，默认 greedy_search；modified_beam_search 只支持 transducer 模型
+	DecodingMethod string
+	// MaxActivePaths modified_beam_search 保留的路径数，为 0 时使用默认值 4
+	MaxActivePaths int
+	// DisableEndpoint 关闭流式识别的端点检测，句子只在 flush 或音频结束时结束
+	DisableEndpoint bool
+	// Endpoint 流式识别的端点检测规则，为 0 的规则使用默认值
+	Endpoint EndpointRules
+
+	// HotwordsFile 热词文件，每行一个热词，只支持 transducer 模型的 modified_beam_search 解码
+	HotwordsFile string
+	// HotwordsScore 热词的加分，为 0 时使用 sherpa-onnx 的默认值 1.5
+	HotwordsScore float32
+	// ModelingUnit 热词的切分方式：cjkchar、bpe 或 cjkchar+bpe，为空时按 cjkchar 处理
+	ModelingUnit string
+	// BpeVocab 使用 bpe 切分热词时需要的 bpe.vocab 文件
+	BpeVocab string
+}
+
+// modelFile 模型需要的一个文件
+type modelFile struct {
+	name        string
+	field       func(m *ModelConfig) *string
+	defaultName string
+}
+
+var (
+	encoderFile         = modelFile{"encoder", func(m *ModelConfig) *string { return &m.Encoder }, "encoder.onnx"}
+	decoderFile         = modelFile{"decoder", func(m *ModelConfig) *string { return &m.Decoder }, "decoder.onnx"}
+	joinerFile          = modelFile{"joiner", func(m *ModelConfig) *string { return &m.Joiner }, "joiner.onnx"}
+	singleModelFile     = modelFile{"model", func(m *ModelConfig) *string { return &m.Model }, "model.onnx"}
+	preprocessorFile    = modelFile{"preprocessor", func(m *ModelConfig) *string { return &m.Preprocessor }, "preprocess.onnx"}
+	uncachedDecoderFile = modelFile{"uncached_decoder", func(m *ModelConfig) *string { return &m.UncachedDecoder }, "uncached_decode.onnx"}
+	cachedDecoderFile   = modelFile{"cached_decoder", func(m *ModelConfig) *string { return &m.CachedDecoder }, "cached_decode.onnx"}
+)
+
+// modelFamily 一种模型类型需要的文件以及支持的识别方式
+type modelFamily struct {
+	files []modelFile
+	// online 是否有流式实现，只有流式模型能用于实时转录
+	online bool
+	// offline 是否有离线实现
+	offline bool
+}
+
+var modelFamilies = map[string]modelFamily{
+	ModelTransducer:    {files: []modelFile{encoderFile, decoderFile, joinerFile}, online: true, offline: true},
+	ModelParaformer:    {files: []modelFile{encoderFile, decoderFile}, online: true, offline: true},
+	ModelZipformer2CTC: {files: []modelFile{singleModelFile}, online: true},
+	ModelWhisper:       {files: []modelFile{encoderFile, decoderFile}, offline: true},
+	ModelSenseVoice:    {files: []modelFile{singleModelFile}, offline: true},
+	ModelNemoCTC:       {files: []modelFile{singleModelFile}, offline: true},
+	ModelMoonshine:     {files: []modelFile{preprocessorFile, encoderFile, uncachedDecoderFile, cachedDecoderFile}, offline: true},
+	ModelFireRedASR:    {files: []modelFile{encoderFile, decoderFile}, offline: true},
+	ModelDolphin:       {files: []modelFile{singleModelFile}, offline: true},
+	ModelTDNN:          {files: []modelFile{singleModelFile}, offline: true},
+}
+
+// 离线 paraformer 只有一个模型文件，与流式版本不同
+var offlineParaformerFiles = []modelFile{singleModelFile}
+
+// NormalizeModelType 统一模型类型的写法，sense_voice 与 sense-voice 等价
+func NormalizeModelType(modelType string) string {
+	modelType = strings.ToLower(strings.TrimSpace(modelType))
+	modelType = strings.ReplaceAll(modelType, "_", "-")
+	if modelType == "" {
+		return ModelTransducer
+	}
+	return modelType
+}
+
+// SupportsOnline 返回模型类型是否有流式实现
+func SupportsOnline(modelType string) bool {
+	return modelFamilies[NormalizeModelType(modelType)].online
+}
+
+// files 返回指定识别方式下模型需要的文件
+func (m *ModelConfig) files(online bool) ([]modelFile, error) {
+	modelType := NormalizeModelType(m.Type)
+	family, ok := modelFamilies[modelType]
+	if !ok {
+		return nil, fmt.Errorf("不支持的模型类型: %s", m.Type)
+	}
+
+	if online && !family.online {
+		return nil, fmt.Errorf("模型类型 %s 不支持流式识别", modelType)
+	}
+	if !online && !family.offline {
+		return nil, fmt.Errorf("模型类型 %s 不支持离线识别", modelType)
+	}
+	if !online && modelType == ModelParaformer {
+		return offlineParaformerFiles, nil
+	}
+	return family.files, nil
+}
+
+// resolve 补全默认文件路径并检查模型文件是否存在
+func (m ModelConfig) resolve(online bool) (ModelConfig, error) {
+	files, err := m.files(online)
+	if err != nil {
+		return m, err
+	}
+	m.Type = NormalizeModelType(m.Type)
+
+	if m.Tokens == "" {
+		m.Tokens = filepath.Join(m.Dir, "tokens.txt")
+	}
+	for _, f := range files {
+		path := f.field(&m)
+		if *path == "" {
+			*path = filepath.Join(m.Dir, f.defaultName)
+		}
+		if _, err := os.Stat(*path); err != nil {
+			return m, fmt.Errorf("%s 模型缺少 %s 文件: %v", m.Type, f.name, err)
+		}
+	}
+	if _, err := os.Stat(m.Tokens); err != nil {
+		return m, fmt.Errorf("%s 模型缺少 tokens 文件: %v", m.Type, err)
+	}
+
+	m = m.withDefaults()
+	if err := m.checkDecoding(m.HotwordsFile != ""); err != nil {
+		return m, err
+	}
+	if m.HotwordsFile != "" {
+		if _, err := os.Stat(m.HotwordsFile); err != nil {
+			return m, fmt.Errorf("热词文件不可用: %v", err)
+		}
+	}
+	return m, nil
+}
+
+// supportsHotwords 检查模型是否支持热词，sherpa-onnx 只对 transducer 模型实现了热词
+func (m ModelConfig) supportsHotwords() error {
+	if NormalizeModelType(m.Type) != ModelTransducer {
+		return fmt.Errorf("%w: %s 模型不支持热词", ErrInvalidOptions, NormalizeModelType(m.Type))
+	}
+	return nil
+}
+
+// withDefaults 补全解码参数的默认值
+func (m ModelConfig) withDefaults() ModelConfig {
+	if m.DecodingMethod == "" {
+		m.DecodingMethod = DecodingGreedySearch
+	}
+	if m.MaxActivePaths == 0 {
+		m.MaxActivePaths = defaultMaxActivePaths
+	}
+	if m.Endpoint.Rule1MinTrailingSilence == 0 {
+		m.Endpoint.Rule1MinTrailingSilence = defaultRule1MinTrailingSilence
+	}
+	if m.Endpoint.Rule2MinTrailingSilence == 0 {
+		m.Endpoint.Rule2MinTrailingSilence = defaultRule2MinTrailingSilence
+	}
+	if m.Endpoint.Rule3MinUtteranceLength == 0 {
+		m.Endpoint.Rule3MinUtteranceLength = defaultRule3MinUtteranceLength
+	}
+	return m
+}
+
+// checkDecoding 检查解码参数的取值以及模型是否支持，hotwords 表示使用了热词
+func (m ModelConfig) checkDecoding(hotwords bool) error {
+	if hotwords {
+		if err := m.supportsHotwords(); err != nil {
+			return err
+		}
+	}
+	switch m.DecodingMethod {
+	case DecodingGreedySearch:
+		if hotwords {
+			return fmt.Errorf("%w: 热词需要使用 modified_beam_search 解码，当前为 %s", ErrInvalidOptions, m.DecodingMethod)
+		}
+	case DecodingModifiedBeamSearch:
+		if NormalizeModelType(m.Type) != ModelTransducer {
+			return fmt.Errorf("%w: %s 模型不支持 modified_beam_search 解码", ErrInvalidOptions, NormalizeModelType(m.Type))
+		}
+	default:
+		return fmt.Errorf("%w: 不支持的解码方法: %s", ErrInvalidOptions, m.DecodingMethod)
+	}
+	if m.MaxActivePaths < 1 || m.MaxActivePaths > maxActivePaths {
+		return fmt.Errorf("%w: max_active_paths 应在 1 到 %d 之间: %d", ErrInvalidOptions, maxActivePaths, m.MaxActivePaths)
+	}
+	return m.Endpoint.validate()
+}
+
+// withDecoding 返回按请求的解码选项修改后的模型配置
+// online 为 false 时忽略只对流式识别生效的端点检测选项；额外的热词由调用方写入热词文件
+func (m ModelConfig) withDecoding(opts DecodingOptions, online bool) (ModelConfig, error) {
+	m = m.withDefaults()
+	hotwords := len(opts.hotwords()) > 0
+
+	switch {
+	case opts.DecodingMethod != "":
+		m.DecodingMethod = opts.DecodingMethod
+	case hotwords:
+		m.DecodingMethod = DecodingModifiedBeamSearch
+	}
+	if opts.MaxActivePaths > 0 {
+		m.MaxActivePaths = opts.MaxActivePaths
+	}
+	// 没有热词时加分不影响解码
+	if (hotwords || m.HotwordsFile != "") && opts.HotwordsScore > 0 {
+		m.HotwordsScore = opts.HotwordsScore
+	}
+
+	if online {
+		if opts.EnableEndpoint != nil {
+			m.DisableEndpoint = !*opts.EnableEndpoint
+		}
+		if v := opts.Endpoint.Rule1MinTrailingSilence; v > 0 {
+			m.Endpoint.Rule1MinTrailingSilence = v
+		}
+		if v := opts.Endpoint.Rule2MinTrailingSilence; v > 0 {
+			m.Endpoint.Rule2MinTrailingSilence = v
+		}
+		if v := opts.Endpoint.Rule3MinUtteranceLength; v > 0 {
+			m.Endpoint.Rule3MinUtteranceLength = v
+		}
+	}
+
+	if err := m.checkDecoding(hotwords || m.HotwordsFile != ""); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// Validate 检查模型类型以及需要的文件
+// 流式模型按流式方式检查，仅有离线实现的模型按离线方式检查
+func (m ModelConfig) Validate() error {
+	_, err := m.resolve(SupportsOnline(m.Type))
+	return err
+}
+
+// newOnlineRecognizerConfig 生成流式识别器配置
+func newOnlineRecognizerConfig(m ModelConfig, sampleRate int) (*sherpa_onnx.OnlineRecognizerConfig, error) {
+	m, err := m.resolve(true)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &sherpa_onnx.OnlineRecognizerConfig{}
+
+	// 设置特征配置
+	config.FeatConfig.SampleRate = sampleRate
+	config.FeatConfig.FeatureDim = 80
+
+	// 设置模型配置
+	model := &config.ModelConfig
+	switch m.Type {
+	case ModelTransducer:
+		model.Transducer.Encoder = m.Encoder
+		model.Transducer.Decoder = m.Decoder
+		model.Transducer.Joiner = m.Joiner
+	case ModelParaformer:
+		model.Paraformer.Encoder = m.Encoder
+		model.Paraformer.Decoder = m.Decoder
+	case ModelZipformer2CTC:
+		model.Zipformer2Ctc.Model = m.Model
+	}
+	model.Tokens = m.Tokens
+	model.NumThreads = m.NumThreads
+	model.Provider = "cpu"
+	model.ModelingUnit = m.ModelingUnit
+	model.BpeVocab = m.BpeVocab
+
+	// 设置识别器配置
+	config.DecodingMethod = m.DecodingMethod
+	config.MaxActivePaths = m.MaxActivePaths
+	config.HotwordsFile = m.HotwordsFile
+	config.HotwordsScore = m.HotwordsScore
+	if !m.DisableEndpoint {
+		config.EnableEndpoint = 1
+	}
+	config.Rule1MinTrailingSilence = m.Endpoint.Rule1MinTrailingSilence
+	config.Rule2MinTrailingSilence = m.Endpoint.Rule2MinTrailingSilence
+	config.Rule3MinUtteranceLength = m.Endpoint.Rule3MinUtteranceLength
+
+	return config, nil
+}
+
+// newOfflineRecognizerConfig 生成离线识别器配置
+func newOfflineRecognizerConfig(m ModelConfig, sampleRate int) (*sherpa_onnx.OfflineRecognizerConfig, error) {
+	m, err := m.resolve(false)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &sherpa_onnx.OfflineRecognizerConfig{}
+
+	config.FeatConfig.SampleRate = sampleRate
+	config.FeatConfig.FeatureDim = 80
+
+	model := &config.ModelConfig
+	switch m.Type {
+	case ModelTransducer:
+		model.Transducer.Encoder = m.Encoder
+		model.Transducer.Decoder = m.Decoder
+		model.Transducer.Joiner = m.Joiner
+	case ModelParaformer:
+		model.Paraformer.Model = m.Model
+	case ModelWhisper:
+		model.Whisper.Encoder = m.Encoder
+		model.Whisper.Decoder = m.Decoder
+		model.Whisper.Language = m.Language
+		model.Whisper.Task = "transcribe"
+		model.Whisper.TailPaddings = -1
+	case ModelSenseVoice:
+		model.SenseVoice.Model = m.Model
+		model.SenseVoice.Language = m.Language
+		model.SenseVoice.UseInverseTextNormalization = 1
+	case ModelNemoCTC:
+		model.NemoCTC.Model = m.Model
+	case ModelMoonshine:
+		model.Moonshine.Preprocessor = m.Preprocessor
+		model.Moonshine.Encoder = m.Encoder
+		model.Moonshine.UncachedDecoder = m.UncachedDecoder
+		model.Moonshine.CachedDecoder = m.CachedDecoder
+	case ModelFireRedASR:
+		model.FireRedAsr.Encoder = m.Encoder
+		model.FireRedAsr.Decoder = m.Decoder
+	case ModelDolphin:
+		model.Dolphin.Model = m.Model
+	case ModelTDNN:
+		model.Tdnn.Model = m.Model
+	}
+	model.Tokens = m.Tokens
+	model.NumThreads = m.NumThreads
+	model.Provider = "cpu"
+	model.ModelingUnit = m.ModelingUnit
+	model.BpeVocab = m.BpeVocab
+
+	config.DecodingMethod = m.DecodingMethod
+	config.MaxActivePaths = m.MaxActivePaths
+	config.HotwordsFile = m.HotwordsFile
+	config.HotwordsScore = m.HotwordsScore
+
+	return config, nil
+}
@@ -0,0 +1,297 @@
+package transcribe
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// modelDir 在临时目录中创建给定的模型文件
+func modelDir(t *testing.T, files ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range append(files, "tokens.txt") {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("创建模型文件失败: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestNormalizeModelType(t *testing.T) {
+	tests := map[string]string{
+		"":               ModelTransducer,
+		"Whisper":        ModelWhisper,
+		"sense_voice":    ModelSenseVoice,
+		"zipformer2_ctc": ModelZipformer2CTC,
+		" nemo-ctc ":     ModelNemoCTC,
+	}
+	for input, expected := range tests {
+		if got := NormalizeModelType(input); got != expected {
+			t.Errorf("NormalizeModelType(%q) = %q，期望 %q", input, got, expected)
+		}
+	}
+}
+
+func TestModelConfigValidate(t *testing.T) {
+	t.Run("默认文件名", func(t *testing.T) {
+		dir := modelDir(t, "encoder.onnx", "decoder.onnx", "joiner.onnx")
+		if err := (ModelConfig{Type: ModelTransducer, Dir: dir}).Validate(); err != nil {
+			t.Errorf("校验失败: %v", err)
+		}
+	})
+
+	t.Run("显式指定文件", func(t *testing.T) {
+		dir := modelDir(t, "tiny-encoder.onnx", "tiny-decoder.onnx")
+		m := ModelConfig{
+			Type:    "whisper",
+			Dir:     dir,
+			Encoder: filepath.Join(dir, "tiny-encoder.onnx"),
+			Decoder: filepath.Join(dir, "tiny-decoder.onnx"),
+		}
+		if err := m.Validate(); err != nil {
+			t.Errorf("校验失败: %v", err)
+		}
+	})
+
+	t.Run("缺少文件", func(t *testing.T) {
+		dir := modelDir(t, "encoder.onnx", "decoder.onnx")
+		err := (ModelConfig{Type: ModelTransducer, Dir: dir}).Validate()
+		if err == nil || !strings.Contains(err.Error(), "joiner") {
+			t.Errorf("缺少 joiner 时应该返回错误，实际: %v", err)
+		}
+	})
+
+	t.Run("未知模型类型", func(t *testing.T) {
+		if err := (ModelConfig{Type: "wav2vec", Dir: t.TempDir()}).Validate(); err == nil {
+			t.Error("未知模型类型应该返回错误")
+		}
+	})
+}
+
+func TestNewOnlineRecognizerConfig(t *testing.T) {
+	dir := modelDir(t, "encoder.onnx", "decoder.onnx", "model.onnx")
+
+	config, err := newOnlineRecognizerConfig(ModelConfig{Type: ModelParaformer, Dir: dir}, 16000)
+	if err != nil {
+		t.Fatalf("创建配置失败: %v", err)
+	}
+	if config.ModelConfig.Paraformer.Encoder != filepath.Join(dir, "encoder.onnx") {
+		t.Errorf("paraformer encoder 路径错误: %s", config.ModelConfig.Paraformer.Encoder)
+	}
+	if config.DecodingMethod != "greedy_search" {
+		t.Errorf("默认解码方法错误: %s", config.DecodingMethod)
+	}
+
+	config, err = newOnlineRecognizerConfig(ModelConfig{Type: ModelZipformer2CTC, Dir: dir}, 16000)
+	if err != nil {
+		t.Fatalf("创建配置失败: %v", err)
+	}
+	if config.ModelConfig.Zipformer2Ctc.Model != filepath.Join(dir, "model.onnx") {
+		t.Errorf("zipformer2-ctc 模型路径错误: %s", config.ModelConfig.Zipformer2Ctc.Model)
+	}
+
+	if _, err := newOnlineRecognizerConfig(ModelConfig{Type: ModelWhisper, Dir: dir}, 16000); err == nil {
+		t.Error("whisper 没有流式实现，应该返回错误")
+	}
+}
+
+func TestNewOfflineRecognizerConfig(t *testing.T) {
+	dir := modelDir(t, "encoder.onnx", "decoder.onnx", "joiner.onnx", "model.onnx",
+		"preprocess.onnx", "uncached_decode.onnx", "cached_decode.onnx")
+
+	tests := []struct {
+		modelType string
+		field     func(m *ModelConfig) string
+		expected  string
+	}{
+		{ModelWhisper, func(m *ModelConfig) string { return m.Encoder }, "encoder.onnx"},
+		{ModelParaformer, func(m *ModelConfig) string { return m.Model }, "model.onnx"},
+		{"sense_voice", func(m *ModelConfig) string { return m.Model }, "model.onnx"},
+		{ModelTransducer, func(m *ModelConfig) string { return m.Joiner }, "joiner.onnx"},
+		{ModelMoonshine, func(m *ModelConfig) string { return m.CachedDecoder }, "cached_decode.onnx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.modelType, func(t *testing.T) {
+			m, err := ModelConfig{Type: tt.modelType, Dir: dir}.resolve(false)
+			if err != nil {
+				t.Fatalf("解析模型配置失败: %v", err)
+			}
+			if got := tt.field(&m); got != filepath.Join(dir, tt.expected) {
+				t.Errorf("模型文件路径错误: %s", got)
+			}
+			if _, err := newOfflineRecognizerConfig(ModelConfig{Type: tt.modelType, Dir: dir, Language: "zh"}, 16000); err != nil {
+				t.Errorf("创建配置失败: %v", err)
+			}
+		})
+	}
+
+	config, err := newOfflineRecognizerConfig(ModelConfig{Type: ModelWhisper, Dir: dir, Language: "zh"}, 16000)
+	if err != nil {
+		t.Fatalf("创建配置失败: %v", err)
+	}
+	if config.ModelConfig.Whisper.Language != "zh" {
+		t.Errorf("whisper 语言错误: %s", config.ModelConfig.Whisper.Language)
+	}
+
+	if _, err := newOfflineRecognizerConfig(ModelConfig{Type: ModelZipformer2CTC, Dir: dir}, 16000); err == nil {
+		t.Error("zipformer2-ctc 没有离线实现，应该返回错误")
+	}
+}
+
+func TestModelConfigHotwords(t *testing.T) {
+	dir := modelDir(t, "encoder.onnx", "decoder.onnx", "joiner.onnx", "model.onnx")
+	hotwords := filepath.Join(dir, "hotwords.txt")
+	if err := os.WriteFile(hotwords, []byte("语音识别\n"), 0o644); err != nil {
+		t.Fatalf("创建热词文件失败: %v", err)
+	}
+
+	t.Run("需要 modified_beam_search", func(t *testing.T) {
+		err := (ModelConfig{Type: ModelTransducer, Dir: dir, HotwordsFile: hotwords}).Validate()
+		if err == nil || !strings.Contains(err.Error(), "modified_beam_search") {
+			t.Errorf("greedy_search 使用热词时应该返回错误，实际: %v", err)
+		}
+	})
+
+	t.Run("只支持 transducer", func(t *testing.T) {
+		err := (ModelConfig{Type: ModelParaformer, Dir: dir, DecodingMethod: "modified_beam_search", HotwordsFile: hotwords}).Validate()
+		if !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("paraformer 使用热词时应该返回 ErrInvalidOptions，实际: %v", err)
+		}
+	})
+
+	t.Run("写入识别器配置", func(t *testing.T) {
+		m := ModelConfig{
+			Type:           ModelTransducer,
+			Dir:            dir,
+			DecodingMethod: "modified_beam_search",
+			HotwordsFile:   hotwords,
+			HotwordsScore:  2,
+			ModelingUnit:   "cjkchar",
+		}
+		config, err := newOnlineRecognizerConfig(m, 16000)
+		if err != nil {
+			t.Fatalf("创建配置失败: %v", err)
+		}
+		if config.HotwordsFile != hotwords || config.HotwordsScore != 2 || config.ModelConfig.ModelingUnit != "cjkchar" {
+			t.Errorf("热词配置错误: %s %g %s", config.HotwordsFile, config.HotwordsScore, config.ModelConfig.ModelingUnit)
+		}
+	})
+
+	t.Run("请求的热词", func(t *testing.T) {
+		base := ModelConfig{Type: ModelTransducer, Dir: dir, HotwordsFile: hotwords, HotwordsScore: 1.5}
+		m, err := base.withDecoding(DecodingOptions{Hotwords: []string{"转录"}, HotwordsScore: 3}, true)
+		if err != nil {
+			t.Fatalf("应用热词失败: %v", err)
+		}
+		if m.HotwordsFile != hotwords || m.HotwordsScore != 3 || m.DecodingMethod != DecodingModifiedBeamSearch {
+			t.Errorf("应用热词后的配置错误: %+v", m)
+		}
+
+		_, err = base.withDecoding(DecodingOptions{Hotwords: []string{"转录"}, DecodingMethod: DecodingGreedySearch}, true)
+		if !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("热词与 greedy_search 同时使用时期望 ErrInvalidOptions，实际: %v", err)
+		}
+	})
+}
+
+func TestModelConfigDecoding(t *testing.T) {
+	dir := modelDir(t, "encoder.onnx", "decoder.onnx", "joiner.onnx")
+
+	t.Run("默认值", func(t *testing.T) {
+		config, err := newOnlineRecognizerConfig(ModelConfig{Type: ModelTransducer, Dir: dir}, 16000)
+		if err != nil {
+			t.Fatalf("创建配置失败: %v", err)
+		}
+		if config.DecodingMethod != DecodingGreedySearch || config.MaxActivePaths != 4 || config.EnableEndpoint != 1 {
+			t.Errorf("解码配置错误: %s %d %d", config.DecodingMethod, config.MaxActivePaths, config.EnableEndpoint)
+		}
+		if config.Rule1MinTrailingSilence != 2.4 || config.Rule2MinTrailingSilence != 1.2 || config.Rule3MinUtteranceLength != 20 {
+			t.Errorf("端点规则错误: %g %g %g", config.Rule1MinTrailingSilence, config.Rule2MinTrailingSilence, config.Rule3MinUtteranceLength)
+		}
+	})
+
+	t.Run("配置的解码参数", func(t *testing.T) {
+		m := ModelConfig{
+			Type:            ModelTransducer,
+			Dir:             dir,
+			DecodingMethod:  DecodingModifiedBeamSearch,
+			MaxActivePaths:  8,
+			DisableEndpoint: true,
+			Endpoint:        EndpointRules{Rule2MinTrailingSilence: 0.8},
+		}
+		config, err := newOnlineRecognizerConfig(m, 16000)
+		if err != nil {
+			t.Fatalf("创建配置失败: %v", err)
+		}
+		if config.DecodingMethod != DecodingModifiedBeamSearch || config.MaxActivePaths != 8 || config.EnableEndpoint != 0 {
+			t.Errorf("解码配置错误: %s %d %d", config.DecodingMethod, config.MaxActivePaths, config.EnableEndpoint)
+		}
+		if config.Rule1MinTrailingSilence != 2.4 || config.Rule2MinTrailingSilence != 0.8 {
+			t.Errorf("端点规则错误: %g %g", config.Rule1MinTrailingSilence, config.Rule2MinTrailingSilence)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		model ModelConfig
+	}{
+		{"不支持的解码方法", ModelConfig{Type: ModelTransducer, Dir: dir, DecodingMethod: "beam_search"}},
+		{"paraformer 不支持 modified_beam_search", ModelConfig{Type: ModelParaformer, Dir: dir, DecodingMethod: DecodingModifiedBeamSearch}},
+		{"max_active_paths 过大", ModelConfig{Type: ModelTransducer, Dir: dir, MaxActivePaths: maxActivePaths + 1}},
+		{"负的端点规则", ModelConfig{Type: ModelTransducer, Dir: dir, Endpoint: EndpointRules{Rule1MinTrailingSilence: -1}}},
+		{"句子长度过大", ModelConfig{Type: ModelTransducer, Dir: dir, Endpoint: EndpointRules{Rule3MinUtteranceLength: maxUtteranceLength + 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.model.Validate(); !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("期望 ErrInvalidOptions，实际: %v", err)
+			}
+		})
+	}
+}
+
+func TestModelConfigWithDecoding(t *testing.T) {
+	base := ModelConfig{Type: ModelTransducer, DecodingMethod: DecodingGreedySearch}
+	enable := false
+	opts := DecodingOptions{
+		DecodingMethod: DecodingModifiedBeamSearch,
+		MaxActivePaths: 8,
+		EnableEndpoint: &enable,
+		Endpoint:       EndpointRules{Rule2MinTrailingSilence: 0.5},
+	}
+
+	m, err := base.withDecoding(opts, true)
+	if err != nil {
+		t.Fatalf("应用解码选项失败: %v", err)
+	}
+	if m.DecodingMethod != DecodingModifiedBeamSearch || m.MaxActivePaths != 8 || !m.DisableEndpoint || m.Endpoint.Rule2MinTrailingSilence != 0.5 {
+		t.Errorf("流式识别的解码配置错误: %+v", m)
+	}
+	if m.Endpoint.Rule1MinTrailingSilence != 2.4 {
+		t.Errorf("没有指定的规则应该使用默认值: %+v", m.Endpoint)
+	}
+
+	// 离线识别没有端点检测
+	m, err = base.withDecoding(opts, false)
+	if err != nil {
+		t.Fatalf("应用解码选项失败: %v", err)
+	}
+	if m.DisableEndpoint || m.Endpoint != base.withDefaults().Endpoint {
+		t.Errorf("离线识别不应该应用端点规则: %+v", m)
+	}
+
+	// 没有热词时加分不改变配置
+	if m, _ := base.withDecoding(DecodingOptions{HotwordsScore: 3}, true); m != base.withDefaults() {
+		t.Errorf("没有热词时不应该修改配置: %+v", m)
+	}
+
+	paraformer := ModelConfig{Type: ModelParaformer}
+	if _, err := paraformer.withDecoding(DecodingOptions{DecodingMethod: DecodingModifiedBeamSearch}, true); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("paraformer 使用 modified_beam_search 时期望 ErrInvalidOptions，实际: %v", err)
+	}
+}
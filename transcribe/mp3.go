@@ -0,0 +1,27 @@
+package transcribe
+
+import (
+	"bytes"
+)
+
+// DecodeMP3 解码 MPEG-1/2 Layer III 文件
+// go-mp3 固定输出 16 位双声道数据，这里混合为单声道
+func DecodeMP3(data []byte) (*Audio, error) {
+	if err := checkMPEGLayer(data); err != nil {
+		return nil, err
+	}
+	r, err := newMP3Reader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ReadAllSamples(r)
+}
+
+// checkMPEGLayer 检查声明为 mp3 的数据是否实际是 Layer I/II，go-mp3 只能解码 Layer III
+// header 是文件开始的 SniffSize 字节
+func checkMPEGLayer(header []byte) error {
+	if SniffFormat(header) == "mp2" {
+		return &UnsupportedFormatError{Format: "mp3", Reason: "只支持 Layer III，文件是 MPEG Layer I/II 音频"}
+	}
+	return nil
+}
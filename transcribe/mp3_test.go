@@ -0,0 +1,74 @@
+package transcribe
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// silentMP3 构造若干个静音的 MPEG-1 Layer III 单声道帧（128kbps, 44.1kHz）
+func silentMP3(frames int) []byte {
+	// 帧长度 = 144 * 128000 / 44100 = 417 字节，帧头之后的 side info 与主数据全为 0
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0xc0})
+	return bytes.Repeat(frame, frames)
+}
+
+// mpegFrames 构造若干个以 header 为帧头、其余内容全为 0 的 MPEG 音频帧
+func mpegFrames(header []byte, frames int) []byte {
+	frame := make([]byte, MPEGFrameSize(header))
+	copy(frame, header)
+	return bytes.Repeat(frame, frames)
+}
+
+func TestDecodeMP3(t *testing.T) {
+	audio, err := DecodeMP3(silentMP3(20))
+	if err != nil {
+		t.Fatalf("解码 MP3 失败: %v", err)
+	}
+	if audio.SampleRate != 44100 {
+		t.Errorf("采样率错误，期望: 44100, 实际: %d", audio.SampleRate)
+	}
+	if len(audio.Samples) == 0 || len(audio.Samples)%1152 != 0 {
+		t.Errorf("采样数应为 1152 的整数倍，实际: %d", len(audio.Samples))
+	}
+	for i, s := range audio.Samples {
+		if s != 0 {
+			t.Fatalf("静音帧解码后采样 %d 不为 0: %f", i, s)
+		}
+	}
+}
+
+func TestDecodeMP3Layer2(t *testing.T) {
+	// MPEG-1 Layer II 384kbps 32kHz
+	mp2 := mpegFrames([]byte{0xff, 0xfd, 0xe8, 0xc0}, 2)
+
+	decoders := map[string]func() error{
+		"DecodeMP3": func() error {
+			_, err := DecodeMP3(mp2)
+			return err
+		},
+		"自动识别格式": func() error {
+			_, err := DecodeAudio(mp2, "", 0)
+			return err
+		},
+		"增量解码": func() error {
+			_, err := NewAudioReader(bytes.NewReader(mp2), "mp3", 0)
+			return err
+		},
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			var unsupported *UnsupportedFormatError
+			if err := decode(); !errors.As(err, &unsupported) {
+				t.Errorf("期望 UnsupportedFormatError，得到 %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeMP3Invalid(t *testing.T) {
+	if _, err := DecodeMP3([]byte("not an mp3 file")); err == nil {
+		t.Error("无效的 MP3 数据期望返回错误")
+	}
+}
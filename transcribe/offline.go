@@ -0,0 +1,142 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// 批量转录使用的识别模式
+const (
+	// ModeOnline 使用流式识别器（OnlineRecognizer）
+	ModeOnline = "online"
+	// ModeOffline 使用非流式识别器（OfflineRecognizer），整段音频一次解码，准确率更高
+	ModeOffline = "offline"
+)
+
+// ErrModeUnavailable 请求的识别模式没有加载对应的识别器
+var ErrModeUnavailable = errors.New("识别模式不可用")
+
+// EnableOfflineRecognizer 加载离线识别器，用于批量转录
+// 实时转录仍然使用流式识别器
+func (st *SherpaTranscriber) EnableOfflineRecognizer(model ModelConfig) error {
+	config, err := newOfflineRecognizerConfig(model, st.sampleRate)
+	if err != nil {
+		return err
+	}
+
+	recognizer := sherpa_onnx.NewOfflineRecognizer(config)
+	if recognizer == nil {
+		return fmt.Errorf("无法加载离线识别模型: %s", model.Type)
+	}
+
+	if st.offlineRecognizer != nil {
+		sherpa_onnx.DeleteOfflineRecognizer(st.offlineRecognizer)
+	}
+	st.offlineRecognizer = recognizer
+	st.offlineConfig = config
+	st.offlineModel = model
+	return nil
+}
+
+// SetBatchMode 设置批量转录默认使用的识别模式
+func (st *SherpaTranscriber) SetBatchMode(mode string) error {
+	switch mode {
+	case "":
+		// 保留模型默认的模式
+		return nil
+	case ModeOnline:
+		if st.recognizer == nil {
+			return fmt.Errorf("%w: 当前模型不支持流式识别", ErrModeUnavailable)
+		}
+	case ModeOffline:
+		if st.offlineRecognizer == nil {
+			return fmt.Errorf("%w: 离线识别器未启用", ErrModeUnavailable)
+		}
+	default:
+		return fmt.Errorf("无效的识别模式: %s", mode)
+	}
+	st.batchMode = mode
+	return nil
+}
+
+// batchRecognizer 一次批量转录使用的识别器
+type batchRecognizer struct {
+	// online 流式模式下的识别器，离线模式时为空
+	online *sherpa_onnx.OnlineRecognizer
+	// recognize 识别一段音频
+	recognize func([]float32) (recognition, error)
+	// release 转录结束后归还识别器
+	release func()
+}
+
+// recognizerFor 返回请求的识别模式和解码选项对应的识别器
+func (st *SherpaTranscriber) recognizerFor(opts TranscribeOptions) (*batchRecognizer, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = st.batchMode
+	}
+
+	switch mode {
+	case "", ModeOnline:
+		if st.recognizer == nil {
+			return nil, fmt.Errorf("%w: 识别器未初始化", ErrModeUnavailable)
+		}
+		recognizers, release, err := st.recognizers(ModeOnline, opts.DecodingOptions)
+		if err != nil {
+			return nil, err
+		}
+		return &batchRecognizer{
+			online: recognizers.online,
+			recognize: func(samples []float32) (recognition, error) {
+				return st.recognizeSamples(recognizers.online, samples)
+			},
+			release: release,
+		}, nil
+	case ModeOffline:
+		if st.offlineRecognizer == nil {
+			return nil, fmt.Errorf("%w: 离线识别器未启用", ErrModeUnavailable)
+		}
+		recognizers, release, err := st.recognizers(ModeOffline, opts.DecodingOptions)
+		if err != nil {
+			return nil, err
+		}
+		return &batchRecognizer{
+			recognize: func(samples []float32) (recognition, error) {
+				return st.recognizeOffline(recognizers.offline, samples)
+			},
+			release: release,
+		}, nil
+	default:
+		return nil, fmt.Errorf("无效的识别模式: %s", mode)
+	}
+}
+
+// recognizeOffline 用离线识别器一次性解码整段音频
+func (st *SherpaTranscriber) recognizeOffline(recognizer *sherpa_onnx.OfflineRecognizer, samples []float32) (recognition, error) {
+	if len(samples) == 0 {
+		return recognition{}, nil
+	}
+
+	stream := sherpa_onnx.NewOfflineStream(recognizer)
+	if stream == nil {
+		return recognition{}, fmt.Errorf("创建离线音频流失败")
+	}
+	defer sherpa_onnx.DeleteOfflineStream(stream)
+
+	sampleRate := st.offlineConfig.FeatConfig.SampleRate
+	stream.AcceptWaveform(sampleRate, samples)
+	recognizer.Decode(stream)
+
+	result := stream.GetResult()
+	if result == nil {
+		return recognition{}, nil
+	}
+	return recognition{
+		text: strings.TrimSpace(result.Text),
+		// sherpa-onnx-go 的离线结果不包含 token 概率
+		tokens: newTokens(result.Tokens, result.Timestamps, nil, float64(len(samples))/float64(sampleRate)),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package transcribe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRecognizerForMode(t *testing.T) {
+	// 没有加载任何识别器
+	st := &SherpaTranscriber{}
+
+	if _, err := st.recognizerFor(TranscribeOptions{Mode: ModeOffline}); !errors.Is(err, ErrModeUnavailable) {
+		t.Errorf("离线识别器未加载时应该返回 ErrModeUnavailable，实际: %v", err)
+	}
+	if _, err := st.recognizerFor(TranscribeOptions{Mode: ModeOnline}); !errors.Is(err, ErrModeUnavailable) {
+		t.Errorf("流式识别器未加载时应该返回 ErrModeUnavailable，实际: %v", err)
+	}
+	if _, err := st.recognizerFor(TranscribeOptions{Mode: "batch"}); err == nil || errors.Is(err, ErrModeUnavailable) {
+		t.Errorf("无效模式应该返回参数错误，实际: %v", err)
+	}
+}
+
+func TestSetBatchMode(t *testing.T) {
+	st := &SherpaTranscriber{}
+
+	if err := st.SetBatchMode(""); err != nil {
+		t.Errorf("空模式应该保留默认设置: %v", err)
+	}
+	if err := st.SetBatchMode(ModeOnline); !errors.Is(err, ErrModeUnavailable) {
+		t.Errorf("流式识别器未加载时不应该允许 online 模式，实际: %v", err)
+	}
+	if err := st.SetBatchMode(ModeOffline); !errors.Is(err, ErrModeUnavailable) {
+		t.Errorf("离线识别器未加载时不应该允许 offline 模式，实际: %v", err)
+	}
+	if err := st.SetBatchMode("batch"); err == nil {
+		t.Error("无效模式应该返回错误")
+	}
+	if st.batchMode != "" {
+		t.Errorf("设置失败后不应该修改当前模式，实际: %s", st.batchMode)
+	}
+}
@@ -0,0 +1,211 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// OggStream Ogg 容器中的一个逻辑流
+type OggStream struct {
+	Serial uint32
+	// Packets 按顺序排列的完整数据包，第一个包是编码器的识别头
+	Packets [][]byte
+	// Granule 最后一页的 granule position，对 Opus/Vorbis 来说即采样数
+	Granule int64
+}
+
+// OggCodecDecoder 解码 Ogg 容器中某种编码的逻辑流
+type OggCodecDecoder func(stream *OggStream) (*Audio, error)
+
+var (
+	oggCodecsMu sync.RWMutex
+	oggCodecs   = map[string]OggCodecDecoder{}
+)
+
+func init() {
+	RegisterOggCodec("opus", DecodeOpus)
+	RegisterOggCodec("vorbis", DecodeVorbis)
+}
+
+// RegisterOggCodec 注册 Ogg 容器内某种编码的解码器，已存在时覆盖
+// 内置 Opus 和 Vorbis 解码器，FLAC、Speex 等其他编码可以由调用方注册
+func RegisterOggCodec(codec string, decoder OggCodecDecoder) {
+	oggCodecsMu.Lock()
+	defer oggCodecsMu.Unlock()
+
+	oggCodecs[codec] = decoder
+}
+
+func lookupOggCodec(codec string) (OggCodecDecoder, bool) {
+	oggCodecsMu.RLock()
+	defer oggCodecsMu.RUnlock()
+
+	decoder, ok := oggCodecs[codec]
+	return decoder, ok
+}
+
+// DecodeOgg 解析 Ogg 容器，根据识别头把第一个逻辑流交给对应编码的解码器
+func DecodeOgg(data []byte) (*Audio, error) {
+	stream, err := ReadOggStream(data)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := OggCodec(stream.Packets[0])
+	if codec == "" {
+		return nil, &UnsupportedFormatError{Format: "ogg", Reason: "无法识别的编码"}
+	}
+
+	decoder, ok := lookupOggCodec(codec)
+	if !ok {
+		return nil, &UnsupportedFormatError{Format: "ogg", Reason: fmt.Sprintf("未注册 %s 解码器", codec)}
+	}
+	return decoder(stream)
+}
+
+// OggCodec 根据逻辑流的第一个数据包识别编码
+func OggCodec(packet []byte) string {
+	switch {
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		return "opus"
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		return "vorbis"
+	case bytes.HasPrefix(packet, []byte("\x7fFLAC")):
+		return "flac"
+	case bytes.HasPrefix(packet, []byte("Speex   ")):
+		return "speex"
+	}
+	return ""
+}
+
+// ReadOggStream 读取 Ogg 文件中第一个逻辑流的全部数据包
+func ReadOggStream(data []byte) (*OggStream, error) {
+	var stream *OggStream
+	var pending []byte
+
+	for offset := 0; offset < len(data); {
+		page, err := parseOggPage(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("解析 Ogg 页（偏移 %d）失败: %v", offset, err)
+		}
+		offset += page.size
+
+		if stream == nil {
+			if page.headerType&oggBeginOfStream == 0 {
+				return nil, fmt.Errorf("Ogg 文件的第一页不是流起始页")
+			}
+			stream = &OggStream{Serial: page.serial}
+		}
+		// 只保留第一个逻辑流，其余复用的流忽略
+		if page.serial != stream.Serial {
+			continue
+		}
+
+		body := page.body
+		for _, lacing := range page.segments {
+			pending = append(pending, body[:lacing]...)
+			body = body[lacing:]
+			// 长度小于 255 的段表示数据包结束
+			if lacing < 255 {
+				stream.Packets = append(stream.Packets, pending)
+				pending = nil
+			}
+		}
+		if page.granule >= 0 {
+			stream.Granule = page.granule
+		}
+		if page.headerType&oggEndOfStream != 0 {
+			break
+		}
+	}
+
+	if stream == nil || len(stream.Packets) == 0 {
+		return nil, fmt.Errorf("Ogg 文件中没有数据包")
+	}
+	return stream, nil
+}
+
+// Ogg 页头标志
+const (
+	oggBeginOfStream = 0x02
+	oggEndOfStream   = 0x04
+)
+
+type oggPage struct {
+	headerType byte
+	granule    int64
+	serial     uint32
+	segments   []int
+	body       []byte
+	size       int
+}
+
+func parseOggPage(data []byte) (*oggPage, error) {
+	if len(data) < 27 || string(data[0:4]) != "OggS" {
+		return nil, fmt.Errorf("缺少 OggS 同步标记")
+	}
+	if data[4] != 0 {
+		return nil, fmt.Errorf("不支持的 Ogg 版本 %d", data[4])
+	}
+
+	nsegs := int(data[26])
+	headerSize := 27 + nsegs
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("Ogg 页头被截断")
+	}
+
+	page := &oggPage{
+		headerType: data[5],
+		granule:    int64(binary.LittleEndian.Uint64(data[6:14])),
+		serial:     binary.LittleEndian.Uint32(data[14:18]),
+		segments:   make([]int, nsegs),
+	}
+
+	bodySize := 0
+	for i := 0; i < nsegs; i++ {
+		page.segments[i] = int(data[27+i])
+		bodySize += page.segments[i]
+	}
+	page.size = headerSize + bodySize
+	if len(data) < page.size {
+		return nil, fmt.Errorf("Ogg 页数据被截断")
+	}
+	page.body = data[headerSize:page.size]
+
+	// 校验 CRC，计算时 CRC 字段按 0 处理
+	expected := binary.LittleEndian.Uint32(data[22:26])
+	crc := oggCRC(0, data[:22])
+	crc = oggCRC(crc, []byte{0, 0, 0, 0})
+	crc = oggCRC(crc, data[26:page.size])
+	if crc != expected {
+		return nil, fmt.Errorf("Ogg 页 CRC 校验失败")
+	}
+
+	return page, nil
+}
+
+// Ogg 使用多项式 0x04c11db7 的非反射 CRC32
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggCRC(crc uint32, data []byte) uint32 {
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
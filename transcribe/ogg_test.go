@@ -0,0 +1,126 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// oggPageBytes 构造一个 Ogg 页，segments 为各段的数据
+func oggPageBytes(headerType byte, granule int64, serial, seq uint32, lacing []int, body []byte) []byte {
+	var page bytes.Buffer
+	page.WriteString("OggS")
+	page.WriteByte(0)
+	page.WriteByte(headerType)
+	binary.Write(&page, binary.LittleEndian, granule)
+	binary.Write(&page, binary.LittleEndian, serial)
+	binary.Write(&page, binary.LittleEndian, seq)
+	binary.Write(&page, binary.LittleEndian, uint32(0))
+	page.WriteByte(byte(len(lacing)))
+	for _, l := range lacing {
+		page.WriteByte(byte(l))
+	}
+	page.Write(body)
+
+	data := page.Bytes()
+	binary.LittleEndian.PutUint32(data[22:26], oggCRC(0, data))
+	return data
+}
+
+// lacingFor 计算一个完整数据包的段表
+func lacingFor(packet []byte) []int {
+	var lacing []int
+	n := len(packet)
+	for n >= 255 {
+		lacing = append(lacing, 255)
+		n -= 255
+	}
+	return append(lacing, n)
+}
+
+func buildOpusFile(serial uint32) []byte {
+	head := append([]byte("OpusHead"), 1, 1, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	return buildOggFile(serial, head)
+}
+
+// buildOggFile 构造识别头为 head 的 Ogg 文件，音频数据包的内容是无效的
+func buildOggFile(serial uint32, head []byte) []byte {
+	tags := []byte("OpusTags")
+	audio := bytes.Repeat([]byte{0xaa}, 300)
+
+	var data []byte
+	data = append(data, oggPageBytes(oggBeginOfStream, 0, serial, 0, lacingFor(head), head)...)
+	data = append(data, oggPageBytes(0, 0, serial, 1, lacingFor(tags), tags)...)
+	// 300 字节的数据包跨越两页：第一页只有一个 255 段
+	data = append(data, oggPageBytes(0, -1, serial, 2, []int{255}, audio[:255])...)
+	data = append(data, oggPageBytes(0x01|oggEndOfStream, 960, serial, 3, []int{45}, audio[255:])...)
+	return data
+}
+
+func TestReadOggStream(t *testing.T) {
+	stream, err := ReadOggStream(buildOpusFile(7))
+	if err != nil {
+		t.Fatalf("解析 Ogg 失败: %v", err)
+	}
+	if stream.Serial != 7 {
+		t.Errorf("流序号错误，期望: 7, 实际: %d", stream.Serial)
+	}
+	if len(stream.Packets) != 3 {
+		t.Fatalf("数据包数量错误，期望: 3, 实际: %d", len(stream.Packets))
+	}
+	if len(stream.Packets[2]) != 300 {
+		t.Errorf("跨页数据包长度错误，期望: 300, 实际: %d", len(stream.Packets[2]))
+	}
+	if stream.Granule != 960 {
+		t.Errorf("granule 错误，期望: 960, 实际: %d", stream.Granule)
+	}
+	if codec := OggCodec(stream.Packets[0]); codec != "opus" {
+		t.Errorf("编码识别错误，期望: opus, 实际: %s", codec)
+	}
+}
+
+func TestReadOggStreamCorrupted(t *testing.T) {
+	data := buildOpusFile(1)
+	data[len(data)-1] ^= 0xff
+
+	if _, err := ReadOggStream(data); err == nil {
+		t.Error("CRC 错误时期望返回错误")
+	}
+}
+
+func TestDecodeOggCodecRegistry(t *testing.T) {
+	data := buildOggFile(1, []byte("Speex   1.2"))
+
+	// 未注册解码器时返回 UnsupportedFormatError
+	_, err := DecodeAudio(data, "ogg", 0)
+	var unsupported *UnsupportedFormatError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("期望 UnsupportedFormatError，实际: %v", err)
+	}
+
+	RegisterOggCodec("speex", func(stream *OggStream) (*Audio, error) {
+		return &Audio{Samples: make([]float32, stream.Granule), SampleRate: 48000}, nil
+	})
+	defer func() {
+		oggCodecsMu.Lock()
+		delete(oggCodecs, "speex")
+		oggCodecsMu.Unlock()
+	}()
+
+	audio, err := DecodeAudio(data, "", 0)
+	if err != nil {
+		t.Fatalf("解码 Ogg Speex 失败: %v", err)
+	}
+	if audio.SampleRate != 48000 || len(audio.Samples) != 960 {
+		t.Errorf("解码结果错误，采样率: %d, 采样数: %d", audio.SampleRate, len(audio.Samples))
+	}
+}
+
+func TestBuiltinOggCodecs(t *testing.T) {
+	for _, codec := range []string{"opus", "vorbis"} {
+		if _, ok := lookupOggCodec(codec); !ok {
+			t.Errorf("%s 解码器没有注册", codec)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package transcribe
+
+/*
+#include <stdlib.h>
+
+// sherpa-onnx C 接口（c-api.h），库由 sherpa-onnx-go 链接
+const char *SherpaOnnxGetOnlineStreamResultAsJson(const void *recognizer, const void *stream);
+void SherpaOnnxDestroyOnlineStreamResultJson(const char *s);
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// onlineResult 流式识别结果的 JSON 形式
+// sherpa-onnx-go 的 GetResult 只返回文本，token、时间戳和 token 的对数概率（ys_probs）只能从 C 接口的 JSON 中取得
+type onlineResult struct {
+	Text       string    `json:"text"`
+	Tokens     []string  `json:"tokens"`
+	Timestamps []float32 `json:"timestamps"`
+	// YsProbs 每个 token 的对数概率，只有 transducer 模型提供
+	YsProbs []float32 `json:"ys_probs"`
+}
+
+// cHandle 返回 sherpa-onnx-go 对象中保存的 C 指针
+// OnlineRecognizer 和 OnlineStream 都只有一个未导出的 impl 字段，TestCHandleLayout 检查这一点
+func cHandle[T sherpa_onnx.OnlineRecognizer | sherpa_onnx.OnlineStream](v *T) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(v))
+}
+
+// getOnlineResult 读取流当前的完整识别结果
+func getOnlineResult(recognizer *sherpa_onnx.OnlineRecognizer, stream *sherpa_onnx.OnlineStream) (*onlineResult, error) {
+	p := C.SherpaOnnxGetOnlineStreamResultAsJson(cHandle(recognizer), cHandle(stream))
+	if p == nil {
+		return nil, fmt.Errorf("获取识别结果失败")
+	}
+	defer C.SherpaOnnxDestroyOnlineStreamResultJson(p)
+
+	return parseOnlineResult([]byte(C.GoString(p)))
+}
+
+func parseOnlineResult(data []byte) (*onlineResult, error) {
+	var result onlineResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析识别结果失败: %v", err)
+	}
+	return &result, nil
+}
+
+// recognition 把流式识别结果转换为文本和 token，duration 为音频时长（秒）
+// token 的时间戳相对于当前句子的开始，流重置后从 0 开始
+func (r *onlineResult) recognition(duration float64) recognition {
+	return recognition{
+		text:   strings.TrimSpace(r.Text),
+		tokens: newTokens(r.Tokens, r.Timestamps, r.YsProbs, duration),
+	}
+}
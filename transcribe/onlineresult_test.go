@@ -0,0 +1,58 @@
+package transcribe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+func TestCHandleLayout(t *testing.T) {
+	// cHandle 依赖 sherpa-onnx-go 的对象只有一个指针字段，升级 sherpa-onnx-go 后需要重新确认
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(sherpa_onnx.OnlineRecognizer{}),
+		reflect.TypeOf(sherpa_onnx.OnlineStream{}),
+	} {
+		if typ.NumField() != 1 || typ.Field(0).Type.Kind() != reflect.Pointer {
+			t.Errorf("%s 的结构与 cHandle 的假设不符", typ)
+		}
+	}
+}
+
+func TestParseOnlineResult(t *testing.T) {
+	// SherpaOnnxGetOnlineStreamResultAsJson 对 transducer 模型返回的格式
+	data := []byte(`{"text": " HELLO WORLD", "tokens": [" HE", "LLO", " WORLD"], ` +
+		`"timestamps": [0.00, 0.32, 0.64], "ys_probs": [-0.105361, -0.916291, 0.000000], ` +
+		`"lm_probs": [], "context_scores": [], "segment": 0, "words": [], ` +
+		`"start_time": 0.00, "is_final": false, "is_eof": false}`)
+
+	result, err := parseOnlineResult(data)
+	if err != nil {
+		t.Fatalf("解析识别结果失败: %v", err)
+	}
+
+	rec := result.recognition(1)
+	if rec.text != "HELLO WORLD" {
+		t.Errorf("文本错误: %q", rec.text)
+	}
+	if len(rec.tokens) != 3 || rec.tokens[1].Start != 0.32 || rec.tokens[2].End != 1 {
+		t.Fatalf("token 错误: %+v", rec.tokens)
+	}
+	if math.Abs(rec.tokens[0].Confidence-0.9) > 1e-4 || math.Abs(rec.tokens[1].Confidence-0.4) > 1e-4 {
+		t.Errorf("token 置信度错误: %+v", rec.tokens)
+	}
+
+	// paraformer 等模型不提供 ys_probs，只有时间戳
+	result, err = parseOnlineResult([]byte(`{"text": "你好", "tokens": ["你", "好"], "timestamps": [0.1, 0.3], "ys_probs": []}`))
+	if err != nil {
+		t.Fatalf("解析识别结果失败: %v", err)
+	}
+	if rec := result.recognition(1); len(rec.tokens) != 2 || rec.tokens[0].Confidence != 0 {
+		t.Errorf("没有概率时 token 错误: %+v", rec.tokens)
+	}
+
+	if _, err := parseOnlineResult([]byte("{")); err == nil {
+		t.Error("无效的 JSON 期望返回错误")
+	}
+}
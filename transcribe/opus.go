@@ -0,0 +1,97 @@
+package transcribe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/pion/opus"
+)
+
+const (
+	// opusSampleRate Opus 的 granule position 和 pre-skip 总是按 48kHz 计数
+	opusSampleRate = 48000
+	// opusMaxFrameSamples 一个 Opus 数据包最多 120ms 音频
+	opusMaxFrameSamples = opusSampleRate * 120 / 1000
+)
+
+// opusHead 对应 Ogg Opus 的 OpusHead 识别头（RFC 7845 第 5.1 节）
+type opusHead struct {
+	channels int
+	preSkip  int
+	// outputGain Q7.8 格式的输出增益（dB）
+	outputGain    int16
+	mappingFamily byte
+}
+
+func parseOpusHead(packet []byte) (*opusHead, error) {
+	if len(packet) < 19 || string(packet[:8]) != "OpusHead" {
+		return nil, fmt.Errorf("OpusHead 识别头无效")
+	}
+	// 主版本号不同的流无法解析，次版本号的变化向后兼容
+	if packet[8]&0xf0 != 0 {
+		return nil, &UnsupportedFormatError{Format: "opus", Reason: fmt.Sprintf("版本 %d", packet[8])}
+	}
+
+	return &opusHead{
+		channels:      int(packet[9]),
+		preSkip:       int(binary.LittleEndian.Uint16(packet[10:12])),
+		outputGain:    int16(binary.LittleEndian.Uint16(packet[16:18])),
+		mappingFamily: packet[18],
+	}, nil
+}
+
+// DecodeOpus 解码 Ogg 容器中的 Opus 流，输出 48kHz 单声道
+// 只支持映射族 0（单声道或立体声），立体声平均混合为单声道
+func DecodeOpus(stream *OggStream) (*Audio, error) {
+	head, err := parseOpusHead(stream.Packets[0])
+	if err != nil {
+		return nil, err
+	}
+	if head.mappingFamily != 0 || head.channels < 1 || head.channels > 2 {
+		return nil, &UnsupportedFormatError{
+			Format: "opus",
+			Reason: fmt.Sprintf("映射族 %d、%d 声道（只支持单声道和立体声）", head.mappingFamily, head.channels),
+		}
+	}
+	if len(stream.Packets) < 2 {
+		return nil, fmt.Errorf("Opus 流缺少 OpusTags 注释头")
+	}
+
+	decoder, err := opus.NewDecoderWithOutput(opusSampleRate, head.channels)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Opus 解码器失败: %v", err)
+	}
+
+	gain := float32(math.Pow(10, float64(head.outputGain)/(20*256)))
+	scale := gain / float32(head.channels)
+	frame := make([]float32, opusMaxFrameSamples*head.channels)
+
+	var samples []float32
+	// 第二个包是 OpusTags 注释头，之后都是音频包
+	for i, packet := range stream.Packets[2:] {
+		if len(packet) == 0 {
+			continue
+		}
+		n, err := decoder.DecodeToFloat32(packet, frame)
+		if err != nil {
+			return nil, fmt.Errorf("解码第 %d 个 Opus 数据包失败: %v", i, err)
+		}
+		for j := 0; j < n; j++ {
+			var sum float32
+			for ch := 0; ch < head.channels; ch++ {
+				sum += frame[j*head.channels+ch]
+			}
+			samples = append(samples, sum*scale)
+		}
+	}
+
+	// 开头的 pre-skip 是编码器的预热数据，结尾按最后一页的 granule position 截断
+	end := len(samples)
+	if stream.Granule > 0 && stream.Granule < int64(end) {
+		end = int(stream.Granule)
+	}
+	start := min(head.preSkip, end)
+
+	return &Audio{Samples: samples[start:end], SampleRate: opusSampleRate}, nil
+}
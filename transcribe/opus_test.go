@@ -0,0 +1,67 @@
+package transcribe
+
+import (
+	"errors"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestDecodeOpus(t *testing.T) {
+	// testdata/opus.ogg 由 libopus 编码，单声道，pre-skip 312，最后的 granule position 为 591
+	data, err := os.ReadFile("testdata/opus.ogg")
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	audio, err := DecodeAudio(data, "opus", 0)
+	if err != nil {
+		t.Fatalf("解码 Ogg Opus 失败: %v", err)
+	}
+	if audio.SampleRate != 48000 {
+		t.Errorf("采样率错误，期望: 48000, 实际: %d", audio.SampleRate)
+	}
+	if len(audio.Samples) != 591-312 {
+		t.Errorf("采样数错误，期望: %d, 实际: %d", 591-312, len(audio.Samples))
+	}
+
+	var energy float64
+	for _, s := range audio.Samples {
+		if s < -1 || s > 1 {
+			t.Fatalf("采样超出范围: %f", s)
+		}
+		energy += float64(s * s)
+	}
+	if rms := math.Sqrt(energy / float64(len(audio.Samples))); rms < 0.01 {
+		t.Errorf("解码结果接近静音，RMS: %f", rms)
+	}
+}
+
+func TestDecodeOpusUnsupported(t *testing.T) {
+	tags := []byte("OpusTags")
+	cases := []struct {
+		name string
+		head []byte
+	}{
+		// 映射族 1，6 声道
+		{"多声道", append([]byte("OpusHead"), 1, 6, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 1)},
+		{"主版本号", append([]byte("OpusHead"), 0x10, 1, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeOpus(&OggStream{Packets: [][]byte{tc.head, tags}})
+			var unsupported *UnsupportedFormatError
+			if !errors.As(err, &unsupported) {
+				t.Errorf("期望 UnsupportedFormatError，实际: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeOpusMissingTags(t *testing.T) {
+	head := append([]byte("OpusHead"), 1, 1, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	if _, err := DecodeOpus(&OggStream{Packets: [][]byte{head}}); err == nil {
+		t.Error("缺少 OpusTags 时期望返回错误")
+	}
+}
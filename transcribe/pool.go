@@ -0,0 +1,275 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull 排队的请求已达上限
+	ErrQueueFull = errors.New("转录队列已满")
+	// ErrQueueTimeout 排队等待解码槽位超时
+	ErrQueueTimeout = errors.New("等待转录超时")
+	// ErrPoolClosed 转录池已关闭
+	ErrPoolClosed = errors.New("转录池已关闭")
+)
+
+var _ Transcriber = (*Pool)(nil)
+
+// PoolConfig 转录池配置
+type PoolConfig struct {
+	// Workers 同时进行的解码数量，默认 1
+	Workers int
+	// QueueSize 等待解码的请求数量上限，超过时返回 ErrQueueFull
+	QueueSize int
+	// QueueTimeout 排队等待的最长时间，为 0 时一直等待
+	QueueTimeout time.Duration
+	// MaxSessions 同时存在的流式会话数量上限，为 0 时不限制
+	MaxSessions int
+}
+
+// PoolStats 转录池的运行状态
+type PoolStats struct {
+	Workers     int `json:"workers"`
+	Busy        int `json:"busy"`
+	QueueDepth  int `json:"queue_depth"`
+	QueueSize   int `json:"queue_size"`
+	Sessions    int `json:"sessions"`
+	MaxSessions int `json:"max_sessions,omitempty"`
+}
+
+// Pool 限制并发解码数量的转录引擎
+// 批量转录占用一个解码槽位，槽位用完时请求进入有界队列排队；
+// 流式会话不占用槽位，只受 MaxSessions 限制
+type Pool struct {
+	transcriber Transcriber
+	config      PoolConfig
+
+	// slots 正在解码的请求，容量为 Workers
+	slots chan struct{}
+	// admitted 正在解码和排队的请求，容量为 Workers+QueueSize
+	admitted chan struct{}
+	done     chan struct{}
+	inflight sync.WaitGroup
+
+	mu        sync.Mutex
+	closed    bool
+	sessions  int
+	avgDecode time.Duration
+}
+
+// NewPool 用转录池包装转录引擎
+func NewPool(transcriber Transcriber, config PoolConfig) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	}
+
+	return &Pool{
+		transcriber: transcriber,
+		config:      config,
+		slots:       make(chan struct{}, config.Workers),
+		admitted:    make(chan struct{}, config.Workers+config.QueueSize),
+		done:        make(chan struct{}),
+	}
+}
+
+func (p *Pool) TranscribeAudio(audioData []byte, opts TranscribeOptions) (*TranscriptionResult, error) {
+	if err := p.acquire(opts.Context); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	start := time.Now()
+	result, err := p.transcriber.TranscribeAudio(audioData, opts)
+	p.observe(time.Since(start))
+	return result, err
+}
+
+// TranscribeReader 拿到解码槽位后才开始读取 r，排队期间不读取 r；
+// r 直接来自请求体时，还没上传的音频在排队期间留在连接中，不会缓存到内存或临时文件
+func (p *Pool) TranscribeReader(r io.Reader, opts TranscribeOptions) (*TranscriptionResult, error) {
+	if err := p.acquire(opts.Context); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	start := time.Now()
+	result, err := p.transcriber.TranscribeReader(r, opts)
+	p.observe(time.Since(start))
+	return result, err
+}
+
+// acquire 进入队列并等待解码槽位，ctx 不为空时取消后离开队列
+func (p *Pool) acquire(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	select {
+	case p.admitted <- struct{}{}:
+	default:
+		p.mu.Unlock()
+		return ErrQueueFull
+	}
+	p.inflight.Add(1)
+	p.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if p.config.QueueTimeout > 0 {
+		timer := time.NewTimer(p.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var canceled <-chan struct{}
+	if ctx != nil {
+		canceled = ctx.Done()
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-p.done:
+		p.leave()
+		return ErrPoolClosed
+	case <-timeout:
+		p.leave()
+		return ErrQueueTimeout
+	case <-canceled:
+		p.leave()
+		return ctx.Err()
+	}
+}
+
+// release 释放解码槽位并离开队列
+func (p *Pool) release() {
+	<-p.slots
+	p.leave()
+}
+
+func (p *Pool) leave() {
+	<-p.admitted
+	p.inflight.Done()
+}
+
+// observe 记录解码耗时，用指数滑动平均估计单个请求的解码时间
+func (p *Pool) observe(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.avgDecode == 0 {
+		p.avgDecode = d
+		return
+	}
+	p.avgDecode = (p.avgDecode*4 + d) / 5
+}
+
+// RetryAfter 估计排队的请求全部处理完所需的时间，至少 1 秒
+func (p *Pool) RetryAfter() time.Duration {
+	stats := p.Stats()
+
+	p.mu.Lock()
+	avg := p.avgDecode
+	p.mu.Unlock()
+
+	wait := avg * time.Duration(stats.QueueDepth+1) / time.Duration(stats.Workers)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// Stats 返回转录池当前的运行状态
+func (p *Pool) Stats() PoolStats {
+	busy := len(p.slots)
+	depth := len(p.admitted) - busy
+	if depth < 0 {
+		depth = 0
+	}
+
+	p.mu.Lock()
+	sessions := p.sessions
+	p.mu.Unlock()
+
+	return PoolStats{
+		Workers:     p.config.Workers,
+		Busy:        busy,
+		QueueDepth:  depth,
+		QueueSize:   p.config.QueueSize,
+		Sessions:    sessions,
+		MaxSessions: p.config.MaxSessions,
+	}
+}
+
+// QueueDepth 返回正在排队等待解码的请求数量
+func (p *Pool) QueueDepth() int {
+	return p.Stats().QueueDepth
+}
+
+func (p *Pool) NewSession(opts DecodingOptions) (Session, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	if p.config.MaxSessions > 0 && p.sessions >= p.config.MaxSessions {
+		p.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	p.sessions++
+	p.inflight.Add(1)
+	p.mu.Unlock()
+
+	session, err := p.transcriber.NewSession(opts)
+	if err != nil {
+		p.endSession()
+		return nil, err
+	}
+	return &poolSession{Session: session, pool: p}, nil
+}
+
+func (p *Pool) endSession() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sessions--
+	p.inflight.Done()
+}
+
+func (p *Pool) GetSampleRate() int {
+	return p.transcriber.GetSampleRate()
+}
+
+// Close 拒绝新的请求，唤醒排队的请求，等正在解码的请求和打开的流式会话都结束后关闭底层转录引擎
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	p.inflight.Wait()
+	return p.transcriber.Close()
+}
+
+// poolSession 关闭时归还会话名额
+type poolSession struct {
+	Session
+	pool *Pool
+	once sync.Once
+}
+
+func (s *poolSession) Close() error {
+	err := s.Session.Close()
+	s.once.Do(s.pool.endSession)
+	return err
+}
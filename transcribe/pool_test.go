@@ -0,0 +1,221 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFor 等待条件成立，超时则测试失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待条件成立超时")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPoolQueueFull(t *testing.T) {
+	fake := NewFakeTranscriber("测试文本")
+	fake.Gate = make(chan struct{})
+	pool := NewPool(fake, PoolConfig{Workers: 1, QueueSize: 1})
+
+	audio := make([]byte, 320)
+	opts := TranscribeOptions{Format: "pcm"}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = pool.TranscribeAudio(audio, opts)
+		}(i)
+		// 第一个请求占用槽位，第二个请求排队
+		waitFor(t, func() bool { return pool.Stats().Busy+pool.QueueDepth() == i+1 })
+	}
+
+	stats := pool.Stats()
+	if stats.Busy != 1 || stats.QueueDepth != 1 {
+		t.Errorf("期望 1 个解码中、1 个排队，得到 %+v", stats)
+	}
+
+	if _, err := pool.TranscribeAudio(audio, opts); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("队列已满时应该返回 ErrQueueFull，实际: %v", err)
+	}
+
+	close(fake.Gate)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("第 %d 个请求失败: %v", i, err)
+		}
+	}
+	if fake.Requests() != 2 {
+		t.Errorf("期望解码 2 次，实际 %d 次", fake.Requests())
+	}
+	if depth := pool.QueueDepth(); depth != 0 {
+		t.Errorf("请求结束后队列应该为空，实际 %d", depth)
+	}
+}
+
+func TestPoolQueueTimeout(t *testing.T) {
+	fake := NewFakeTranscriber("测试文本")
+	fake.Gate = make(chan struct{})
+	pool := NewPool(fake, PoolConfig{Workers: 1, QueueSize: 1, QueueTimeout: 20 * time.Millisecond})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm"})
+	}()
+	waitFor(t, func() bool { return pool.Stats().Busy == 1 })
+
+	if _, err := pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm"}); !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("排队超时应该返回 ErrQueueTimeout，实际: %v", err)
+	}
+
+	close(fake.Gate)
+	<-done
+}
+
+func TestPoolCancel(t *testing.T) {
+	fake := NewFakeTranscriber("测试文本")
+	fake.Gate = make(chan struct{})
+	pool := NewPool(fake, PoolConfig{Workers: 1, QueueSize: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	running := make(chan error)
+	go func() {
+		_, err := pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm", Context: ctx})
+		running <- err
+	}()
+	waitFor(t, func() bool { return pool.Stats().Busy == 1 })
+
+	queued := make(chan error)
+	go func() {
+		_, err := pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm", Context: ctx})
+		queued <- err
+	}()
+	waitFor(t, func() bool { return pool.QueueDepth() == 1 })
+
+	// 取消后排队的请求离开队列，正在解码的请求提前结束
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Errorf("取消后排队的请求应该返回 context.Canceled，实际: %v", err)
+	}
+	if err := <-running; !errors.Is(err, context.Canceled) {
+		t.Errorf("取消后正在解码的请求应该返回 context.Canceled，实际: %v", err)
+	}
+	if stats := pool.Stats(); stats.Busy != 0 || stats.QueueDepth != 0 {
+		t.Errorf("取消后应该释放槽位: %+v", stats)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	fake := NewFakeTranscriber("测试文本")
+	fake.Gate = make(chan struct{})
+	pool := NewPool(fake, PoolConfig{Workers: 1, QueueSize: 1})
+
+	go pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm"})
+	waitFor(t, func() bool { return pool.Stats().Busy == 1 })
+
+	queued := make(chan error)
+	go func() {
+		_, err := pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm"})
+		queued <- err
+	}()
+	waitFor(t, func() bool { return pool.QueueDepth() == 1 })
+
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+
+	if err := <-queued; !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("关闭后排队的请求应该返回 ErrPoolClosed，实际: %v", err)
+	}
+
+	// 正在解码的请求结束后才关闭底层引擎
+	close(fake.Gate)
+	<-closed
+	if !fake.closed {
+		t.Error("底层转录引擎没有关闭")
+	}
+
+	if _, err := pool.TranscribeAudio(make([]byte, 320), TranscribeOptions{Format: "pcm"}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("关闭后应该返回 ErrPoolClosed，实际: %v", err)
+	}
+}
+
+func TestPoolCloseWaitsForSessions(t *testing.T) {
+	fake := NewFakeTranscriber("测试文本")
+	pool := NewPool(fake, PoolConfig{})
+
+	session, err := pool.NewSession(DecodingOptions{})
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("还有打开的会话时不应该关闭底层转录引擎")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := pool.NewSession(DecodingOptions{}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("关闭中应该拒绝新会话，实际: %v", err)
+	}
+
+	session.Close()
+	<-closed
+	if !fake.closed {
+		t.Error("会话关闭后底层转录引擎应该关闭")
+	}
+}
+
+func TestPoolMaxSessions(t *testing.T) {
+	pool := NewPool(NewFakeTranscriber("测试文本"), PoolConfig{MaxSessions: 1})
+
+	session, err := pool.NewSession(DecodingOptions{})
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if _, err := pool.NewSession(DecodingOptions{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("会话数量达到上限时应该返回 ErrQueueFull，实际: %v", err)
+	}
+
+	session.Close()
+	session.Close()
+	if n := pool.Stats().Sessions; n != 0 {
+		t.Errorf("会话关闭后计数应该为 0，实际 %d", n)
+	}
+	if _, err := pool.NewSession(DecodingOptions{}); err != nil {
+		t.Errorf("会话关闭后应该可以创建新会话: %v", err)
+	}
+}
+
+func TestPoolRetryAfter(t *testing.T) {
+	pool := NewPool(NewFakeTranscriber("测试文本"), PoolConfig{Workers: 2})
+
+	if d := pool.RetryAfter(); d != time.Second {
+		t.Errorf("没有解码记录时期望 1 秒，得到 %v", d)
+	}
+
+	pool.observe(4 * time.Second)
+	if d := pool.RetryAfter(); d != 2*time.Second {
+		t.Errorf("期望 2 秒，得到 %v", d)
+	}
+}
@@ -0,0 +1,247 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// ErrTooManyRecognizers 按请求参数创建的识别器数量达到上限，并且都在使用中
+var ErrTooManyRecognizers = errors.New("自定义识别器数量达到上限")
+
+// recognizerKey 区分按请求参数创建的识别器
+type recognizerKey struct {
+	mode string
+	// hotwords 额外的热词，每行一个
+	hotwords string
+	// model 应用解码选项后的模型配置
+	model ModelConfig
+}
+
+// recognizerSet 一组解码参数对应的识别器，流式和离线识别器只有与 mode 对应的一个不为空
+type recognizerSet struct {
+	online  *sherpa_onnx.OnlineRecognizer
+	loop    *decodeLoop
+	offline *sherpa_onnx.OfflineRecognizer
+}
+
+func (r *recognizerSet) close() {
+	if r.loop != nil {
+		r.loop.close()
+	}
+	if r.online != nil {
+		sherpa_onnx.DeleteOnlineRecognizer(r.online)
+	}
+	if r.offline != nil {
+		sherpa_onnx.DeleteOfflineRecognizer(r.offline)
+	}
+}
+
+// cachedRecognizers 缓存中的一组识别器
+type cachedRecognizers struct {
+	set  *recognizerSet
+	refs int
+	// used 最近一次使用的序号，用于淘汰最久未使用的识别器
+	used uint64
+}
+
+// recognizerCache 缓存按请求参数创建的识别器
+// sherpa-onnx-go 不能按流设置热词和解码参数，不同的参数需要各自加载一份模型，
+// 因此限制数量，空闲的识别器按最久未使用淘汰
+type recognizerCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[recognizerKey]*cachedRecognizers
+	clock   uint64
+	create  func(key recognizerKey) (*recognizerSet, error)
+}
+
+func newRecognizerCache(max int, create func(key recognizerKey) (*recognizerSet, error)) *recognizerCache {
+	return &recognizerCache{
+		max:     max,
+		entries: make(map[recognizerKey]*cachedRecognizers),
+		create:  create,
+	}
+}
+
+// acquire 返回 key 对应的识别器，不存在时创建；使用完后调用返回的 release
+// 创建识别器需要加载模型，期间持有锁，相同参数的并发请求只会加载一次
+func (c *recognizerCache) acquire(key recognizerKey) (*recognizerSet, func(), error) {
+	if c == nil {
+		return nil, nil, ErrTooManyRecognizers
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock++
+	entry, ok := c.entries[key]
+	if !ok {
+		if c.max <= 0 {
+			return nil, nil, ErrTooManyRecognizers
+		}
+		if len(c.entries) >= c.max && !c.evict() {
+			return nil, nil, ErrTooManyRecognizers
+		}
+
+		set, err := c.create(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		entry = &cachedRecognizers{set: set}
+		c.entries[key] = entry
+	}
+	entry.refs++
+	entry.used = c.clock
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			entry.refs--
+		})
+	}
+	return entry.set, release, nil
+}
+
+// evict 释放最久未使用的空闲识别器，没有空闲的识别器时返回 false
+func (c *recognizerCache) evict() bool {
+	var oldest recognizerKey
+	var victim *cachedRecognizers
+	for key, entry := range c.entries {
+		if entry.refs == 0 && (victim == nil || entry.used < victim.used) {
+			oldest, victim = key, entry
+		}
+	}
+	if victim == nil {
+		return false
+	}
+	delete(c.entries, oldest)
+	victim.set.close()
+	return true
+}
+
+// size 返回缓存的识别器数量
+func (c *recognizerCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// close 释放所有识别器
+func (c *recognizerCache) close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		entry.set.close()
+		delete(c.entries, key)
+	}
+}
+
+// defaultCustomRecognizers 默认最多按请求参数创建的识别器数量
+const defaultCustomRecognizers = 2
+
+// SetMaxCustomRecognizers 设置按请求参数（热词、解码方法等）额外创建的识别器数量上限
+// 每个识别器都会另外加载一份模型，0 表示请求只能使用默认的解码参数
+func (st *SherpaTranscriber) SetMaxCustomRecognizers(n int) {
+	st.custom.close()
+	st.custom = newRecognizerCache(n, st.createRecognizers)
+}
+
+// recognizers 返回识别模式和解码选项对应的识别器，使用完后调用返回的 release
+// 解码选项与配置相同时使用默认的识别器
+func (st *SherpaTranscriber) recognizers(mode string, opts DecodingOptions) (*recognizerSet, func(), error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	online := mode != ModeOffline
+	model := st.onlineModel
+	if !online {
+		model = st.offlineModel
+	}
+	custom, err := model.withDecoding(opts, online)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	words := opts.hotwords()
+	if len(words) == 0 && custom == model.withDefaults() {
+		return &recognizerSet{
+			online:  st.recognizer,
+			loop:    st.decodeLoop,
+			offline: st.offlineRecognizer,
+		}, func() {}, nil
+	}
+	return st.custom.acquire(recognizerKey{
+		mode:     mode,
+		hotwords: strings.Join(words, "\n"),
+		model:    custom,
+	})
+}
+
+// createRecognizers 按缓存键加载一组新的识别器，额外的热词写入临时文件，加载完成后删除
+func (st *SherpaTranscriber) createRecognizers(key recognizerKey) (*recognizerSet, error) {
+	model := key.model
+	if key.hotwords != "" {
+		file, err := writeHotwordsFile(model.HotwordsFile, strings.Split(key.hotwords, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file)
+		model.HotwordsFile = file
+	}
+
+	if key.mode == ModeOffline {
+		config, err := newOfflineRecognizerConfig(model, st.sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		recognizer := sherpa_onnx.NewOfflineRecognizer(config)
+		if recognizer == nil {
+			return nil, fmt.Errorf("无法加载离线识别模型: %s", model.Type)
+		}
+		st.logger.Infof("按请求参数创建离线识别器: %s", describeRecognizer(key))
+		return &recognizerSet{offline: recognizer}, nil
+	}
+
+	config, err := newOnlineRecognizerConfig(model, st.sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	recognizer := sherpa_onnx.NewOnlineRecognizer(config)
+	if recognizer == nil {
+		return nil, fmt.Errorf("无法加载识别模型: %s", NormalizeModelType(model.Type))
+	}
+	st.logger.Infof("按请求参数创建识别器: %s", describeRecognizer(key))
+	return &recognizerSet{
+		online: recognizer,
+		loop:   newDecodeLoop(sherpaStreamRecognizer{recognizer}, maxDecodeBatch),
+	}, nil
+}
+
+// describeRecognizer 返回识别器解码参数的简短描述，用于日志
+func describeRecognizer(key recognizerKey) string {
+	words := 0
+	if key.hotwords != "" {
+		words = strings.Count(key.hotwords, "\n") + 1
+	}
+	m := key.model
+	desc := fmt.Sprintf("%s, max_active_paths=%d, %d 个热词", m.DecodingMethod, m.MaxActivePaths, words)
+	if key.mode == ModeOnline {
+		if m.DisableEndpoint {
+			desc += ", 关闭端点检测"
+		} else {
+			desc += fmt.Sprintf(", 端点规则 %g/%g/%g", m.Endpoint.Rule1MinTrailingSilence, m.Endpoint.Rule2MinTrailingSilence, m.Endpoint.Rule3MinUtteranceLength)
+		}
+	}
+	return desc
+}
@@ -0,0 +1,153 @@
+package transcribe
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// countingCache 记录创建识别器次数的缓存
+func countingCache(max int) (*recognizerCache, *int) {
+	created := 0
+	cache := newRecognizerCache(max, func(key recognizerKey) (*recognizerSet, error) {
+		created++
+		return &recognizerSet{}, nil
+	})
+	return cache, &created
+}
+
+func hotwordsKey(words ...string) recognizerKey {
+	return recognizerKey{mode: ModeOnline, hotwords: strings.Join(words, "\n")}
+}
+
+func TestSherpaRecognizersKey(t *testing.T) {
+	cache, created := countingCache(4)
+	model := ModelConfig{Type: ModelTransducer}
+	st := &SherpaTranscriber{onlineModel: model, offlineModel: model, custom: cache}
+
+	acquire := func(mode string, opts DecodingOptions) *recognizerSet {
+		t.Helper()
+		set, release, err := st.recognizers(mode, opts)
+		if err != nil {
+			t.Fatalf("获取识别器失败: %v", err)
+		}
+		release()
+		return set
+	}
+
+	// 与配置相同的解码选项使用默认识别器
+	acquire(ModeOnline, DecodingOptions{})
+	acquire(ModeOnline, DecodingOptions{DecodingMethod: DecodingGreedySearch, MaxActivePaths: 4, HotwordsScore: 2})
+	if *created != 0 {
+		t.Fatalf("默认解码选项不应该创建识别器，创建了 %d 次", *created)
+	}
+
+	// 相同的热词集合共用一个识别器
+	a := acquire(ModeOnline, DecodingOptions{Hotwords: []string{"转录", "语音识别"}})
+	b := acquire(ModeOnline, DecodingOptions{Hotwords: []string{"语音识别", " 转录", "转录"}})
+	if a != b || *created != 1 {
+		t.Errorf("相同的热词应该共用识别器，创建了 %d 次", *created)
+	}
+
+	// 解码参数和识别模式不同时各自创建
+	acquire(ModeOnline, DecodingOptions{MaxActivePaths: 8})
+	acquire(ModeOffline, DecodingOptions{Hotwords: []string{"转录", "语音识别"}})
+	if *created != 3 {
+		t.Errorf("期望创建 3 次，实际 %d 次", *created)
+	}
+}
+
+func TestRecognizerCache(t *testing.T) {
+	cache, created := countingCache(2)
+
+	// 相同参数复用同一个识别器
+	first, releaseFirst, err := cache.acquire(hotwordsKey("a"))
+	if err != nil {
+		t.Fatalf("创建识别器失败: %v", err)
+	}
+	again, releaseAgain, err := cache.acquire(hotwordsKey("a"))
+	if err != nil || again != first {
+		t.Fatalf("相同参数应该复用识别器: %v", err)
+	}
+	if *created != 1 {
+		t.Errorf("期望创建 1 次，实际 %d 次", *created)
+	}
+
+	_, releaseB, err := cache.acquire(hotwordsKey("b"))
+	if err != nil {
+		t.Fatalf("创建识别器失败: %v", err)
+	}
+
+	// 达到上限且都在使用中
+	if _, _, err := cache.acquire(hotwordsKey("c")); !errors.Is(err, ErrTooManyRecognizers) {
+		t.Errorf("期望 ErrTooManyRecognizers，实际: %v", err)
+	}
+
+	// 释放后淘汰最久未使用的空闲识别器
+	releaseFirst()
+	releaseFirst()
+	releaseAgain()
+	releaseB()
+	if _, release, err := cache.acquire(hotwordsKey("b")); err != nil {
+		t.Fatalf("获取识别器失败: %v", err)
+	} else {
+		release()
+	}
+	if _, release, err := cache.acquire(hotwordsKey("c")); err != nil {
+		t.Fatalf("淘汰空闲识别器后应该可以创建: %v", err)
+	} else {
+		release()
+	}
+	if cache.size() != 2 {
+		t.Errorf("期望缓存 2 个识别器，实际 %d 个", cache.size())
+	}
+	if _, release, _ := cache.acquire(hotwordsKey("b")); *created != 3 {
+		t.Errorf("最近使用的识别器不应该被淘汰，创建了 %d 次", *created)
+	} else {
+		release()
+	}
+
+	cache.close()
+	if cache.size() != 0 {
+		t.Errorf("关闭后不应该保留识别器")
+	}
+}
+
+func TestRecognizerCacheDisabled(t *testing.T) {
+	cache, _ := countingCache(0)
+	if _, _, err := cache.acquire(hotwordsKey("a")); !errors.Is(err, ErrTooManyRecognizers) {
+		t.Errorf("上限为 0 时期望 ErrTooManyRecognizers，实际: %v", err)
+	}
+
+	var nilCache *recognizerCache
+	if _, _, err := nilCache.acquire(hotwordsKey("a")); !errors.Is(err, ErrTooManyRecognizers) {
+		t.Errorf("未初始化的缓存期望 ErrTooManyRecognizers，实际: %v", err)
+	}
+}
+
+func TestSherpaRecognizersInvalid(t *testing.T) {
+	st := &SherpaTranscriber{onlineModel: ModelConfig{Type: ModelParaformer}}
+
+	// 没有热词时使用默认识别器
+	set, release, err := st.recognizers(ModeOnline, DecodingOptions{})
+	if err != nil || set == nil {
+		t.Fatalf("默认选项应该返回默认识别器: %v", err)
+	}
+	release()
+
+	tests := []struct {
+		name string
+		opts DecodingOptions
+	}{
+		{"paraformer 不支持热词", DecodingOptions{Hotwords: []string{"转录"}}},
+		{"paraformer 不支持 modified_beam_search", DecodingOptions{DecodingMethod: DecodingModifiedBeamSearch}},
+		{"无效的加分", DecodingOptions{HotwordsScore: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := st.recognizers(ModeOnline, tt.opts); !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("期望 ErrInvalidOptions，实际: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package transcribe
+
+import (
+	"fmt"
+	"math"
+)
+
+// 重采样滤波器参数
+const (
+	// 每侧保留的 sinc 过零点数量，越大过渡带越窄
+	resampleZeroCrossings = 16
+	// 截止频率相对于目标奈奎斯特频率的比例，留出过渡带避免混叠
+	resampleRolloff = 0.945
+	// Kaiser 窗参数，约 90dB 阻带衰减
+	resampleKaiserBeta = 8.6
+	// 多相系数表的最大元素数量，超过时按需计算系数
+	resampleMaxTableSize = 1 << 20
+)
+
+// Resampler 多相加窗 sinc 重采样器
+// 支持任意整数采样率之间的转换，可以分块调用 Process 进行流式处理，最后调用 Flush 输出剩余采样
+type Resampler struct {
+	inRate  int
+	outRate int
+	// 化简后的插值倍数 L 和抽取倍数 M
+	up   int64
+	down int64
+	// 截止频率（相对于输入奈奎斯特频率）和每侧抽头数
+	cutoff float64
+	half   int
+	// filters[p] 为相位 p/L 对应的 2*half 个抽头，可能为空（按需计算）
+	filters [][]float32
+
+	buf      []float32 // 尚未完全消耗的输入
+	bufStart int64     // buf[0] 对应的输入下标
+	consumed int64     // 已输入的采样总数
+	next     int64     // 下一个输出采样的下标
+}
+
+// NewResampler 创建从 inRate 到 outRate 的重采样器
+func NewResampler(inRate, outRate int) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("无效的采样率: %d -> %d", inRate, outRate)
+	}
+
+	g := gcd(inRate, outRate)
+	r := &Resampler{
+		inRate:  inRate,
+		outRate: outRate,
+		up:      int64(outRate / g),
+		down:    int64(inRate / g),
+	}
+
+	// 降采样时截止频率需要按比例降低以抑制混叠
+	r.cutoff = resampleRolloff * math.Min(1, float64(outRate)/float64(inRate))
+	r.half = int(math.Ceil(resampleZeroCrossings / r.cutoff))
+
+	if r.up*int64(2*r.half) <= resampleMaxTableSize {
+		r.filters = make([][]float32, r.up)
+		for p := range r.filters {
+			r.filters[p] = r.phaseFilter(int64(p))
+		}
+	}
+
+	return r, nil
+}
+
+// Resample 一次性转换整段音频的采样率
+func Resample(samples []float32, inRate, outRate int) ([]float32, error) {
+	if inRate == outRate {
+		return append([]float32(nil), samples...), nil
+	}
+
+	r, err := NewResampler(inRate, outRate)
+	if err != nil {
+		return nil, err
+	}
+	out := r.Process(samples)
+	return append(out, r.Flush()...), nil
+}
+
+// InputRate 返回输入采样率
+func (r *Resampler) InputRate() int {
+	return r.inRate
+}
+
+// OutputRate 返回输出采样率
+func (r *Resampler) OutputRate() int {
+	return r.outRate
+}
+
+// Process 输入一块音频，返回已经可以确定的输出采样
+// 由于滤波器需要看到后续输入，输出会比输入滞后约 half 个采样
+func (r *Resampler) Process(in []float32) []float32 {
+	r.buf = append(r.buf, in...)
+	r.consumed += int64(len(in))
+
+	out := r.produce(r.consumed - int64(r.half))
+	r.compact()
+	return out
+}
+
+// Flush 在输入结束后输出剩余的采样，并重置重采样器状态
+func (r *Resampler) Flush() []float32 {
+	// 输出总数为 ceil(consumed * L / M)，缺失的后续输入按 0 处理
+	total := (r.consumed*r.up + r.down - 1) / r.down
+	var out []float32
+	for ; r.next < total; r.next++ {
+		out = append(out, r.sample(r.next))
+	}
+
+	r.buf = r.buf[:0]
+	r.bufStart = 0
+	r.consumed = 0
+	r.next = 0
+	return out
+}
+
+// produce 输出所有中心位置不超过 limit 的采样
+func (r *Resampler) produce(limit int64) []float32 {
+	var out []float32
+	for {
+		center := r.next * r.down / r.up
+		if center >= limit {
+			break
+		}
+		out = append(out, r.sample(r.next))
+		r.next++
+	}
+	return out
+}
+
+// sample 计算第 n 个输出采样
+func (r *Resampler) sample(n int64) float32 {
+	pos := n * r.down
+	center := pos / r.up
+	phase := pos % r.up
+
+	var taps []float32
+	if r.filters != nil {
+		taps = r.filters[phase]
+	} else {
+		taps = r.phaseFilter(phase)
+	}
+
+	// taps[j] 对应输入下标 center - half + 1 + j
+	first := center - int64(r.half) + 1
+	var acc float32
+	for j, h := range taps {
+		i := first + int64(j) - r.bufStart
+		if i < 0 || i >= int64(len(r.buf)) {
+			continue
+		}
+		acc += r.buf[i] * h
+	}
+	return acc
+}
+
+// compact 丢弃后续输出不再需要的输入
+func (r *Resampler) compact() {
+	keepFrom := r.next*r.down/r.up - int64(r.half) + 1
+	drop := keepFrom - r.bufStart
+	if drop <= 0 {
+		return
+	}
+	if drop > int64(len(r.buf)) {
+		drop = int64(len(r.buf))
+	}
+	r.buf = append(r.buf[:0], r.buf[drop:]...)
+	r.bufStart += drop
+}
+
+// phaseFilter 计算相位 phase/L 对应的抽头，并归一化直流增益
+func (r *Resampler) phaseFilter(phase int64) []float32 {
+	frac := float64(phase) / float64(r.up)
+	taps := make([]float32, 2*r.half)
+	coeffs := make([]float64, 2*r.half)
+
+	var sum float64
+	for j := range coeffs {
+		// 输出位置与输入采样之间的距离（以输入采样为单位）
+		x := frac + float64(r.half-1-j)
+		c := r.cutoff * sinc(r.cutoff*x) * kaiser(x/float64(r.half), resampleKaiserBeta)
+		coeffs[j] = c
+		sum += c
+	}
+	for j, c := range coeffs {
+		taps[j] = float32(c / sum)
+	}
+	return taps
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser 返回 Kaiser 窗在 x ∈ [-1, 1] 处的值
+func kaiser(x, beta float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return besselI0(beta*math.Sqrt(1-x*x)) / besselI0(beta)
+}
+
+// besselI0 第一类零阶修正贝塞尔函数（级数展开）
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// streamResampler 会话内部使用的重采样状态，输入采样率变化时自动重建
+type streamResampler struct {
+	targetRate int
+	resampler  *Resampler
+}
+
+// process 将 sampleRate 的音频转换为目标采样率
+func (s *streamResampler) process(sampleRate int, samples []float32) ([]float32, error) {
+	if sampleRate <= 0 {
+		sampleRate = s.targetRate
+	}
+
+	var out []float32
+	if s.resampler != nil && s.resampler.InputRate() != sampleRate {
+		out = s.resampler.Flush()
+		s.resampler = nil
+	}
+
+	if sampleRate == s.targetRate {
+		return append(out, samples...), nil
+	}
+
+	if s.resampler == nil {
+		r, err := NewResampler(sampleRate, s.targetRate)
+		if err != nil {
+			return nil, err
+		}
+		s.resampler = r
+	}
+	return append(out, s.resampler.Process(samples)...), nil
+}
+
+// flush 输出重采样器中剩余的采样
+func (s *streamResampler) flush() []float32 {
+	if s.resampler == nil {
+		return nil
+	}
+	return s.resampler.Flush()
+}
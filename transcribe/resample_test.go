@@ -0,0 +1,158 @@
+package transcribe
+
+import (
+	"math"
+	"testing"
+)
+
+func sineWave(freq float64, sampleRate, n int) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// maxSineError 计算输出中间部分与理想正弦波的最大误差（忽略两端的边界效应）
+func maxSineError(samples []float32, freq float64, sampleRate int) float64 {
+	expected := sineWave(freq, sampleRate, len(samples))
+	margin := len(samples) / 10
+	var maxErr float64
+	for i := margin; i < len(samples)-margin; i++ {
+		maxErr = math.Max(maxErr, math.Abs(float64(samples[i]-expected[i])))
+	}
+	return maxErr
+}
+
+func rms(samples []float32) float64 {
+	margin := len(samples) / 10
+	var sum float64
+	for _, s := range samples[margin : len(samples)-margin] {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)-2*margin))
+}
+
+func TestResampleLength(t *testing.T) {
+	tests := []struct {
+		in, out, n, expected int
+	}{
+		{48000, 16000, 48000, 16000},
+		{44100, 16000, 44100, 16000},
+		{8000, 16000, 8000, 16000},
+		{16000, 16000, 1234, 1234},
+		{22050, 16000, 1001, 727}, // ceil(1001 * 16000 / 22050)
+	}
+
+	for _, tt := range tests {
+		out, err := Resample(make([]float32, tt.n), tt.in, tt.out)
+		if err != nil {
+			t.Fatalf("重采样失败: %v", err)
+		}
+		if len(out) != tt.expected {
+			t.Errorf("%d -> %d 输出长度错误，期望: %d, 实际: %d", tt.in, tt.out, tt.expected, len(out))
+		}
+	}
+}
+
+func TestResampleSineAccuracy(t *testing.T) {
+	tests := []struct {
+		in, out int
+		freq    float64
+	}{
+		{48000, 16000, 1000},
+		{44100, 16000, 440},
+		{8000, 16000, 1000},
+		{22050, 16000, 3000},
+	}
+
+	for _, tt := range tests {
+		out, err := Resample(sineWave(tt.freq, tt.in, tt.in), tt.in, tt.out)
+		if err != nil {
+			t.Fatalf("重采样失败: %v", err)
+		}
+		if e := maxSineError(out, tt.freq, tt.out); e > 2e-3 {
+			t.Errorf("%d -> %d 的 %.0fHz 正弦波误差过大: %g", tt.in, tt.out, tt.freq, e)
+		}
+	}
+}
+
+func TestResampleRejectsAliasing(t *testing.T) {
+	// 12kHz 超过 16kHz 的奈奎斯特频率，降采样后应被滤除
+	out, err := Resample(sineWave(12000, 48000, 48000), 48000, 16000)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+	if level := rms(out); level > 0.5*0.01 {
+		t.Errorf("混叠分量衰减不足，输出 RMS: %g", level)
+	}
+}
+
+func TestResamplerStreamingMatchesBatch(t *testing.T) {
+	input := sineWave(700, 44100, 20000)
+	expected, err := Resample(input, 44100, 16000)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+
+	r, err := NewResampler(44100, 16000)
+	if err != nil {
+		t.Fatalf("创建重采样器失败: %v", err)
+	}
+	var got []float32
+	for start, size := 0, 1; start < len(input); size = size*3%997 + 1 {
+		end := start + size
+		if end > len(input) {
+			end = len(input)
+		}
+		got = append(got, r.Process(input[start:end])...)
+		start = end
+	}
+	got = append(got, r.Flush()...)
+
+	if len(got) != len(expected) {
+		t.Fatalf("流式输出长度错误，期望: %d, 实际: %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if math.Abs(float64(got[i]-expected[i])) > 1e-6 {
+			t.Fatalf("采样 %d 不一致，期望: %f, 实际: %f", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestStreamResamplerRateChange(t *testing.T) {
+	s := &streamResampler{targetRate: 16000}
+
+	out, err := s.process(16000, []float32{0.1, 0.2})
+	if err != nil || len(out) != 2 {
+		t.Fatalf("相同采样率应直接透传，实际: %v, %v", out, err)
+	}
+
+	total := 0
+	for i := 0; i < 10; i++ {
+		out, err = s.process(8000, make([]float32, 800))
+		if err != nil {
+			t.Fatalf("重采样失败: %v", err)
+		}
+		total += len(out)
+	}
+	// 切换回 16kHz 时会先输出 8kHz 重采样器中剩余的采样
+	out, err = s.process(16000, nil)
+	if err != nil {
+		t.Fatalf("重采样失败: %v", err)
+	}
+	total += len(out)
+
+	if total != 16000 {
+		t.Errorf("8kHz -> 16kHz 输出总数错误，期望: 16000, 实际: %d", total)
+	}
+}
+
+func TestNewResamplerInvalidRate(t *testing.T) {
+	if _, err := NewResampler(0, 16000); err == nil {
+		t.Error("输入采样率为 0 时期望返回错误")
+	}
+	if _, err := NewResampler(16000, -1); err == nil {
+		t.Error("输出采样率为负数时期望返回错误")
+	}
+}
@@ -1,10 +1,12 @@
 package transcribe
 
 import (
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"io"
 	"strings"
+	"sync"
 
 	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 	"github.com/sirupsen/logrus"
@@ -13,6 +15,11 @@ import (
 // 由于 sherpa-onnx-go 可能不在标准库中，我们先使用一个简化的实现
 // 在实际使用中，您需要安装 sherpa-onnx-go 库
 
+var _ Transcriber = (*SherpaTranscriber)(nil)
+
+// segmentBlockSeconds 边读边转录时每次做语音检测切分的音频长度（秒）
+const segmentBlockSeconds = 120
+
 type SherpaTranscriber struct {
 	recognizer *sherpa_onnx.OnlineRecognizer
 	logger     *logrus.Logger
@@ -20,6 +27,24 @@ type SherpaTranscriber struct {
 	// 添加说话人分离相关字段
 	diarizationEnabled   bool
 	diarizationModelPath string
+	diarizer             Diarizer
+	// sampleRate 模型采样率
+	sampleRate int
+	// decodeLoop 为所有流式会话批量解码
+	decodeLoop *decodeLoop
+	// 离线识别器，用于批量转录
+	offlineRecognizer *sherpa_onnx.OfflineRecognizer
+	offlineConfig     *sherpa_onnx.OfflineRecognizerConfig
+	// batchMode 批量转录默认使用的识别模式，为空时使用流式识别器
+	batchMode string
+	// segmenter 不为空时批量转录先按语音检测切分句子，再逐句识别
+	segmenter      Segmenter
+	segmentWorkers int
+	// onlineModel 和 offlineModel 创建识别器使用的模型配置，按请求设置热词时据此另外创建识别器
+	onlineModel  ModelConfig
+	offlineModel ModelConfig
+	// custom 按请求参数创建的识别器
+	custom *recognizerCache
 }
 
 // 说话人分离结果结构体
@@ -28,14 +53,31 @@ type SpeakerSegment struct {
 	Start     float64 `json:"start"`
 	End       float64 `json:"end"`
 	Text      string  `json:"text"`
+	// Confidence 片段内 token 概率的几何平均，识别器不提供概率时为 0
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// Segment 语音检测切分出的一句话
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+	// Confidence 句子内 token 概率的几何平均，识别器不提供概率时为 0
+	Confidence float64 `json:"confidence,omitempty"`
 }
 
 type TranscriptionResult struct {
-	Text       string  `json:"text"`
+	Text string `json:"text"`
+	// Confidence 所有 token 概率的几何平均，识别器不提供概率时为 0 并在 JSON 中省略
 	Confidence float64 `json:"confidence,omitempty"`
 	Duration   float64 `json:"duration,omitempty"`
 	// 添加说话人分离结果
 	SpeakerSegments []SpeakerSegment `json:"speaker_segments,omitempty"`
+	// Segments 启用语音检测切分时每句话的文本和时间
+	Segments []Segment `json:"segments,omitempty"`
+	// Words 和 Tokens 只在请求时间戳且识别器提供 token 时间戳时返回
+	Words  []Word  `json:"words,omitempty"`
+	Tokens []Token `json:"tokens,omitempty"`
 }
 
 type SherpaRequest struct {
@@ -45,251 +87,503 @@ type SherpaRequest struct {
 
 // 新增：支持说话人分离的构造函数
 func NewSherpaTranscriberWithDiarization(modelPath, tokensPath, diarizationModelPath string, sampleRate, numThreads int, decodingMethod string) *SherpaTranscriber {
-	logger := logrus.New()
-
-	// 创建 sherpa-onnx 配置
-	config := &sherpa_onnx.OnlineRecognizerConfig{}
-
-	// 设置特征配置
-	config.FeatConfig.SampleRate = sampleRate
-	config.FeatConfig.FeatureDim = 80
-
-	// 设置模型配置
-	config.ModelConfig.Transducer.Encoder = filepath.Join(modelPath, "encoder.onnx")
-	config.ModelConfig.Transducer.Decoder = filepath.Join(modelPath, "decoder.onnx")
-	config.ModelConfig.Transducer.Joiner = filepath.Join(modelPath, "joiner.onnx")
-	config.ModelConfig.Tokens = tokensPath
-	config.ModelConfig.NumThreads = numThreads
-	config.ModelConfig.Provider = "cpu"
-
-	// 设置识别器配置
-	config.DecodingMethod = decodingMethod
-	config.EnableEndpoint = 1
-	config.Rule1MinTrailingSilence = 2.4
-	config.Rule2MinTrailingSilence = 1.2
-	config.Rule3MinUtteranceLength = 300
-
-	// 创建识别器
-	recognizer := sherpa_onnx.NewOnlineRecognizer(config)
-	if recognizer == nil {
-		logger.Errorf("创建识别器失败")
+	transcriber := NewSherpaTranscriber(modelPath, tokensPath, sampleRate, numThreads, decodingMethod)
+	if transcriber == nil {
 		return nil
 	}
 
-	return &SherpaTranscriber{
-		recognizer:           recognizer,
-		logger:               logger,
-		config:               config,
-		diarizationEnabled:   true,
-		diarizationModelPath: diarizationModelPath,
+	// 创建说话人分离器
+	diarizer, err := NewSherpaDiarizer(diarizationModelPath, numThreads, 0, 0)
+	if err != nil {
+		transcriber.logger.Errorf("创建说话人分离器失败: %v", err)
+		transcriber.Close()
+		return nil
 	}
+
+	transcriber.SetDiarizer(diarizer)
+	transcriber.diarizationModelPath = diarizationModelPath
+	return transcriber
 }
 
+// NewSherpaTranscriber 使用模型目录下的 transducer 模型创建转录器
 func NewSherpaTranscriber(modelPath, tokensPath string, sampleRate, numThreads int, decodingMethod string) *SherpaTranscriber {
-	logger := logrus.New()
+	transcriber, err := NewSherpaTranscriberWithModel(ModelConfig{
+		Type:           ModelTransducer,
+		Dir:            modelPath,
+		Tokens:         tokensPath,
+		NumThreads:     numThreads,
+		DecodingMethod: decodingMethod,
+	}, sampleRate)
+	if err != nil {
+		logrus.Errorf("创建识别器失败: %v", err)
+		return nil
+	}
+	return transcriber
+}
+
+// NewSherpaTranscriberWithModel 按模型配置创建转录器
+// 有流式实现的模型同时用于实时转录和批量转录；
+// 只有离线实现的模型（whisper、sense-voice 等）只能用于批量转录
+func NewSherpaTranscriberWithModel(model ModelConfig, sampleRate int) (*SherpaTranscriber, error) {
+	st := &SherpaTranscriber{
+		logger:     logrus.New(),
+		sampleRate: sampleRate,
+	}
+	st.custom = newRecognizerCache(defaultCustomRecognizers, st.createRecognizers)
+
+	if !SupportsOnline(model.Type) {
+		if err := st.EnableOfflineRecognizer(model); err != nil {
+			return nil, err
+		}
+		st.batchMode = ModeOffline
+		return st, nil
+	}
 
 	// 创建 sherpa-onnx 配置
-	config := &sherpa_onnx.OnlineRecognizerConfig{}
-
-	// 设置特征配置
-	config.FeatConfig.SampleRate = sampleRate
-	config.FeatConfig.FeatureDim = 80
-
-	// 设置模型配置
-	config.ModelConfig.Transducer.Encoder = filepath.Join(modelPath, "encoder.onnx")
-	config.ModelConfig.Transducer.Decoder = filepath.Join(modelPath, "decoder.onnx")
-	config.ModelConfig.Transducer.Joiner = filepath.Join(modelPath, "joiner.onnx")
-	config.ModelConfig.Tokens = tokensPath
-	config.ModelConfig.NumThreads = numThreads
-	config.ModelConfig.Provider = "cpu"
-
-	// 设置识别器配置
-	config.DecodingMethod = decodingMethod
-	config.EnableEndpoint = 1
-	config.Rule1MinTrailingSilence = 2.4
-	config.Rule2MinTrailingSilence = 1.2
-	config.Rule3MinUtteranceLength = 300
+	config, err := newOnlineRecognizerConfig(model, sampleRate)
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建识别器
 	recognizer := sherpa_onnx.NewOnlineRecognizer(config)
 	if recognizer == nil {
-		logger.Errorf("创建识别器失败")
-		return nil
+		return nil, fmt.Errorf("无法加载识别模型: %s", NormalizeModelType(model.Type))
 	}
 
-	return &SherpaTranscriber{
-		recognizer:           recognizer,
-		logger:               logger,
-		config:               config,
-		diarizationEnabled:   false,
-		diarizationModelPath: "",
-	}
+	st.recognizer = recognizer
+	st.decodeLoop = newDecodeLoop(sherpaStreamRecognizer{recognizer}, maxDecodeBatch)
+	st.config = config
+	st.onlineModel = model
+	return st, nil
 }
 
 // 新增：带说话人分离的转录方法
-func (st *SherpaTranscriber) TranscribeAudioWithDiarization(audioData []byte, format string) (*TranscriptionResult, error) {
-	if !st.diarizationEnabled {
+func (st *SherpaTranscriber) TranscribeAudioWithDiarization(audioData []byte, opts TranscribeOptions) (*TranscriptionResult, error) {
+	if !st.diarizationEnabled || st.diarizer == nil {
 		return nil, fmt.Errorf("说话人分离功能未启用")
 	}
 
-	// 处理音频数据
-	audioSamples, err := st.processAudioData(audioData, format)
+	recognizer, err := st.recognizerFor(opts)
 	if err != nil {
-		return nil, fmt.Errorf("处理音频数据失败: %v", err)
+		return nil, err
 	}
+	defer recognizer.release()
 
-	// 创建说话人分离器（简化实现）
-	// 注意：这里需要根据实际的 sherpa-onnx-go API 来调整
-	// 当前实现为占位符，实际使用时需要参考官方示例
-	diarizer, err := st.createSpeakerDiarizer()
+	// 处理音频数据
+	audioSamples, err := st.processAudioData(audioData, opts)
 	if err != nil {
-		return nil, fmt.Errorf("创建说话人分离器失败: %v", err)
+		return nil, fmt.Errorf("处理音频数据失败: %w", err)
 	}
-	defer st.cleanupSpeakerDiarizer(diarizer)
+	return st.transcribeDiarization(audioSamples, recognizer.recognize, opts)
+}
 
-	// 执行说话人分离
-	speakerSegments, err := st.performDiarization(diarizer, audioSamples)
+// transcribeDiarization 执行说话人分离，每个片段只识别自己的音频
+func (st *SherpaTranscriber) transcribeDiarization(audioSamples []float32, recognize func([]float32) (recognition, error), opts TranscribeOptions) (*TranscriptionResult, error) {
+	sampleRate := st.sampleRate
+	speakerSegments, tokens, err := buildSpeakerSegments(st.diarizer, audioSamples, sampleRate, recognize, opts.Progress)
 	if err != nil {
 		return nil, fmt.Errorf("说话人分离计算失败: %v", err)
 	}
 
-	// 创建流进行语音识别
-	stream := sherpa_onnx.NewOnlineStream(st.recognizer)
-	if stream == nil {
-		return nil, fmt.Errorf("创建音频流失败")
+	// 整段文本由各片段文本拼接而成
+	texts := make([]string, 0, len(speakerSegments))
+	for _, seg := range speakerSegments {
+		if seg.Text != "" {
+			texts = append(texts, seg.Text)
+		}
 	}
-	defer sherpa_onnx.DeleteOnlineStream(stream)
-
-	// 将音频数据输入到流中
-	stream.AcceptWaveform(st.config.FeatConfig.SampleRate, audioSamples)
-	stream.InputFinished()
-
-	// 获取识别结果
-	recognitionResult := st.recognizer.GetResult(stream)
 
-	return &TranscriptionResult{
-		Text:            strings.TrimSpace(recognitionResult.Text),
-		Confidence:      0.95,
-		Duration:        float64(len(audioSamples)) / float64(st.config.FeatConfig.SampleRate),
+	result := &TranscriptionResult{
+		Text:            strings.Join(texts, " "),
+		Confidence:      aggregateConfidence(tokens),
+		Duration:        float64(len(audioSamples)) / float64(sampleRate),
 		SpeakerSegments: speakerSegments,
-	}, nil
+	}
+	if opts.Timestamps {
+		if err := result.setTokens(tokens); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-// 创建说话人分离器（占位符实现）
-func (st *SherpaTranscriber) createSpeakerDiarizer() (interface{}, error) {
-	// 这里需要根据实际的 sherpa-onnx-go API 来实现
-	// 参考：https://github.com/k2-fsa/sherpa-onnx/blob/master/go-api-examples/non-streaming-speaker-diarization/main.go
-	st.logger.Info("创建说话人分离器（功能待实现）")
-	return nil, nil
+// SetDiarizer 替换说话人分离后端并启用说话人分离
+func (st *SherpaTranscriber) SetDiarizer(diarizer Diarizer) {
+	if st.diarizer != nil && st.diarizer != diarizer {
+		st.diarizer.Close()
+	}
+	st.diarizer = diarizer
+	st.diarizationEnabled = diarizer != nil
 }
 
-// 清理说话人分离器（占位符实现）
-func (st *SherpaTranscriber) cleanupSpeakerDiarizer(diarizer interface{}) {
-	// 这里需要根据实际的 sherpa-onnx-go API 来实现
-	st.logger.Info("清理说话人分离器（功能待实现）")
+// SetSegmenter 启用语音检测切分，workers 为同时识别的句子数量
+// 启用说话人分离时按说话人片段识别，不使用语音检测
+func (st *SherpaTranscriber) SetSegmenter(segmenter Segmenter, workers int) {
+	if st.segmenter != nil && st.segmenter != segmenter {
+		st.segmenter.Close()
+	}
+	st.segmenter = segmenter
+	st.segmentWorkers = workers
 }
 
-// 执行说话人分离（占位符实现）
-func (st *SherpaTranscriber) performDiarization(diarizer interface{}, audioSamples []float32) ([]SpeakerSegment, error) {
-	// 这里需要根据实际的 sherpa-onnx-go API 来实现
-	// 当前返回空的说话人片段
-	st.logger.Info("执行说话人分离（功能待实现）")
-	return []SpeakerSegment{}, nil
+// transcribeSegments 按语音检测切分句子后逐句识别，整段文本由各句文本拼接而成
+func (st *SherpaTranscriber) transcribeSegments(samples []float32, recognize func([]float32) (recognition, error), opts TranscribeOptions) (*TranscriptionResult, error) {
+	segments, tokens, err := buildSegments(st.segmenter, samples, st.sampleRate, st.segmentWorkers, recognize, opts.Progress)
+	if err != nil {
+		return nil, fmt.Errorf("语音检测切分失败: %v", err)
+	}
+	return st.segmentsResult(segments, tokens, len(samples), opts)
 }
 
-func (st *SherpaTranscriber) TranscribeAudio(audioData []byte, format string) (*TranscriptionResult, error) {
-	if st.recognizer == nil {
-		return nil, fmt.Errorf("识别器未初始化")
+// segmentsResult 用分段识别的结果组装转录结果，整段文本由各句文本拼接而成
+func (st *SherpaTranscriber) segmentsResult(segments []Segment, tokens []Token, numSamples int, opts TranscribeOptions) (*TranscriptionResult, error) {
+	texts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		texts = append(texts, seg.Text)
 	}
 
-	// 如果启用了说话人分离，使用带说话人分离的方法
-	if st.diarizationEnabled {
-		return st.TranscribeAudioWithDiarization(audioData, format)
+	result := &TranscriptionResult{
+		Text:       strings.Join(texts, " "),
+		Confidence: aggregateConfidence(tokens),
+		Duration:   float64(numSamples) / float64(st.sampleRate),
+		Segments:   segments,
 	}
+	if opts.Timestamps {
+		if err := result.setTokens(tokens); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
 
-	// 创建流
-	stream := sherpa_onnx.NewOnlineStream(st.recognizer)
+// recognizeSamples 用一个独立的流识别给定的音频采样
+func (st *SherpaTranscriber) recognizeSamples(recognizer *sherpa_onnx.OnlineRecognizer, samples []float32) (recognition, error) {
+	if len(samples) == 0 {
+		return recognition{}, nil
+	}
+
+	stream := sherpa_onnx.NewOnlineStream(recognizer)
 	if stream == nil {
-		return nil, fmt.Errorf("创建音频流失败")
+		return recognition{}, fmt.Errorf("创建音频流失败")
 	}
 	defer sherpa_onnx.DeleteOnlineStream(stream)
 
-	// 处理音频数据
-	audioSamples, err := st.processAudioData(audioData, format)
+	sampleRate := st.sampleRate
+	stream.AcceptWaveform(sampleRate, samples)
+
+	// 补一段静音，保证最后几帧也能被解码
+	tailPaddings := make([]float32, int(float32(sampleRate)*0.3))
+	stream.AcceptWaveform(sampleRate, tailPaddings)
+	stream.InputFinished()
+
+	for recognizer.IsReady(stream) {
+		recognizer.Decode(stream)
+	}
+
+	result, err := getOnlineResult(recognizer, stream)
 	if err != nil {
-		return nil, fmt.Errorf("处理音频数据失败: %v", err)
+		return recognition{}, err
 	}
+	return result.recognition(float64(len(samples)) / float64(sampleRate)), nil
+}
 
-	// 将音频数据输入到流中
-	stream.AcceptWaveform(st.config.FeatConfig.SampleRate, audioSamples)
+func (st *SherpaTranscriber) TranscribeAudio(audioData []byte, opts TranscribeOptions) (*TranscriptionResult, error) {
+	// 如果启用了说话人分离，使用带说话人分离的方法
+	if st.diarizationEnabled {
+		return st.TranscribeAudioWithDiarization(audioData, opts)
+	}
 
-	// 标记输入结束
-	stream.InputFinished()
+	recognizer, err := st.recognizerFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer recognizer.release()
+
+	// 处理音频数据
+	audioSamples, err := st.processAudioData(audioData, opts)
+	if err != nil {
+		return nil, fmt.Errorf("处理音频数据失败: %w", err)
+	}
 
-	// 获取识别结果
-	result := st.recognizer.GetResult(stream)
+	if st.segmenter != nil {
+		return st.transcribeSegments(audioSamples, recognizer.recognize, opts)
+	}
 
-	return &TranscriptionResult{
-		Text:       strings.TrimSpace(result.Text),
-		Confidence: 0.95, // sherpa-onnx 可能不提供置信度
-		Duration:   float64(len(audioSamples)) / float64(st.config.FeatConfig.SampleRate),
-	}, nil
+	rec, err := recognizer.recognize(audioSamples)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TranscriptionResult{
+		Text:       rec.text,
+		Confidence: aggregateConfidence(rec.tokens),
+		Duration:   float64(len(audioSamples)) / float64(st.sampleRate),
+	}
+	if opts.Timestamps {
+		if err := result.setTokens(rec.tokens); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-func (st *SherpaTranscriber) TranscribeStream(audioData []byte) (*TranscriptionResult, error) {
-	// 流式转录实现
-	return st.TranscribeAudio(audioData, "wav")
+// TranscribeReader 边读边解码进行转录
+// 流式识别直接把解码出的音频送入识别器；启用语音检测时每次切分 segmentBlockSeconds 秒的音频。
+// 说话人分离和不分段的离线识别需要整段音频，这时只在内存中保留解码后的采样
+func (st *SherpaTranscriber) TranscribeReader(r io.Reader, opts TranscribeOptions) (*TranscriptionResult, error) {
+	recognizer, err := st.recognizerFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer recognizer.release()
+	recognize := recognizer.recognize
+
+	counter := &countingReader{r: r}
+	var input io.Reader = counter
+	if opts.Context != nil {
+		input = &contextReader{ctx: opts.Context, r: counter}
+		recognize = func(samples []float32) (recognition, error) {
+			if err := opts.Context.Err(); err != nil {
+				return recognition{}, err
+			}
+			return recognizer.recognize(samples)
+		}
+	}
+	reader, err := st.openAudioReader(input, opts)
+	if err != nil {
+		return nil, fmt.Errorf("处理音频数据失败: %w", err)
+	}
+
+	// 按已读取的字节数报告进度，最后由调用方标记完成
+	progress := func() {}
+	if opts.Progress != nil && opts.Size > 0 {
+		progress = func() {
+			if p := float64(counter.n) / float64(opts.Size); p < 1 {
+				opts.Progress(p)
+			}
+		}
+	}
+
+	switch {
+	case st.diarizationEnabled:
+		audio, err := ReadAllSamples(reader)
+		if err != nil {
+			return nil, fmt.Errorf("处理音频数据失败: %w", err)
+		}
+		return st.transcribeDiarization(audio.Samples, recognize, opts)
+	case st.segmenter != nil:
+		blockSize := segmentBlockSeconds * st.sampleRate
+		segments, tokens, numSamples, err := streamSegments(st.segmenter, reader, blockSize, st.segmentWorkers, recognize, progress)
+		if err != nil {
+			return nil, fmt.Errorf("语音检测切分失败: %v", err)
+		}
+		return st.segmentsResult(segments, tokens, numSamples, opts)
+	}
+
+	var rec recognition
+	var numSamples int
+	if recognizer.online == nil {
+		audio, err := ReadAllSamples(reader)
+		if err != nil {
+			return nil, fmt.Errorf("处理音频数据失败: %w", err)
+		}
+		numSamples = len(audio.Samples)
+		rec, err = recognize(audio.Samples)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rec, numSamples, err = st.recognizeReader(recognizer.online, reader, progress)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &TranscriptionResult{
+		Text:       rec.text,
+		Confidence: aggregateConfidence(rec.tokens),
+		Duration:   float64(numSamples) / float64(st.sampleRate),
+	}
+	if opts.Timestamps {
+		if err := result.setTokens(rec.tokens); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-func (st *SherpaTranscriber) processAudioData(audioData []byte, format string) ([]float32, error) {
-	switch strings.ToLower(format) {
-	case "wav":
-		return st.processWavData(audioData)
-	case "pcm":
-		return st.processPcmData(audioData)
-	default:
-		return nil, fmt.Errorf("不支持的音频格式: %s", format)
+// openAudioReader 创建增量解码并重采样到模型采样率的读取器
+func (st *SherpaTranscriber) openAudioReader(r io.Reader, opts TranscribeOptions) (AudioReader, error) {
+	sampleRate := opts.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = st.sampleRate
+	}
+
+	reader, err := NewAudioReader(r, opts.Format, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	if reader.SampleRate() != st.sampleRate {
+		st.logger.Debugf("将音频从 %dHz 重采样到 %dHz", reader.SampleRate(), st.sampleRate)
 	}
+	return NewResampledReader(reader, st.sampleRate)
 }
 
-func (st *SherpaTranscriber) processWavData(audioData []byte) ([]float32, error) {
-	// 简单的 WAV 文件处理
-	// 这里假设是 16-bit PCM WAV 文件
-	if len(audioData) < 44 {
-		return nil, fmt.Errorf("音频数据太短，不是有效的 WAV 文件")
+// recognizeReader 把 reader 中的音频逐块送入一个流式识别的流，返回识别结果和采样总数
+func (st *SherpaTranscriber) recognizeReader(recognizer *sherpa_onnx.OnlineRecognizer, reader AudioReader, progress func()) (recognition, int, error) {
+	stream := sherpa_onnx.NewOnlineStream(recognizer)
+	if stream == nil {
+		return recognition{}, 0, fmt.Errorf("创建音频流失败")
+	}
+	defer sherpa_onnx.DeleteOnlineStream(stream)
+
+	sampleRate := st.sampleRate
+	buf := make([]float32, readBlockSize)
+	total := 0
+	for {
+		n, err := reader.ReadSamples(buf)
+		if n > 0 {
+			stream.AcceptWaveform(sampleRate, buf[:n])
+			total += n
+			for recognizer.IsReady(stream) {
+				recognizer.Decode(stream)
+			}
+			progress()
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return recognition{}, 0, fmt.Errorf("处理音频数据失败: %w", err)
+		}
+	}
+	if total == 0 {
+		return recognition{}, 0, nil
 	}
 
-	// 跳过 WAV 头部（44字节）
-	audioSamples := make([]float32, 0, (len(audioData)-44)/2)
+	// 补一段静音，保证最后几帧也能被解码
+	tailPaddings := make([]float32, int(float32(sampleRate)*0.3))
+	stream.AcceptWaveform(sampleRate, tailPaddings)
+	stream.InputFinished()
 
-	for i := 44; i < len(audioData)-1; i += 2 {
-		// 将 16-bit 整数转换为 float32
-		sample := int16(binary.LittleEndian.Uint16(audioData[i : i+2]))
-		audioSamples = append(audioSamples, float32(sample)/32768.0)
+	for recognizer.IsReady(stream) {
+		recognizer.Decode(stream)
 	}
 
-	return audioSamples, nil
+	result, err := getOnlineResult(recognizer, stream)
+	if err != nil {
+		return recognition{}, 0, err
+	}
+	return result.recognition(float64(total) / float64(sampleRate)), total, nil
+}
+
+// countingReader 记录已经读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
 }
 
-func (st *SherpaTranscriber) processPcmData(audioData []byte) ([]float32, error) {
-	// 处理原始 PCM 数据
-	audioSamples := make([]float32, 0, len(audioData)/2)
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// contextReader 在 ctx 取消后停止读取，让边读边解码的识别尽快结束
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-	for i := 0; i < len(audioData)-1; i += 2 {
-		sample := int16(binary.LittleEndian.Uint16(audioData[i : i+2]))
-		audioSamples = append(audioSamples, float32(sample)/32768.0)
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
 	}
+	return c.r.Read(p)
+}
 
-	return audioSamples, nil
+func (st *SherpaTranscriber) TranscribeStream(audioData []byte) (*TranscriptionResult, error) {
+	// 流式转录实现
+	return st.TranscribeAudio(audioData, TranscribeOptions{Format: "wav"})
+}
+
+// processAudioData 解码音频并重采样到模型采样率
+func (st *SherpaTranscriber) processAudioData(audioData []byte, opts TranscribeOptions) ([]float32, error) {
+	modelRate := st.sampleRate
+
+	sampleRate := opts.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = modelRate
+	}
+
+	audio, err := DecodeAudio(audioData, opts.Format, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if audio.SampleRate != modelRate {
+		st.logger.Debugf("将音频从 %dHz 重采样到 %dHz", audio.SampleRate, modelRate)
+	}
+	audio, err = audio.Resample(modelRate)
+	if err != nil {
+		return nil, fmt.Errorf("重采样失败: %v", err)
+	}
+
+	return audio.Samples, nil
 }
 
 func (st *SherpaTranscriber) Close() error {
+	if st.diarizer != nil {
+		st.diarizer.Close()
+		st.diarizer = nil
+	}
+	if st.segmenter != nil {
+		st.segmenter.Close()
+		st.segmenter = nil
+	}
+	st.custom.close()
+	if st.offlineRecognizer != nil {
+		sherpa_onnx.DeleteOfflineRecognizer(st.offlineRecognizer)
+		st.offlineRecognizer = nil
+	}
+	if st.decodeLoop != nil {
+		st.decodeLoop.close()
+	}
 	if st.recognizer != nil {
 		sherpa_onnx.DeleteOnlineRecognizer(st.recognizer)
 	}
 	return nil
 }
 
+// NewSession 创建一个基于 OnlineStream 的流式识别会话
+// 设置了热词时会话使用按热词创建的识别器，与相同热词的会话一起批量解码
+func (st *SherpaTranscriber) NewSession(opts DecodingOptions) (Session, error) {
+	if st.recognizer == nil {
+		return nil, fmt.Errorf("%w: 当前模型不支持流式识别", ErrModeUnavailable)
+	}
+
+	recognizers, release, err := st.recognizers(ModeOnline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := sherpa_onnx.NewOnlineStream(recognizers.online)
+	if stream == nil {
+		release()
+		return nil, fmt.Errorf("创建音频流失败")
+	}
+
+	return &sherpaSession{
+		loop:       recognizers.loop,
+		stream:     stream,
+		state:      newStreamState(stream),
+		sampleRate: st.sampleRate,
+		resampler:  streamResampler{targetRate: st.sampleRate},
+		release:    release,
+	}, nil
+}
+
 // 添加获取识别器的方法
 func (st *SherpaTranscriber) GetRecognizer() *sherpa_onnx.OnlineRecognizer {
 	return st.recognizer
@@ -297,5 +591,58 @@ func (st *SherpaTranscriber) GetRecognizer() *sherpa_onnx.OnlineRecognizer {
 
 // 添加获取采样率的方法
 func (st *SherpaTranscriber) GetSampleRate() int {
-	return st.config.FeatConfig.SampleRate
+	return st.sampleRate
+}
+
+// sherpaSession 基于 sherpa-onnx OnlineStream 的流式识别会话
+// 音频交给转录器的解码循环，与其他会话一起批量解码
+type sherpaSession struct {
+	loop       *decodeLoop
+	stream     *sherpa_onnx.OnlineStream
+	state      *streamState
+	sampleRate int
+	resampler  streamResampler
+	// release 归还会话使用的识别器
+	release func()
+	mu      sync.Mutex
+}
+
+func (s *sherpaSession) AcceptWaveform(sampleRate int, samples []float32) ([]StreamResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil {
+		return nil, fmt.Errorf("会话已关闭")
+	}
+
+	samples, err := s.resampler.process(sampleRate, samples)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	return s.loop.submit(s.state, s.sampleRate, samples, false)
+}
+
+func (s *sherpaSession) Flush() ([]StreamResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil {
+		return nil, fmt.Errorf("会话已关闭")
+	}
+	return s.loop.submit(s.state, s.sampleRate, s.resampler.flush(), true)
+}
+
+func (s *sherpaSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream != nil {
+		sherpa_onnx.DeleteOnlineStream(s.stream)
+		s.stream = nil
+		s.release()
+	}
+	return nil
 }
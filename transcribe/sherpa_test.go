@@ -53,7 +53,7 @@ func TestTranscribeAudioWithDiarization(t *testing.T) {
 	audioData := make([]byte, 1000)
 
 	// 测试带说话人分离的转录
-	result, err := transcriber.TranscribeAudioWithDiarization(audioData, "wav")
+	result, err := transcriber.TranscribeAudioWithDiarization(audioData, TranscribeOptions{Format: "wav"})
 
 	// 由于当前实现为占位符，我们期望得到错误或空结果
 	if err == nil {
@@ -0,0 +1,367 @@
+package transcribe
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 转录结果的输出格式
+const (
+	OutputJSON = "json"
+	OutputText = "text"
+	OutputSRT  = "srt"
+	OutputVTT  = "vtt"
+	OutputTSV  = "tsv"
+)
+
+// 字幕切分的默认限制
+const (
+	defaultMaxLineLength = 42
+	defaultMaxLines      = 2
+	defaultMaxCueSeconds = 7.0
+	// maxCueGap 停顿超过该秒数时开始新的一条字幕，避免字幕在静音期间一直显示
+	maxCueGap = 1.0
+)
+
+// SubtitleOptions 字幕切分选项，为 0 时使用默认值
+type SubtitleOptions struct {
+	// MaxLineLength 每行最多字符数，默认 42
+	MaxLineLength int
+	// MaxLines 每条字幕最多行数，默认 2
+	MaxLines int
+	// MaxDuration 每条字幕最长秒数，默认 7
+	MaxDuration float64
+}
+
+func (o SubtitleOptions) withDefaults() SubtitleOptions {
+	if o.MaxLineLength <= 0 {
+		o.MaxLineLength = defaultMaxLineLength
+	}
+	if o.MaxLines <= 0 {
+		o.MaxLines = defaultMaxLines
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = defaultMaxCueSeconds
+	}
+	return o
+}
+
+// Cue 一条字幕
+type Cue struct {
+	Start float64
+	End   float64
+	// Speaker 说话人编号，没有说话人分离结果时为 -1
+	Speaker int
+	// Lines 按行长度折行后的文本
+	Lines []string
+}
+
+// IsOutputFormat 返回是否为支持的输出格式
+func IsOutputFormat(format string) bool {
+	switch format {
+	case OutputJSON, OutputText, OutputSRT, OutputVTT, OutputTSV:
+		return true
+	}
+	return false
+}
+
+// OutputContentType 返回输出格式对应的 Content-Type
+func OutputContentType(format string) string {
+	switch format {
+	case OutputSRT:
+		return "application/x-subrip; charset=utf-8"
+	case OutputVTT:
+		return "text/vtt; charset=utf-8"
+	case OutputTSV:
+		return "text/tab-separated-values; charset=utf-8"
+	case OutputText:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// RenderTranscript 把转录结果渲染为 text、srt、vtt 或 tsv
+func RenderTranscript(result *TranscriptionResult, format string, opts SubtitleOptions) ([]byte, error) {
+	switch format {
+	case OutputText:
+		return renderText(result), nil
+	case OutputSRT:
+		return renderSRT(BuildCues(result, opts)), nil
+	case OutputVTT:
+		return renderVTT(BuildCues(result, opts)), nil
+	case OutputTSV:
+		return renderTSV(BuildCues(result, opts), len(result.SpeakerSegments) > 0), nil
+	}
+	return nil, fmt.Errorf("不支持的输出格式: %s", format)
+}
+
+// speakerLabel 说话人标签，编号与 speaker_segments 中的 speaker_id 一致
+func speakerLabel(speaker int) string {
+	return fmt.Sprintf("SPEAKER_%02d", speaker)
+}
+
+func renderText(result *TranscriptionResult) []byte {
+	var buf bytes.Buffer
+	if len(result.SpeakerSegments) == 0 {
+		buf.WriteString(result.Text)
+		buf.WriteString("\n")
+		return buf.Bytes()
+	}
+
+	for _, seg := range result.SpeakerSegments {
+		if seg.Text == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", speakerLabel(seg.SpeakerID), seg.Text)
+	}
+	return buf.Bytes()
+}
+
+func renderSRT(cues []Cue) []byte {
+	var buf bytes.Buffer
+	for i, cue := range cues {
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n", i+1, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","))
+		lines := cue.Lines
+		if cue.Speaker >= 0 {
+			lines = append([]string{"[" + speakerLabel(cue.Speaker) + "] " + lines[0]}, lines[1:]...)
+		}
+		buf.WriteString(strings.Join(lines, "\n"))
+		buf.WriteString("\n\n")
+	}
+	return buf.Bytes()
+}
+
+// vttEscaper 转义 WebVTT 字幕文本中的特殊字符，否则 < 会被当成标签，--> 会被当成时间轴
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func renderVTT(cues []Cue) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&buf, "%s --> %s\n", formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."))
+		text := vttEscaper.Replace(strings.Join(cue.Lines, "\n"))
+		if cue.Speaker >= 0 {
+			text = "<v " + speakerLabel(cue.Speaker) + ">" + text
+		}
+		buf.WriteString(text)
+		buf.WriteString("\n\n")
+	}
+	return buf.Bytes()
+}
+
+// renderTSV 每行一条字幕，时间单位为毫秒，有说话人时增加 speaker 列
+func renderTSV(cues []Cue, speakers bool) []byte {
+	var buf bytes.Buffer
+	if speakers {
+		buf.WriteString("start\tend\tspeaker\ttext\n")
+	} else {
+		buf.WriteString("start\tend\ttext\n")
+	}
+	for _, cue := range cues {
+		fmt.Fprintf(&buf, "%d\t%d\t", milliseconds(cue.Start), milliseconds(cue.End))
+		if speakers {
+			buf.WriteString(speakerLabel(cue.Speaker))
+			buf.WriteString("\t")
+		}
+		buf.WriteString(joinWords(cue.Lines))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func milliseconds(seconds float64) int64 {
+	return int64(math.Round(seconds * 1000))
+}
+
+// formatTimestamp 格式化为 HH:MM:SS,mmm（SRT）或 HH:MM:SS.mmm（WebVTT）
+func formatTimestamp(seconds float64, sep string) string {
+	ms := milliseconds(seconds)
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// cueUnit 切分字幕的最小单位：一个词，或者没有词时间戳时的一段文本
+type cueUnit struct {
+	text    string
+	start   float64
+	end     float64
+	speaker int
+}
+
+// BuildCues 把转录结果切分为字幕
+// 有词时间戳时按词切分；否则使用说话人片段、语音检测切分的句子或整段文本，并按字符数比例估计切分点的时间。
+// 说话人变化、停顿超过 1 秒、超过行数限制或超过时长限制时开始新的一条字幕
+func BuildCues(result *TranscriptionResult, opts SubtitleOptions) []Cue {
+	opts = opts.withDefaults()
+
+	var cues []Cue
+	var current []cueUnit
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		cues = append(cues, Cue{
+			Start:   current[0].start,
+			End:     current[len(current)-1].end,
+			Speaker: current[0].speaker,
+			Lines:   wrapLines(current, opts.MaxLineLength),
+		})
+		current = nil
+	}
+
+	for _, unit := range cueUnits(result, opts) {
+		if len(current) > 0 {
+			candidate := append(append([]cueUnit(nil), current...), unit)
+			if unit.speaker != current[0].speaker ||
+				unit.start-current[len(current)-1].end > maxCueGap ||
+				unit.end-current[0].start > opts.MaxDuration ||
+				len(wrapLines(candidate, opts.MaxLineLength)) > opts.MaxLines {
+				flush()
+			}
+		}
+		current = append(current, unit)
+	}
+	flush()
+	return cues
+}
+
+// cueUnits 返回切分字幕使用的单位
+func cueUnits(result *TranscriptionResult, opts SubtitleOptions) []cueUnit {
+	if len(result.Words) > 0 {
+		units := make([]cueUnit, 0, len(result.Words))
+		for _, word := range result.Words {
+			units = append(units, cueUnit{
+				text:    word.Text,
+				start:   word.Start,
+				end:     word.End,
+				speaker: speakerAt(result.SpeakerSegments, (word.Start+word.End)/2),
+			})
+		}
+		return units
+	}
+
+	if len(result.SpeakerSegments) > 0 {
+		var units []cueUnit
+		for _, seg := range result.SpeakerSegments {
+			units = append(units, splitText(seg.Text, seg.Start, seg.End, seg.SpeakerID, opts)...)
+		}
+		return units
+	}
+
+	if len(result.Segments) > 0 {
+		var units []cueUnit
+		for _, seg := range result.Segments {
+			units = append(units, splitText(seg.Text, seg.Start, seg.End, -1, opts)...)
+		}
+		return units
+	}
+
+	return splitText(result.Text, 0, result.Duration, -1, opts)
+}
+
+// speakerAt 返回 t 时刻的说话人，不在任何片段内时使用最近的片段
+func speakerAt(segments []SpeakerSegment, t float64) int {
+	speaker := -1
+	best := math.Inf(1)
+	for _, seg := range segments {
+		var distance float64
+		switch {
+		case t < seg.Start:
+			distance = seg.Start - t
+		case t > seg.End:
+			distance = t - seg.End
+		}
+		if distance < best {
+			best = distance
+			speaker = seg.SpeakerID
+		}
+	}
+	return speaker
+}
+
+// splitText 把没有词时间戳的文本切成不超过一行长度和字幕时长的片段，时间按字符数比例分配
+func splitText(text string, start, end float64, speaker int, opts SubtitleOptions) []cueUnit {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	runes := []rune(text)
+	limit := opts.MaxLineLength
+	if end > start {
+		// 每段的时长也不能超过限制
+		if n := int(math.Ceil((end - start) / opts.MaxDuration)); n > 1 && len(runes)/n < limit {
+			limit = (len(runes) + n - 1) / n
+		}
+	}
+
+	var units []cueUnit
+	offset := 0
+	for offset < len(runes) {
+		n := breakPoint(runes[offset:], limit)
+		piece := strings.TrimSpace(string(runes[offset : offset+n]))
+		if piece != "" {
+			units = append(units, cueUnit{
+				text:    piece,
+				start:   start + (end-start)*float64(offset)/float64(len(runes)),
+				end:     start + (end-start)*float64(offset+n)/float64(len(runes)),
+				speaker: speaker,
+			})
+		}
+		offset += n
+	}
+	return units
+}
+
+// breakPoint 返回不超过 limit 个字符的切分位置，优先在空格或标点之后切分
+func breakPoint(runes []rune, limit int) int {
+	if len(runes) <= limit {
+		return len(runes)
+	}
+	for i := limit; i > limit/2; i-- {
+		if unicode.IsSpace(runes[i-1]) || unicode.IsPunct(runes[i-1]) {
+			return i
+		}
+	}
+	return limit
+}
+
+// wrapLines 把单位拼接成不超过 maxLength 个字符的行，过长的单位单独成行
+func wrapLines(units []cueUnit, maxLength int) []string {
+	var lines []string
+	var line []string
+	for _, unit := range units {
+		candidate := append(append([]string(nil), line...), unit.text)
+		if len(line) > 0 && utf8.RuneCountInString(joinWords(candidate)) > maxLength {
+			lines = append(lines, joinWords(line))
+			line = nil
+		}
+		line = append(line, unit.text)
+	}
+	if len(line) > 0 {
+		lines = append(lines, joinWords(line))
+	}
+	return lines
+}
+
+// joinWords 拼接词，表意文字之间不加空格
+func joinWords(words []string) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			last, _ := utf8.DecodeLastRuneInString(words[i-1])
+			first, _ := utf8.DecodeRuneInString(word)
+			if !isIdeograph(last) && !isIdeograph(first) {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// words 生成每个 0.5 秒的词
+func words(texts ...string) []Word {
+	result := make([]Word, len(texts))
+	for i, text := range texts {
+		result[i] = Word{Text: text, Start: float64(i) * 0.5, End: float64(i)*0.5 + 0.5}
+	}
+	return result
+}
+
+func TestBuildCuesFromWords(t *testing.T) {
+	result := &TranscriptionResult{Words: words("the", "quick", "brown", "fox", "jumps", "over", "the", "lazy", "dog")}
+
+	// 每行 10 个字符，每条 1 行
+	cues := BuildCues(result, SubtitleOptions{MaxLineLength: 10, MaxLines: 1})
+	var texts []string
+	for _, cue := range cues {
+		texts = append(texts, strings.Join(cue.Lines, "|"))
+	}
+	expected := []string{"the quick", "brown fox", "jumps over", "the lazy", "dog"}
+	if fmt.Sprint(texts) != fmt.Sprint(expected) {
+		t.Errorf("期望 %q，得到 %q", expected, texts)
+	}
+	if cues[1].Start != 1 || cues[1].End != 2 || cues[1].Speaker != -1 {
+		t.Errorf("第二条字幕的时间错误: %+v", cues[1])
+	}
+
+	// 两行一条，时长不超过 2 秒
+	cues = BuildCues(result, SubtitleOptions{MaxLineLength: 10, MaxLines: 2, MaxDuration: 2})
+	if len(cues) != 3 || fmt.Sprint(cues[0].Lines) != "[the quick brown fox]" {
+		t.Errorf("按时长切分错误: %+v", cues)
+	}
+	for _, cue := range cues {
+		if cue.End-cue.Start > 2 {
+			t.Errorf("字幕时长超过限制: %+v", cue)
+		}
+	}
+}
+
+func TestBuildCuesSpeakers(t *testing.T) {
+	result := &TranscriptionResult{
+		Words: words("你", "好", "再", "见"),
+		SpeakerSegments: []SpeakerSegment{
+			{SpeakerID: 0, Start: 0, End: 1, Text: "你好"},
+			{SpeakerID: 1, Start: 1, End: 2, Text: "再见"},
+		},
+	}
+
+	cues := BuildCues(result, SubtitleOptions{})
+	if len(cues) != 2 {
+		t.Fatalf("说话人变化时应该切分字幕，得到 %+v", cues)
+	}
+	if cues[0].Lines[0] != "你好" || cues[0].Speaker != 0 || cues[1].Lines[0] != "再见" || cues[1].Speaker != 1 {
+		t.Errorf("说话人字幕错误: %+v", cues)
+	}
+
+	// 没有词时间戳时使用说话人片段
+	result.Words = nil
+	cues = BuildCues(result, SubtitleOptions{})
+	if len(cues) != 2 || cues[1].Start != 1 || cues[1].Speaker != 1 {
+		t.Errorf("按说话人片段生成的字幕错误: %+v", cues)
+	}
+}
+
+func TestBuildCuesFromSegments(t *testing.T) {
+	// 没有词时间戳时使用语音检测切分的句子
+	result := &TranscriptionResult{
+		Text: "你好 再见",
+		Segments: []Segment{
+			{Start: 1, End: 2, Text: "你好"},
+			{Start: 5, End: 6, Text: "再见"},
+		},
+		Duration: 8,
+	}
+
+	cues := BuildCues(result, SubtitleOptions{})
+	if len(cues) != 2 || cues[0].Start != 1 || cues[1].Start != 5 || cues[1].Lines[0] != "再见" || cues[1].Speaker != -1 {
+		t.Errorf("按句子生成的字幕错误: %+v", cues)
+	}
+}
+
+func TestBuildCuesFromText(t *testing.T) {
+	// 没有任何时间戳时按字符数比例估计时间
+	result := &TranscriptionResult{Text: "今天天气很好，我们去公园散步吧", Duration: 3}
+
+	cues := BuildCues(result, SubtitleOptions{MaxLineLength: 6, MaxLines: 1})
+	var texts []string
+	for _, cue := range cues {
+		texts = append(texts, cue.Lines[0])
+	}
+	expected := []string{"今天天气很好", "，我们去公园", "散步吧"}
+	if fmt.Sprint(texts) != fmt.Sprint(expected) {
+		t.Errorf("期望 %q，得到 %q", expected, texts)
+	}
+	if cues[0].Start != 0 || cues[2].End != 3 {
+		t.Errorf("字幕时间应该覆盖整段音频: %+v", cues)
+	}
+
+	// 时长限制同样适用
+	cues = BuildCues(&TranscriptionResult{Text: "hello world", Duration: 20}, SubtitleOptions{MaxDuration: 5})
+	if len(cues) < 4 {
+		t.Errorf("20 秒的文本应该至少切成 4 条，得到 %+v", cues)
+	}
+}
+
+func TestRenderTranscript(t *testing.T) {
+	result := &TranscriptionResult{
+		Text: "你好 再见",
+		Words: []Word{
+			{Text: "你", Start: 0, End: 0.5},
+			{Text: "好", Start: 0.5, End: 1},
+			{Text: "再", Start: 3661, End: 3661.5},
+			{Text: "见", Start: 3661.5, End: 3662.25},
+		},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{OutputText, "你好 再见\n"},
+		{OutputSRT, "1\n00:00:00,000 --> 00:00:01,000\n你好\n\n2\n01:01:01,000 --> 01:01:02,250\n再见\n\n"},
+		{OutputVTT, "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n你好\n\n01:01:01.000 --> 01:01:02.250\n再见\n\n"},
+		{OutputTSV, "start\tend\ttext\n0\t1000\t你好\n3661000\t3662250\t再见\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := RenderTranscript(result, tt.format, SubtitleOptions{})
+			if err != nil {
+				t.Fatalf("渲染失败: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("期望:\n%q\n得到:\n%q", tt.expected, data)
+			}
+		})
+	}
+
+	if _, err := RenderTranscript(result, "docx", SubtitleOptions{}); err == nil {
+		t.Error("不支持的输出格式应该返回错误")
+	}
+}
+
+func TestRenderTranscriptSpeakers(t *testing.T) {
+	result := &TranscriptionResult{
+		Text: "你好 再见",
+		SpeakerSegments: []SpeakerSegment{
+			{SpeakerID: 0, Start: 0, End: 1, Text: "你好"},
+			{SpeakerID: 1, Start: 1, End: 2, Text: "再见"},
+		},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{OutputText, "SPEAKER_00: 你好\nSPEAKER_01: 再见\n"},
+		{OutputSRT, "1\n00:00:00,000 --> 00:00:01,000\n[SPEAKER_00] 你好\n\n2\n00:00:01,000 --> 00:00:02,000\n[SPEAKER_01] 再见\n\n"},
+		{OutputVTT, "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n<v SPEAKER_00>你好\n\n00:00:01.000 --> 00:00:02.000\n<v SPEAKER_01>再见\n\n"},
+		{OutputTSV, "start\tend\tspeaker\ttext\n0\t1000\tSPEAKER_00\t你好\n1000\t2000\tSPEAKER_01\t再见\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := RenderTranscript(result, tt.format, SubtitleOptions{})
+			if err != nil {
+				t.Fatalf("渲染失败: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("期望:\n%q\n得到:\n%q", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestRenderVTTEscape(t *testing.T) {
+	result := &TranscriptionResult{
+		SpeakerSegments: []SpeakerSegment{
+			{SpeakerID: 0, Start: 0, End: 1, Text: "A&B <b> --> C"},
+		},
+	}
+
+	data, err := RenderTranscript(result, OutputVTT, SubtitleOptions{})
+	if err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+	expected := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n<v SPEAKER_00>A&amp;B &lt;b&gt; --&gt; C\n\n"
+	if string(data) != expected {
+		t.Errorf("期望:\n%q\n得到:\n%q", expected, data)
+	}
+}
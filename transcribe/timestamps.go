@@ -0,0 +1,204 @@
+package transcribe
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokenDuration token 的最长时长（秒），避免停顿前的最后一个 token 覆盖整段静音
+const maxTokenDuration = 1.0
+
+// ErrTimestampsUnavailable 请求了时间戳，但当前模型不输出 token 的时间戳
+var ErrTimestampsUnavailable = errors.New("当前模型不提供时间戳")
+
+// Token 识别器输出的一个 token，时间单位为秒
+type Token struct {
+	Text  string  `json:"text"`
+	Start float64 `json:"start"`
+	// End 下一个 token 的开始时间，最长 1 秒
+	End float64 `json:"end"`
+	// Confidence token 的概率，识别器不提供时为 0
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// Word 由 token 合并得到的词，中文、日文等不以空格分词的语言每个 token 是一个词
+type Word struct {
+	Text  string  `json:"text"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Confidence 词中各 token 概率的几何平均，识别器不提供概率时为 0
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// recognition 一段音频的识别结果
+type recognition struct {
+	text string
+	// tokens 识别器不提供 token 时间戳时为空
+	tokens []Token
+}
+
+// newTokens 把识别器输出的 token、开始时间和对数概率合并为 Token，duration 为音频时长
+// 时间戳与 token 数量不一致时无法对齐，返回 nil；logProbs 数量不一致时不设置置信度；
+// <|zh|> 这样的特殊 token 会被去掉
+func newTokens(texts []string, timestamps []float32, logProbs []float32, duration float64) []Token {
+	if len(texts) == 0 || len(texts) != len(timestamps) {
+		return nil
+	}
+
+	tokens := make([]Token, 0, len(texts))
+	for i, text := range texts {
+		if text == "" || isSpecialToken(text) {
+			continue
+		}
+
+		start := roundTime(timestamps[i])
+		end := duration
+		if i+1 < len(timestamps) {
+			end = roundTime(timestamps[i+1])
+		}
+		if end > start+maxTokenDuration {
+			end = start + maxTokenDuration
+		}
+		if end < start {
+			end = start
+		}
+		token := Token{Text: text, Start: start, End: end}
+		if len(logProbs) == len(texts) {
+			token.Confidence = math.Exp(float64(logProbs[i]))
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// roundTime 把 float32 时间戳转换为保留到毫秒的秒数
+func roundTime(t float32) float64 {
+	return roundSeconds(float64(t))
+}
+
+// roundSeconds 把秒数保留到毫秒
+func roundSeconds(t float64) float64 {
+	return math.Round(t*1000) / 1000
+}
+
+// isSpecialToken 返回是否为语言、情感等标记，例如 sense-voice 的 <|zh|>
+func isSpecialToken(text string) bool {
+	return strings.HasPrefix(text, "<|") && strings.HasSuffix(text, "|>")
+}
+
+// offsetTokens 把 token 的时间整体后移 offset 秒，结果保留到毫秒
+func offsetTokens(tokens []Token, offset float64) []Token {
+	shifted := make([]Token, len(tokens))
+	for i, token := range tokens {
+		token.Start = roundSeconds(token.Start + offset)
+		token.End = roundSeconds(token.End + offset)
+		shifted[i] = token
+	}
+	return shifted
+}
+
+// buildWords 把 token 合并为词
+// 以 ▁ 或空格开头的 token 开始一个新词，以 @@ 结尾的 token 与下一个 token 相连；
+// 汉字等表意文字每个 token 单独成词，标点附加到前一个词
+func buildWords(tokens []Token) []Word {
+	// 使用 @@ 标记续接的词表（例如 paraformer）中，没有 @@ 的 token 都是词尾
+	continuation := false
+	for _, token := range tokens {
+		if strings.HasSuffix(token.Text, "@@") {
+			continuation = true
+			break
+		}
+	}
+
+	var words []Word
+	// groups 每个词包含的 token
+	var groups [][]Token
+	// join 上一个 token 以 @@ 结尾
+	join := false
+	// ideographic 上一个词是表意文字
+	ideographic := false
+
+	for _, token := range tokens {
+		text := token.Text
+		continued := strings.HasSuffix(text, "@@")
+		text = strings.TrimSuffix(text, "@@")
+
+		boundary := continuation || strings.HasPrefix(text, "▁") || strings.HasPrefix(text, " ")
+		text = strings.TrimSpace(strings.TrimPrefix(text, "▁"))
+		if text == "" {
+			join = false
+			continue
+		}
+
+		first, _ := utf8.DecodeRuneInString(text)
+		punct := unicode.IsPunct(first)
+		ideograph := isIdeograph(first)
+
+		appendToLast := len(words) > 0 && (join || punct || (!boundary && !ideograph && !ideographic))
+		if appendToLast {
+			last := &words[len(words)-1]
+			last.Text += text
+			last.End = token.End
+		} else {
+			words = append(words, Word{Text: text, Start: token.Start, End: token.End})
+			groups = append(groups, nil)
+			ideographic = ideograph
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], token)
+		join = continued
+	}
+
+	for i := range words {
+		words[i].Confidence = aggregateConfidence(groups[i])
+	}
+	return words
+}
+
+// isIdeograph 返回是否为不以空格分词的文字
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// setTokens 设置 token 和由 token 合并得到的词
+// 有文本却没有 token 说明模型不提供时间戳，返回 ErrTimestampsUnavailable，而不是不带时间戳地返回结果
+func (r *TranscriptionResult) setTokens(tokens []Token) error {
+	if len(tokens) == 0 {
+		if r.Text != "" {
+			return ErrTimestampsUnavailable
+		}
+		return nil
+	}
+	r.Tokens = tokens
+	r.Words = buildWords(tokens)
+	return nil
+}
+
+// setTokens 设置句子的 token、词和置信度，token 的时间已经换算为会话音频中的时间
+func (r *StreamResult) setTokens(tokens []Token) {
+	if len(tokens) == 0 {
+		return
+	}
+	r.Tokens = tokens
+	r.Words = buildWords(tokens)
+	r.Confidence = aggregateConfidence(tokens)
+}
+
+// aggregateConfidence 返回 token 概率的几何平均，即平均对数概率的指数
+// 任何一个 token 没有概率时返回 0，表示置信度未知
+func aggregateConfidence(tokens []Token) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, token := range tokens {
+		if token.Confidence <= 0 {
+			return 0
+		}
+		sum += math.Log(token.Confidence)
+	}
+	return math.Exp(sum / float64(len(tokens)))
+}
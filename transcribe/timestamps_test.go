@@ -0,0 +1,117 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNewTokens(t *testing.T) {
+	tokens := newTokens(
+		[]string{"<|zh|>", "你", "好", "▁hello"},
+		[]float32{0, 0.2, 0.4, 3},
+		nil,
+		5,
+	)
+
+	expected := []Token{
+		{Text: "你", Start: 0.2, End: 0.4},
+		{Text: "好", Start: 0.4, End: 1.4}, // 停顿前的 token 最长 1 秒
+		{Text: "▁hello", Start: 3, End: 4},
+	}
+	if fmt.Sprint(tokens) != fmt.Sprint(expected) {
+		t.Errorf("期望 %+v，得到 %+v", expected, tokens)
+	}
+
+	// 时间戳数量不一致时无法对齐
+	if tokens := newTokens([]string{"a", "b"}, []float32{0}, nil, 1); tokens != nil {
+		t.Errorf("时间戳数量不一致时期望 nil，得到 %+v", tokens)
+	}
+	if tokens := newTokens([]string{"a"}, nil, nil, 1); tokens != nil {
+		t.Errorf("没有时间戳时期望 nil，得到 %+v", tokens)
+	}
+}
+
+func TestBuildWords(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		words  []string
+	}{
+		{"sentencepiece", []string{"▁HE", "LLO", "▁WORLD"}, []string{"HELLO", "WORLD"}},
+		{"whisper", []string{" Hello", ",", " world", "."}, []string{"Hello,", "world."}},
+		{"@@ 续接", []string{"hel@@", "lo", "world"}, []string{"hello", "world"}},
+		{"中文", []string{"今", "天", "好", "，"}, []string{"今", "天", "好，"}},
+		{"中英混合", []string{"打", "开", "▁WI", "FI"}, []string{"打", "开", "WIFI"}},
+		{"中文后的英文", []string{"用", "GPU"}, []string{"用", "GPU"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := make([]Token, len(tt.tokens))
+			for i, text := range tt.tokens {
+				tokens[i] = Token{Text: text, Start: float64(i), End: float64(i) + 0.5}
+			}
+
+			words := buildWords(tokens)
+			texts := make([]string, len(words))
+			for i, word := range words {
+				texts[i] = word.Text
+			}
+			if fmt.Sprint(texts) != fmt.Sprint(tt.words) {
+				t.Errorf("期望 %q，得到 %q", tt.words, texts)
+			}
+		})
+	}
+
+	// 词的时间从第一个 token 开始，到最后一个 token 结束
+	words := buildWords([]Token{{Text: "▁HE", Start: 1, End: 1.2}, {Text: "LLO", Start: 1.2, End: 1.5}})
+	if len(words) != 1 || words[0].Start != 1 || words[0].End != 1.5 {
+		t.Errorf("词的时间错误: %+v", words)
+	}
+}
+
+func TestTokenConfidence(t *testing.T) {
+	tokens := newTokens([]string{"▁HE", "LLO", "▁WORLD"}, []float32{0, 0.2, 0.5}, []float32{float32(math.Log(0.9)), float32(math.Log(0.4)), 0}, 1)
+	if math.Abs(tokens[0].Confidence-0.9) > 1e-6 || tokens[2].Confidence != 1 {
+		t.Errorf("token 置信度错误: %+v", tokens)
+	}
+
+	// 词的置信度是 token 概率的几何平均
+	words := buildWords(tokens)
+	if len(words) != 2 || math.Abs(words[0].Confidence-0.6) > 1e-6 || words[1].Confidence != 1 {
+		t.Errorf("词的置信度错误: %+v", words)
+	}
+	if c := aggregateConfidence(tokens); math.Abs(c-math.Cbrt(0.36)) > 1e-6 {
+		t.Errorf("整体置信度错误: %v", c)
+	}
+
+	// 任何一个 token 没有概率时置信度未知
+	tokens[1].Confidence = 0
+	if c := aggregateConfidence(tokens); c != 0 {
+		t.Errorf("缺少概率时期望 0，得到 %v", c)
+	}
+	if c := aggregateConfidence(nil); c != 0 {
+		t.Errorf("没有 token 时期望 0，得到 %v", c)
+	}
+}
+
+func TestSetTokensUnavailable(t *testing.T) {
+	// 有文本却没有 token 说明模型不提供时间戳
+	result := &TranscriptionResult{Text: "你好"}
+	if err := result.setTokens(nil); !errors.Is(err, ErrTimestampsUnavailable) {
+		t.Errorf("期望 ErrTimestampsUnavailable，得到 %v", err)
+	}
+
+	// 没有识别出文本时没有 token 是正常的
+	result = &TranscriptionResult{}
+	if err := result.setTokens(nil); err != nil {
+		t.Errorf("空文本不应该返回错误: %v", err)
+	}
+
+	result = &TranscriptionResult{Text: "你好"}
+	if err := result.setTokens([]Token{{Text: "你", End: 0.2}, {Text: "好", Start: 0.2, End: 0.4}}); err != nil || len(result.Words) != 2 {
+		t.Errorf("设置 token 失败: %v，词 %+v", err, result.Words)
+	}
+}
@@ -0,0 +1,430 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// 语音检测的默认参数
+const (
+	defaultVADThreshold   = 0.5
+	defaultMinSilence     = 0.5
+	defaultMinSpeech      = 0.25
+	defaultMaxSegment     = 30.0
+	sileroVADWindowSize   = 512
+	energyFrameSeconds    = 0.03
+	energySpeechPadding   = 0.1
+	energyMarginDB        = 10.0
+	energyMinThresholdDB  = -50.0
+	energyMaxThresholdDB  = -30.0
+	energySilenceFloorDB  = -100.0
+	energyNoisePercentile = 0.1
+)
+
+// VADSegment 语音检测得到的一段语音（单位：秒）
+type VADSegment struct {
+	Start float64
+	End   float64
+}
+
+// Segmenter 语音检测后端，把长音频切分为一句一句的语音
+type Segmenter interface {
+	// Segment 返回按开始时间排序、互不重叠的语音片段
+	Segment(samples []float32, sampleRate int) ([]VADSegment, error)
+	// Close 释放后端持有的资源
+	Close() error
+}
+
+// SegmenterConfig 语音检测参数，为 0 时使用默认值
+type SegmenterConfig struct {
+	// Threshold Silero VAD 的语音概率阈值，默认 0.5；能量检测不使用
+	Threshold float32
+	// MinSilence 结束一句话所需的最短静音（秒），默认 0.5
+	MinSilence float64
+	// MinSpeech 最短语音（秒），更短的片段被丢弃，默认 0.25
+	MinSpeech float64
+	// MaxSegment 最长片段（秒），更长的语音会被切开，默认 30
+	MaxSegment float64
+}
+
+func (c SegmenterConfig) withDefaults() SegmenterConfig {
+	if c.Threshold <= 0 {
+		c.Threshold = defaultVADThreshold
+	}
+	if c.MinSilence <= 0 {
+		c.MinSilence = defaultMinSilence
+	}
+	if c.MinSpeech <= 0 {
+		c.MinSpeech = defaultMinSpeech
+	}
+	if c.MaxSegment <= 0 {
+		c.MaxSegment = defaultMaxSegment
+	}
+	return c
+}
+
+// SileroSegmenter 基于 sherpa-onnx Silero VAD 的语音检测
+type SileroSegmenter struct {
+	vad        *sherpa_onnx.VoiceActivityDetector
+	sampleRate int
+	// VoiceActivityDetector 有内部状态，这里串行化调用
+	mu sync.Mutex
+}
+
+// NewSileroSegmenter 加载 Silero VAD 模型（silero_vad.onnx），sampleRate 为输入音频的采样率
+func NewSileroSegmenter(modelPath string, sampleRate, numThreads int, config SegmenterConfig) (*SileroSegmenter, error) {
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, fmt.Errorf("VAD 模型文件不存在: %v", err)
+	}
+	config = config.withDefaults()
+
+	vadConfig := &sherpa_onnx.VadModelConfig{}
+	vadConfig.SileroVad.Model = modelPath
+	vadConfig.SileroVad.Threshold = config.Threshold
+	vadConfig.SileroVad.MinSilenceDuration = float32(config.MinSilence)
+	vadConfig.SileroVad.MinSpeechDuration = float32(config.MinSpeech)
+	vadConfig.SileroVad.MaxSpeechDuration = float32(config.MaxSegment)
+	vadConfig.SileroVad.WindowSize = sileroVADWindowSize
+	vadConfig.SampleRate = sampleRate
+	vadConfig.NumThreads = numThreads
+	vadConfig.Provider = "cpu"
+
+	// 缓冲区需要能放下一个最长的片段
+	vad := sherpa_onnx.NewVoiceActivityDetector(vadConfig, float32(config.MaxSegment)+10)
+	if vad == nil {
+		return nil, fmt.Errorf("无法加载 VAD 模型: %s", modelPath)
+	}
+	return &SileroSegmenter{vad: vad, sampleRate: sampleRate}, nil
+}
+
+func (s *SileroSegmenter) Segment(samples []float32, sampleRate int) ([]VADSegment, error) {
+	if sampleRate != s.sampleRate {
+		return nil, fmt.Errorf("VAD 采样率 %d 与音频采样率 %d 不一致", s.sampleRate, sampleRate)
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.vad.Reset()
+
+	var segments []VADSegment
+	drain := func() {
+		for !s.vad.IsEmpty() {
+			seg := s.vad.Front()
+			start := float64(seg.Start) / float64(sampleRate)
+			segments = append(segments, VADSegment{
+				Start: start,
+				End:   start + float64(len(seg.Samples))/float64(sampleRate),
+			})
+			s.vad.Pop()
+		}
+	}
+
+	for offset := 0; offset < len(samples); offset += sileroVADWindowSize {
+		end := offset + sileroVADWindowSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		s.vad.AcceptWaveform(samples[offset:end])
+		drain()
+	}
+	s.vad.Flush()
+	drain()
+
+	return segments, nil
+}
+
+func (s *SileroSegmenter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.vad != nil {
+		sherpa_onnx.DeleteVoiceActivityDetector(s.vad)
+		s.vad = nil
+	}
+	return nil
+}
+
+// EnergySegmenter 基于短时能量的语音检测，不需要模型文件
+// 阈值为噪声底（帧能量的第 10 百分位）加 10dB，并限制在 -50 到 -30 dBFS 之间
+type EnergySegmenter struct {
+	config SegmenterConfig
+}
+
+func NewEnergySegmenter(config SegmenterConfig) *EnergySegmenter {
+	return &EnergySegmenter{config: config.withDefaults()}
+}
+
+func (e *EnergySegmenter) Segment(samples []float32, sampleRate int) ([]VADSegment, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("无效的采样率: %d", sampleRate)
+	}
+	frameLen := int(float64(sampleRate) * energyFrameSeconds)
+	if frameLen < 1 {
+		frameLen = 1
+	}
+	frameSeconds := float64(frameLen) / float64(sampleRate)
+	duration := float64(len(samples)) / float64(sampleRate)
+
+	energies := frameEnergies(samples, frameLen)
+	if len(energies) == 0 {
+		return nil, nil
+	}
+	threshold := energyThreshold(energies)
+
+	// 连续的语音帧组成片段，短于 MinSilence 的静音不会结束片段
+	silenceFrames := int(math.Ceil(e.config.MinSilence / frameSeconds))
+	var frames [][2]int
+	start, last := -1, -1
+	for i, energy := range energies {
+		if energy <= threshold {
+			if start >= 0 && i-last > silenceFrames {
+				frames = append(frames, [2]int{start, last + 1})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		last = i
+	}
+	if start >= 0 {
+		frames = append(frames, [2]int{start, last + 1})
+	}
+
+	var segments []VADSegment
+	for _, f := range frames {
+		if float64(f[1]-f[0])*frameSeconds < e.config.MinSpeech {
+			continue
+		}
+		for _, part := range splitFrames(energies, f[0], f[1], int(e.config.MaxSegment/frameSeconds)) {
+			segments = append(segments, VADSegment{
+				Start: float64(part[0]) * frameSeconds,
+				End:   math.Min(float64(part[1])*frameSeconds, duration),
+			})
+		}
+	}
+	return padSegments(segments, energySpeechPadding, duration), nil
+}
+
+func (e *EnergySegmenter) Close() error {
+	return nil
+}
+
+// frameEnergies 返回每帧的均方根能量（dBFS），静音帧为 -100
+func frameEnergies(samples []float32, frameLen int) []float64 {
+	energies := make([]float64, 0, (len(samples)+frameLen-1)/frameLen)
+	for offset := 0; offset < len(samples); offset += frameLen {
+		end := offset + frameLen
+		if end > len(samples) {
+			end = len(samples)
+		}
+		var sum float64
+		for _, s := range samples[offset:end] {
+			sum += float64(s) * float64(s)
+		}
+		energy := energySilenceFloorDB
+		if rms := math.Sqrt(sum / float64(end-offset)); rms > 0 {
+			energy = math.Max(20*math.Log10(rms), energySilenceFloorDB)
+		}
+		energies = append(energies, energy)
+	}
+	return energies
+}
+
+// energyThreshold 根据噪声底估计语音能量阈值
+func energyThreshold(energies []float64) float64 {
+	sorted := append([]float64(nil), energies...)
+	sort.Float64s(sorted)
+	floor := sorted[int(float64(len(sorted)-1)*energyNoisePercentile)]
+	return math.Min(math.Max(floor+energyMarginDB, energyMinThresholdDB), energyMaxThresholdDB)
+}
+
+// splitFrames 把超过 maxFrames 的片段在后半段能量最低的帧处切开
+func splitFrames(energies []float64, start, end, maxFrames int) [][2]int {
+	if maxFrames < 2 {
+		maxFrames = 2
+	}
+
+	var parts [][2]int
+	for end-start > maxFrames {
+		cut := start + maxFrames
+		for i := start + maxFrames - 1; i > start+maxFrames/2; i-- {
+			if energies[i] < energies[cut-1] {
+				cut = i + 1
+			}
+		}
+		parts = append(parts, [2]int{start, cut})
+		start = cut
+	}
+	return append(parts, [2]int{start, end})
+}
+
+// padSegments 在片段前后各留出 padding 秒，避免切掉字头字尾，片段之间不会因此重叠
+func padSegments(segments []VADSegment, padding, duration float64) []VADSegment {
+	for i := range segments {
+		lower := 0.0
+		if i > 0 {
+			lower = segments[i-1].End
+		}
+		upper := duration
+		if i+1 < len(segments) {
+			upper = segments[i+1].Start
+		}
+		segments[i].Start = math.Max(segments[i].Start-padding, lower)
+		segments[i].End = math.Min(segments[i].End+padding, upper)
+	}
+	return segments
+}
+
+// buildSegments 用语音检测把音频切分为句子并分别识别，workers 大于 1 时并行识别
+// 返回的 token 时间已换算为整段音频中的时间；progress 不为空时每识别完一句调用一次
+func buildSegments(segmenter Segmenter, samples []float32, sampleRate, workers int, recognize func([]float32) (recognition, error), progress func(float64)) ([]Segment, []Token, error) {
+	spans, err := segmenter.Segment(samples, sampleRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return recognizeSpans(spans, samples, sampleRate, workers, recognize, progress)
+}
+
+// blockEdgeSeconds 句子结束的位置离块末尾不到这个时长（秒）时，认为句子可能延续到下一块
+const blockEdgeSeconds = 1
+
+// streamSegments 从 reader 每次读取 blockSize 个采样进行切分和识别
+// 块末尾的最后一句可能被截断，留到下一块和后面的音频一起切分；
+// 整块只有一句且这句在块末尾之前已经结束时直接识别。
+// 返回的时间都是整段音频中的时间，同时返回读取的采样总数；progress 不为空时每处理完一块调用一次
+func streamSegments(segmenter Segmenter, reader AudioReader, blockSize, workers int, recognize func([]float32) (recognition, error), progress func()) ([]Segment, []Token, int, error) {
+	sampleRate := reader.SampleRate()
+	chunk := make([]float32, readBlockSize)
+
+	var segments []Segment
+	var tokens []Token
+	var block []float32
+	// offset block 第一个采样在整段音频中的位置
+	offset := 0
+	eof := false
+
+	for !eof {
+		for len(block) < blockSize && !eof {
+			n, err := reader.ReadSamples(chunk[:min(len(chunk), blockSize-len(block))])
+			block = append(block, chunk[:n]...)
+			if errors.Is(err, io.EOF) {
+				eof = true
+			} else if err != nil {
+				return nil, nil, 0, err
+			}
+		}
+
+		spans, err := segmenter.Segment(block, sampleRate)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		cut := len(block)
+		if !eof && len(spans) > 0 {
+			last := spans[len(spans)-1]
+			reachesEnd := last.End*float64(sampleRate) >= float64(len(block)-blockEdgeSeconds*sampleRate)
+			// 从块开头一直说到块末尾的句子没法再往后留，只能在块末尾截断
+			if start := int(last.Start * float64(sampleRate)); start > 0 && (len(spans) > 1 || reachesEnd) {
+				cut = start
+				spans = spans[:len(spans)-1]
+			}
+		}
+
+		blockSegments, blockTokens, err := recognizeSpans(spans, block[:cut], sampleRate, workers, recognize, nil)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		start := float64(offset) / float64(sampleRate)
+		for _, seg := range blockSegments {
+			seg.Start += start
+			seg.End += start
+			segments = append(segments, seg)
+		}
+		tokens = append(tokens, offsetTokens(blockTokens, start)...)
+
+		// 复制剩余的采样，让上一块的内存可以被回收
+		block = append([]float32(nil), block[cut:]...)
+		offset += cut
+		if progress != nil {
+			progress()
+		}
+	}
+	return segments, tokens, offset, nil
+}
+
+// recognizeSpans 分别识别 samples 中的每个语音片段，workers 大于 1 时并行识别
+func recognizeSpans(spans []VADSegment, samples []float32, sampleRate, workers int, recognize func([]float32) (recognition, error), progress func(float64)) ([]Segment, []Token, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	recs := make([]recognition, len(spans))
+	errs := make([]error, len(spans))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i, span := range spans {
+		start := int(span.Start * float64(sampleRate))
+		end := int(span.End * float64(sampleRate))
+		if start < 0 {
+			start = 0
+		}
+		if end > len(samples) {
+			end = len(samples)
+		}
+		if start >= end {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk []float32) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			recs[i], errs[i] = recognize(chunk)
+
+			mu.Lock()
+			done++
+			if progress != nil {
+				progress(float64(done) / float64(len(spans)))
+			}
+			mu.Unlock()
+		}(i, samples[start:end])
+	}
+	wg.Wait()
+
+	segments := make([]Segment, 0, len(spans))
+	var tokens []Token
+	for i, span := range spans {
+		if errs[i] != nil {
+			return nil, nil, fmt.Errorf("识别语音片段 [%.2f, %.2f] 失败: %v", span.Start, span.End, errs[i])
+		}
+		if recs[i].text == "" {
+			continue
+		}
+		segments = append(segments, Segment{
+			Start:      span.Start,
+			End:        span.End,
+			Text:       recs[i].text,
+			Confidence: aggregateConfidence(recs[i].tokens),
+		})
+		offset := float64(int(span.Start*float64(sampleRate))) / float64(sampleRate)
+		tokens = append(tokens, offsetTokens(recs[i].tokens, offset)...)
+	}
+	return segments, tokens, nil
+}
@@ -0,0 +1,224 @@
+package transcribe
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+)
+
+// toneSamples 按 [开始, 结束] 秒生成有声音的片段，其余为静音
+func toneSamples(sampleRate int, duration float64, spans ...[2]float64) []float32 {
+	samples := make([]float32, int(duration*float64(sampleRate)))
+	for _, span := range spans {
+		for i := int(span[0] * float64(sampleRate)); i < int(span[1]*float64(sampleRate)); i++ {
+			samples[i] = float32(0.3 * math.Sin(2*math.Pi*50*float64(i)/float64(sampleRate)))
+		}
+	}
+	return samples
+}
+
+func TestEnergySegmenter(t *testing.T) {
+	samples := toneSamples(1000, 6,
+		[2]float64{0.5, 1.5},
+		[2]float64{2.5, 3.5},
+		[2]float64{3.7, 4.2}, // 0.2 秒的停顿不结束句子
+		[2]float64{4.9, 5.0}, // 太短，丢弃
+	)
+
+	segments, err := NewEnergySegmenter(SegmenterConfig{}).Segment(samples, 1000)
+	if err != nil {
+		t.Fatalf("语音检测失败: %v", err)
+	}
+
+	expected := []VADSegment{{Start: 0.4, End: 1.6}, {Start: 2.4, End: 4.3}}
+	if len(segments) != len(expected) {
+		t.Fatalf("期望 %d 个片段，得到 %+v", len(expected), segments)
+	}
+	for i, seg := range segments {
+		if math.Abs(seg.Start-expected[i].Start) > 0.05 || math.Abs(seg.End-expected[i].End) > 0.05 {
+			t.Errorf("片段 %d 期望 %+v，得到 %+v", i, expected[i], seg)
+		}
+	}
+
+	// 静音没有片段
+	if segments, _ := NewEnergySegmenter(SegmenterConfig{}).Segment(make([]float32, 1000), 1000); len(segments) != 0 {
+		t.Errorf("静音期望没有片段，得到 %+v", segments)
+	}
+}
+
+func TestEnergySegmenterMaxSegment(t *testing.T) {
+	// 10 秒连续语音按最长 4 秒切开
+	samples := toneSamples(1000, 10, [2]float64{0, 10})
+
+	segments, err := NewEnergySegmenter(SegmenterConfig{MaxSegment: 4}).Segment(samples, 1000)
+	if err != nil {
+		t.Fatalf("语音检测失败: %v", err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("期望 3 个片段，得到 %+v", segments)
+	}
+	for i, seg := range segments {
+		if seg.End-seg.Start > 4+2*energySpeechPadding {
+			t.Errorf("片段超过最长时长: %+v", seg)
+		}
+		if i > 0 && seg.Start < segments[i-1].End {
+			t.Errorf("片段重叠: %+v", segments)
+		}
+	}
+	if segments[0].Start != 0 || segments[2].End != 10 {
+		t.Errorf("片段应该覆盖整段语音: %+v", segments)
+	}
+}
+
+// fakeSegmenter 用于测试的语音检测后端，直接返回预设片段
+type fakeSegmenter struct {
+	segments []VADSegment
+	err      error
+}
+
+func (s *fakeSegmenter) Segment(samples []float32, sampleRate int) ([]VADSegment, error) {
+	return s.segments, s.err
+}
+
+func (s *fakeSegmenter) Close() error { return nil }
+
+func TestBuildSegments(t *testing.T) {
+	segmenter := &fakeSegmenter{segments: []VADSegment{{Start: 0, End: 2}, {Start: 2.5, End: 3}, {Start: 4, End: 10}}}
+
+	for _, workers := range []int{1, 3} {
+		var mu sync.Mutex
+		var progress []float64
+		segments, tokens, err := buildSegments(segmenter, markedSamples(100, 5), 100, workers, markerRecognizer, func(p float64) {
+			mu.Lock()
+			progress = append(progress, p)
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("分段识别失败: %v", err)
+		}
+
+		expected := []Segment{
+			{Start: 0, End: 2, Text: "01"},
+			{Start: 2.5, End: 3, Text: "2"},
+			{Start: 4, End: 10, Text: "4"},
+		}
+		if len(segments) != len(expected) {
+			t.Fatalf("期望 %d 句，得到 %+v", len(expected), segments)
+		}
+		for i, seg := range segments {
+			if seg != expected[i] {
+				t.Errorf("%d 个 worker 时第 %d 句期望 %+v，得到 %+v", workers, i, expected[i], seg)
+			}
+		}
+
+		// token 时间换算为整段音频中的时间
+		if len(tokens) != 4 || tokens[2].Start != 2.5 || tokens[3].Start != 4 {
+			t.Errorf("token 时间错误: %+v", tokens)
+		}
+		if len(progress) != 3 || progress[2] != 1 {
+			t.Errorf("进度错误: %v", progress)
+		}
+	}
+
+	failing := func([]float32) (recognition, error) { return recognition{}, errors.New("解码失败") }
+	if _, _, err := buildSegments(segmenter, markedSamples(100, 5), 100, 2, failing, nil); err == nil {
+		t.Error("识别失败时应该返回错误")
+	}
+	segmenter.err = errors.New("VAD 失败")
+	if _, _, err := buildSegments(segmenter, markedSamples(100, 5), 100, 1, markerRecognizer, nil); err == nil {
+		t.Error("语音检测失败时应该返回错误")
+	}
+}
+
+func TestStreamSegments(t *testing.T) {
+	samples := toneSamples(1000, 20,
+		[2]float64{1, 2},
+		[2]float64{4.5, 6}, // 跨过第一块的末尾
+		[2]float64{8, 9.5},
+		[2]float64{12, 13},
+		[2]float64{17, 18},
+	)
+	segmenter := NewEnergySegmenter(SegmenterConfig{})
+	// 每句识别为一个从句子开头开始的 token
+	recognize := func(chunk []float32) (recognition, error) {
+		return recognition{text: "x", tokens: []Token{{Text: "x", End: float64(len(chunk)) / 1000}}}, nil
+	}
+	expected, _, err := buildSegments(segmenter, samples, 1000, 1, recognize, nil)
+	if err != nil {
+		t.Fatalf("分段识别失败: %v", err)
+	}
+
+	reader := newSliceReader(&Audio{Samples: samples, SampleRate: 1000})
+	blocks := 0
+	segments, tokens, total, err := streamSegments(segmenter, reader, 5000, 2, recognize, func() { blocks++ })
+	if err != nil {
+		t.Fatalf("分块识别失败: %v", err)
+	}
+	if total != len(samples) {
+		t.Errorf("采样总数错误，期望: %d, 实际: %d", len(samples), total)
+	}
+	if blocks < 4 {
+		t.Errorf("期望至少处理 4 块，实际: %d", blocks)
+	}
+
+	// 分块切分的结果与整段切分一致，句子不会在块边界被截断
+	if len(segments) != len(expected) {
+		t.Fatalf("期望 %d 句，得到 %+v", len(expected), segments)
+	}
+	for i, seg := range segments {
+		if math.Abs(seg.Start-expected[i].Start) > 0.05 || math.Abs(seg.End-expected[i].End) > 0.05 {
+			t.Errorf("第 %d 句期望 %+v，得到 %+v", i, expected[i], seg)
+		}
+	}
+	// token 时间换算为整段音频中的时间
+	if len(tokens) != len(segments) {
+		t.Fatalf("期望 %d 个 token，得到 %+v", len(segments), tokens)
+	}
+	for i, token := range tokens {
+		if math.Abs(token.Start-segments[i].Start) > 0.002 || math.Abs(token.End-segments[i].End) > 0.002 {
+			t.Errorf("token 时间错误，句子: %+v, token: %+v", segments[i], token)
+		}
+	}
+}
+
+func TestStreamSegmentsBlockBoundary(t *testing.T) {
+	recognize := func(chunk []float32) (recognition, error) {
+		return recognition{text: "x"}, nil
+	}
+
+	tests := []struct {
+		name     string
+		speech   [2]float64
+		expected [2]float64
+	}{
+		// 第一块只有一句，并且一直说到块末尾
+		{"跨过块边界的一句", [2]float64{3, 7}, [2]float64{3, 7}},
+		// 比一块还长的句子只能在块末尾截断，但不能卡住
+		{"比一块还长的一句", [2]float64{0, 7}, [2]float64{0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := toneSamples(1000, 10, tt.speech)
+			reader := newSliceReader(&Audio{Samples: samples, SampleRate: 1000})
+			segments, _, total, err := streamSegments(NewEnergySegmenter(SegmenterConfig{}), reader, 5000, 1, recognize, nil)
+			if err != nil {
+				t.Fatalf("分块识别失败: %v", err)
+			}
+			if total != len(samples) {
+				t.Errorf("采样总数错误，期望: %d, 实际: %d", len(samples), total)
+			}
+			if len(segments) == 0 {
+				t.Fatal("没有识别出句子")
+			}
+			if tt.speech == tt.expected && len(segments) != 1 {
+				t.Errorf("跨块的一句不应该被截断: %+v", segments)
+			}
+			// 句子前后留有语音检测的余量
+			first := segments[0]
+			if math.Abs(first.Start-tt.expected[0]) > 0.15 || math.Abs(first.End-tt.expected[1]) > 0.15 {
+				t.Errorf("第一句期望 %v，得到 %+v", tt.expected, segments)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package transcribe
+
+import (
+	"fmt"
+
+	"github.com/jfreymuth/vorbis"
+)
+
+// vorbisHeaderCount Vorbis 流开头的识别头、注释头和配置头
+const vorbisHeaderCount = 3
+
+// DecodeVorbis 解码 Ogg 容器中的 Vorbis 流，多声道平均混合为单声道
+func DecodeVorbis(stream *OggStream) (*Audio, error) {
+	if len(stream.Packets) < vorbisHeaderCount {
+		return nil, fmt.Errorf("Vorbis 流缺少头部，只有 %d 个数据包", len(stream.Packets))
+	}
+
+	var decoder vorbis.Decoder
+	for i, header := range stream.Packets[:vorbisHeaderCount] {
+		if err := decoder.ReadHeader(header); err != nil {
+			return nil, fmt.Errorf("读取第 %d 个 Vorbis 头失败: %v", i, err)
+		}
+	}
+
+	channels := decoder.Channels()
+	if channels <= 0 || decoder.SampleRate() <= 0 {
+		return nil, fmt.Errorf("Vorbis 声道数 %d 或采样率 %d 无效", channels, decoder.SampleRate())
+	}
+	scale := 1 / float32(channels)
+	buffer := make([]float32, decoder.BufferSize())
+
+	var samples []float32
+	for i, packet := range stream.Packets[vorbisHeaderCount:] {
+		frame, err := decoder.DecodeInto(packet, buffer)
+		if err != nil {
+			return nil, fmt.Errorf("解码第 %d 个 Vorbis 数据包失败: %v", i, err)
+		}
+		for j := 0; j+channels <= len(frame); j += channels {
+			var sum float32
+			for ch := 0; ch < channels; ch++ {
+				sum += frame[j+ch]
+			}
+			samples = append(samples, sum*scale)
+		}
+	}
+
+	// 最后一个包解码出的数据可能多于实际长度，按最后一页的 granule position 截断
+	if stream.Granule > 0 && stream.Granule < int64(len(samples)) {
+		samples = samples[:stream.Granule]
+	}
+
+	return &Audio{Samples: samples, SampleRate: decoder.SampleRate()}, nil
+}
@@ -0,0 +1,58 @@
+package transcribe
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func TestDecodeVorbis(t *testing.T) {
+	// testdata/vorbis.ogg 是 44.1kHz 单声道、1 秒长的 Vorbis 文件
+	data, err := os.ReadFile("testdata/vorbis.ogg")
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	audio, err := DecodeAudio(data, "", 0)
+	if err != nil {
+		t.Fatalf("解码 Ogg Vorbis 失败: %v", err)
+	}
+	if audio.SampleRate != 44100 {
+		t.Errorf("采样率错误，期望: 44100, 实际: %d", audio.SampleRate)
+	}
+	if len(audio.Samples) != 44100 {
+		t.Fatalf("采样数错误，期望: 44100, 实际: %d", len(audio.Samples))
+	}
+
+	// 参考值由 libvorbis 解码同一文件得到
+	reference := map[int][]float32{
+		0:     {0.0057678223, 0.006225586, 0.007873535, 0.010772705},
+		22050: {0, 0, 0, 0},
+		44096: {0.009918213, 0.011047363, 0.012359619, 0.014007568},
+	}
+	for offset, expected := range reference {
+		for i, want := range expected {
+			got := audio.Samples[offset+i]
+			if math.Abs(float64(got-want)) > 1e-4 {
+				t.Errorf("第 %d 个采样错误，期望: %f, 实际: %f", offset+i, want, got)
+			}
+		}
+	}
+}
+
+func TestDecodeVorbisMissingHeaders(t *testing.T) {
+	data, err := os.ReadFile("testdata/vorbis.ogg")
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+	stream, err := ReadOggStream(data)
+	if err != nil {
+		t.Fatalf("解析 Ogg 失败: %v", err)
+	}
+
+	// 缺少配置头
+	stream.Packets = stream.Packets[:2]
+	if _, err := DecodeVorbis(stream); err == nil {
+		t.Error("缺少头部时期望返回错误")
+	}
+}
@@ -0,0 +1,241 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// WAV 文件中的编码格式标识
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// Audio 解码后的单声道音频，采样值范围 [-1, 1]
+type Audio struct {
+	Samples    []float32
+	SampleRate int
+}
+
+// Duration 返回音频时长（秒）
+func (a *Audio) Duration() float64 {
+	if a.SampleRate <= 0 {
+		return 0
+	}
+	return float64(len(a.Samples)) / float64(a.SampleRate)
+}
+
+// UnsupportedFormatError 表示音频容器或编码不受支持
+type UnsupportedFormatError struct {
+	Format string
+	Reason string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("不支持的 %s 音频: %s", e.Format, e.Reason)
+}
+
+// wavFormat 对应 WAV 文件的 fmt 块
+type wavFormat struct {
+	formatTag     uint16
+	channels      int
+	sampleRate    int
+	blockAlign    int
+	bitsPerSample int
+}
+
+// DecodeWAV 解析 RIFF/WAVE 数据
+// 会遍历所有块读取 fmt 和 data，支持 8/16/24/32 位整数、32/64 位浮点和 WAVE_FORMAT_EXTENSIBLE，
+// 多声道会被平均混合为单声道
+func DecodeWAV(data []byte) (*Audio, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("音频数据太短，不是有效的 WAV 文件")
+	}
+	if string(data[0:4]) != "RIFF" {
+		if string(data[0:4]) == "RIFX" {
+			return nil, &UnsupportedFormatError{Format: "wav", Reason: "不支持大端序 RIFX 文件"}
+		}
+		return nil, fmt.Errorf("缺少 RIFF 头，不是有效的 WAV 文件")
+	}
+	if string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("RIFF 类型不是 WAVE")
+	}
+
+	var format *wavFormat
+	var pcm []byte
+
+	offset := 12
+	for offset+8 <= len(data) {
+		id := string(data[offset : offset+4])
+		size := int64(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+
+		// 流式写入的文件可能没有回填块大小，这里截断到实际数据长度
+		end := int64(body) + size
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		chunk := data[body:end]
+
+		switch id {
+		case "fmt ":
+			f, err := parseWavFormat(chunk)
+			if err != nil {
+				return nil, err
+			}
+			format = f
+		case "data":
+			pcm = chunk
+		}
+
+		// 块大小为奇数时后面有一个填充字节
+		next := end + size%2
+		if next > int64(len(data)) {
+			break
+		}
+		offset = int(next)
+	}
+
+	if format == nil {
+		return nil, fmt.Errorf("WAV 文件缺少 fmt 块")
+	}
+	if pcm == nil {
+		return nil, fmt.Errorf("WAV 文件缺少 data 块")
+	}
+
+	samples, err := decodeWavSamples(format, pcm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Audio{
+		Samples:    samples,
+		SampleRate: format.sampleRate,
+	}, nil
+}
+
+func parseWavFormat(chunk []byte) (*wavFormat, error) {
+	if len(chunk) < 16 {
+		return nil, fmt.Errorf("WAV fmt 块长度不足: %d", len(chunk))
+	}
+
+	f := &wavFormat{
+		formatTag:     binary.LittleEndian.Uint16(chunk[0:2]),
+		channels:      int(binary.LittleEndian.Uint16(chunk[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(chunk[4:8])),
+		blockAlign:    int(binary.LittleEndian.Uint16(chunk[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(chunk[14:16])),
+	}
+
+	// WAVE_FORMAT_EXTENSIBLE 的实际编码在 SubFormat GUID 的前两个字节
+	if f.formatTag == wavFormatExtensible {
+		if len(chunk) < 40 {
+			return nil, fmt.Errorf("WAVE_FORMAT_EXTENSIBLE 的 fmt 块长度不足: %d", len(chunk))
+		}
+		if !bytes.Equal(chunk[26:40], wavSubFormatSuffix) {
+			return nil, &UnsupportedFormatError{Format: "wav", Reason: "未知的 SubFormat GUID"}
+		}
+		f.formatTag = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+
+	if f.channels <= 0 {
+		return nil, fmt.Errorf("WAV 声道数无效: %d", f.channels)
+	}
+	if f.sampleRate <= 0 {
+		return nil, fmt.Errorf("WAV 采样率无效: %d", f.sampleRate)
+	}
+
+	switch f.formatTag {
+	case wavFormatPCM:
+		switch f.bitsPerSample {
+		case 8, 16, 24, 32:
+		default:
+			return nil, &UnsupportedFormatError{Format: "wav", Reason: fmt.Sprintf("不支持 %d 位 PCM", f.bitsPerSample)}
+		}
+	case wavFormatIEEEFloat:
+		switch f.bitsPerSample {
+		case 32, 64:
+		default:
+			return nil, &UnsupportedFormatError{Format: "wav", Reason: fmt.Sprintf("不支持 %d 位浮点", f.bitsPerSample)}
+		}
+	default:
+		return nil, &UnsupportedFormatError{Format: "wav", Reason: fmt.Sprintf("不支持的编码格式 0x%04x", f.formatTag)}
+	}
+
+	if f.blockAlign < f.channels*f.bitsPerSample/8 {
+		return nil, fmt.Errorf("WAV blockAlign %d 与 %d 声道 %d 位不匹配", f.blockAlign, f.channels, f.bitsPerSample)
+	}
+
+	return f, nil
+}
+
+// KSDATAFORMAT_SUBTYPE_* GUID 除前两个字节以外的公共部分
+var wavSubFormatSuffix = []byte{
+	0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00,
+	0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71,
+}
+
+// decodeWavSamples 将交错的多声道数据转换为单声道 float32
+func decodeWavSamples(f *wavFormat, pcm []byte) ([]float32, error) {
+	// 每个采样占用的字节数，由 blockAlign 决定（可能大于有效位数）
+	width := f.blockAlign / f.channels
+	if width*8 < f.bitsPerSample {
+		return nil, fmt.Errorf("WAV 采样宽度 %d 字节不足以容纳 %d 位", width, f.bitsPerSample)
+	}
+
+	decode, err := wavSampleDecoder(f.formatTag, f.bitsPerSample)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := len(pcm) / f.blockAlign
+	samples := make([]float32, frames)
+	scale := 1 / float32(f.channels)
+
+	for i := 0; i < frames; i++ {
+		frame := pcm[i*f.blockAlign:]
+		var sum float32
+		for ch := 0; ch < f.channels; ch++ {
+			sum += decode(frame[ch*width:])
+		}
+		samples[i] = sum * scale
+	}
+
+	return samples, nil
+}
+
+// wavSampleDecoder 返回把一个采样的字节转换为 float32 的函数
+func wavSampleDecoder(formatTag uint16, bits int) (func([]byte) float32, error) {
+	switch {
+	case formatTag == wavFormatPCM && bits == 8:
+		// 8 位 PCM 是无符号数
+		return func(b []byte) float32 {
+			return (float32(b[0]) - 128) / 128
+		}, nil
+	case formatTag == wavFormatPCM && bits == 16:
+		return func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		}, nil
+	case formatTag == wavFormatPCM && bits == 24:
+		return func(b []byte) float32 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return float32(v) / 8388608
+		}, nil
+	case formatTag == wavFormatPCM && bits == 32:
+		return func(b []byte) float32 {
+			return float32(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648)
+		}, nil
+	case formatTag == wavFormatIEEEFloat && bits == 32:
+		return func(b []byte) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}, nil
+	case formatTag == wavFormatIEEEFloat && bits == 64:
+		return func(b []byte) float32 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}, nil
+	}
+	return nil, &UnsupportedFormatError{Format: "wav", Reason: fmt.Sprintf("不支持编码 0x%04x 的 %d 位采样", formatTag, bits)}
+}
@@ -0,0 +1,196 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// wavChunk 测试用的 RIFF 块
+type wavChunk struct {
+	id   string
+	body []byte
+}
+
+// buildRIFF 把若干块拼接成 RIFF/WAVE 文件
+func buildRIFF(chunks ...wavChunk) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, c := range chunks {
+		body.WriteString(c.id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(c.body)))
+		body.Write(c.body)
+		if len(c.body)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// fmtChunk 构造 fmt 块
+func fmtChunk(formatTag uint16, channels, sampleRate, bits int) wavChunk {
+	var b bytes.Buffer
+	blockAlign := channels * bits / 8
+	binary.Write(&b, binary.LittleEndian, formatTag)
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate*blockAlign))
+	binary.Write(&b, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&b, binary.LittleEndian, uint16(bits))
+	return wavChunk{id: "fmt ", body: b.Bytes()}
+}
+
+// extensibleFmtChunk 构造 WAVE_FORMAT_EXTENSIBLE 的 fmt 块
+func extensibleFmtChunk(subFormat uint16, channels, sampleRate, bits int) wavChunk {
+	c := fmtChunk(wavFormatExtensible, channels, sampleRate, bits)
+	var b bytes.Buffer
+	b.Write(c.body)
+	binary.Write(&b, binary.LittleEndian, uint16(22))   // cbSize
+	binary.Write(&b, binary.LittleEndian, uint16(bits)) // wValidBitsPerSample
+	binary.Write(&b, binary.LittleEndian, uint32(0))    // dwChannelMask
+	binary.Write(&b, binary.LittleEndian, subFormat)
+	b.Write(wavSubFormatSuffix)
+	return wavChunk{id: "fmt ", body: b.Bytes()}
+}
+
+func pcm16Chunk(values ...int16) wavChunk {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, values)
+	return wavChunk{id: "data", body: b.Bytes()}
+}
+
+func assertSamples(t *testing.T, got []float32, want ...float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("采样数量错误，期望: %d, 实际: %d (%v)", len(want), len(got), got)
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-4 {
+			t.Errorf("采样 %d 错误，期望: %f, 实际: %f", i, want[i], got[i])
+		}
+	}
+}
+
+func TestDecodeWAVPCM16WithExtraChunks(t *testing.T) {
+	data := buildRIFF(
+		wavChunk{id: "LIST", body: []byte("INFOISFT\x05\x00\x00\x00test\x00")},
+		fmtChunk(wavFormatPCM, 1, 8000, 16),
+		wavChunk{id: "fact", body: []byte{3, 0, 0, 0}},
+		pcm16Chunk(0, 16384, -32768),
+	)
+
+	audio, err := DecodeWAV(data)
+	if err != nil {
+		t.Fatalf("解析 WAV 失败: %v", err)
+	}
+	if audio.SampleRate != 8000 {
+		t.Errorf("采样率错误，期望: 8000, 实际: %d", audio.SampleRate)
+	}
+	assertSamples(t, audio.Samples, 0, 0.5, -1)
+}
+
+func TestDecodeWAVStereoDownmix(t *testing.T) {
+	data := buildRIFF(
+		fmtChunk(wavFormatPCM, 2, 16000, 16),
+		pcm16Chunk(16384, -16384, 16384, 0),
+	)
+
+	audio, err := DecodeWAV(data)
+	if err != nil {
+		t.Fatalf("解析 WAV 失败: %v", err)
+	}
+	assertSamples(t, audio.Samples, 0, 0.25)
+}
+
+func TestDecodeWAVSampleWidths(t *testing.T) {
+	float32Data := new(bytes.Buffer)
+	binary.Write(float32Data, binary.LittleEndian, []float32{0.25, -0.5})
+	float64Data := new(bytes.Buffer)
+	binary.Write(float64Data, binary.LittleEndian, []float64{0.25, -0.5})
+	int32Data := new(bytes.Buffer)
+	binary.Write(int32Data, binary.LittleEndian, []int32{1 << 29, -1 << 30})
+
+	tests := []struct {
+		name string
+		fmt  wavChunk
+		data []byte
+	}{
+		{"8位", fmtChunk(wavFormatPCM, 1, 16000, 8), []byte{160, 64}},
+		{"24位", fmtChunk(wavFormatPCM, 1, 16000, 24), []byte{0x00, 0x00, 0x20, 0x00, 0x00, 0xc0}},
+		{"32位整数", fmtChunk(wavFormatPCM, 1, 16000, 32), int32Data.Bytes()},
+		{"32位浮点", fmtChunk(wavFormatIEEEFloat, 1, 16000, 32), float32Data.Bytes()},
+		{"64位浮点", fmtChunk(wavFormatIEEEFloat, 1, 16000, 64), float64Data.Bytes()},
+		{"EXTENSIBLE 浮点", extensibleFmtChunk(wavFormatIEEEFloat, 1, 16000, 32), float32Data.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audio, err := DecodeWAV(buildRIFF(tt.fmt, wavChunk{id: "data", body: tt.data}))
+			if err != nil {
+				t.Fatalf("解析 WAV 失败: %v", err)
+			}
+			assertSamples(t, audio.Samples, 0.25, -0.5)
+		})
+	}
+}
+
+func TestDecodeWAVTruncatedDataChunk(t *testing.T) {
+	data := buildRIFF(fmtChunk(wavFormatPCM, 1, 16000, 16), pcm16Chunk(16384, 16384))
+	// 模拟流式写入：data 块大小为 0xFFFFFFFF
+	binary.LittleEndian.PutUint32(data[len(data)-8:], 0xFFFFFFFF)
+
+	audio, err := DecodeWAV(data)
+	if err != nil {
+		t.Fatalf("解析 WAV 失败: %v", err)
+	}
+	assertSamples(t, audio.Samples, 0.5, 0.5)
+}
+
+func TestDecodeWAVUnsupportedCodec(t *testing.T) {
+	tests := []struct {
+		name string
+		fmt  wavChunk
+	}{
+		{"MP3 编码", fmtChunk(0x0055, 1, 16000, 16)},
+		{"A-law 扩展", extensibleFmtChunk(0x0006, 1, 16000, 8)},
+		{"12位 PCM", fmtChunk(wavFormatPCM, 1, 16000, 12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeWAV(buildRIFF(tt.fmt, pcm16Chunk(0)))
+			var unsupported *UnsupportedFormatError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("期望 UnsupportedFormatError，实际: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeWAVInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"数据过短", []byte("RIFF")},
+		{"不是 RIFF", append([]byte("OggS"), make([]byte, 40)...)},
+		{"缺少 fmt 块", buildRIFF(pcm16Chunk(0))},
+		{"缺少 data 块", buildRIFF(fmtChunk(wavFormatPCM, 1, 16000, 16))},
+		{"声道数为 0", buildRIFF(fmtChunk(wavFormatPCM, 0, 16000, 16), pcm16Chunk(0))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeWAV(tt.data); err == nil {
+				t.Error("期望返回错误")
+			}
+		})
+	}
+}